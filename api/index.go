@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"log"
 	"net/http"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"loan-dynamic-api/config"
 	"loan-dynamic-api/routes"
+	"loan-dynamic-api/services"
 )
 
 var e *echo.Echo
@@ -31,6 +33,16 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Warning: Failed to ensure indexes: %v", err)
 		}
 
+		// Seed chart of accounts
+		if err := services.EnsureChartOfAccounts(context.Background(), config.GetDatabase()); err != nil {
+			log.Printf("Warning: Failed to seed chart of accounts: %v", err)
+		}
+
+		// Members left pending from before kyc_submissions existed
+		if err := services.BackfillOpenKYCSubmissions(context.Background(), config.GetDatabase()); err != nil {
+			log.Printf("Warning: Failed to backfill KYC submissions: %v", err)
+		}
+
 		// Create Echo instance
 		e = routes.NewEcho()
 	}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// reconcile runs the end-of-day balance reconciliation once and exits
+// with status 1 when mismatches or broken balanceafter chains are found.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found")
+	}
+
+	if err := config.InitMongoAtlas(); err != nil {
+		log.Fatalf("Failed to initialize MongoDB Atlas: %v", err)
+	}
+	defer config.DisconnectMongoAtlas()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report, err := services.ReconcileBalances(ctx, config.GetDatabase(), "cli")
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	log.Printf("Report %s (%s): %d accounts checked, %d balance mismatches, %d chain breaks",
+		report.ReportID, report.ReportDate, report.AccountsChecked, report.MismatchCount, report.ChainBreakCount)
+
+	if report.MismatchCount > 0 || report.ChainBreakCount > 0 {
+		config.DisconnectMongoAtlas()
+		os.Exit(1)
+	}
+}
@@ -82,6 +82,15 @@ func EnsureIndexes() error {
         {
             Keys: bson.D{{"mobile", 1}},
         },
+        {
+            Keys: bson.D{{"citizen_id", 1}},
+        },
+        {
+            Keys: bson.D{{"bank_id", 1}, {"bank_account_no", 1}},
+        },
+        {
+            Keys: bson.D{{"kyc_status", 1}, {"kyc_expires_at", 1}},
+        },
         {
             Keys: bson.D{{"created_at", -1}},
         },
@@ -105,6 +114,13 @@ func EnsureIndexes() error {
         {
             Keys: bson.D{{"memberid", 1}},
         },
+        {
+            Keys: bson.D{{"status", 1}, {"lastactivityat", 1}},
+        },
+        {
+            Keys:    bson.D{{"fixeddeposit.maturitydate", 1}},
+            Options: options.Index().SetSparse(true),
+        },
     }
 
     if _, err := accColl.Indexes().CreateMany(ctx, accIndexes); err != nil {
@@ -127,12 +143,369 @@ func EnsureIndexes() error {
         {
             Keys: bson.D{{"datetime", -1}},
         },
+        {
+            // One transfer per idempotency key (e.g. a scheduled transfer occurrence)
+            Keys:    bson.D{{"idempotencykey", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"idempotencykey": bson.M{"$exists": true}}),
+        },
     }
 
     if _, err := txColl.Indexes().CreateMany(ctx, txIndexes); err != nil {
         return fmt.Errorf("failed to create indexes for deposit_transactions: %w", err)
     }
 
+    // 6. deposit_transactions limit usage (sum per account over a period)
+    if _, err := txColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys: bson.D{{"accountid", 1}, {"type", 1}, {"datetime", -1}},
+    }); err != nil {
+        return fmt.Errorf("failed to create limit index for deposit_transactions: %w", err)
+    }
+
+    // 7. member_transfer_limits, transfer_limit_locks & transfer_approvals Indexes
+    limitColl := db.Collection("member_transfer_limits")
+    if _, err := limitColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{"memberid", 1}},
+        Options: options.Index().SetUnique(true),
+    }); err != nil {
+        return fmt.Errorf("failed to create indexes for member_transfer_limits: %w", err)
+    }
+
+    lockColl := db.Collection("transfer_limit_locks")
+    if _, err := lockColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{"memberid", 1}},
+        Options: options.Index().SetUnique(true),
+    }); err != nil {
+        return fmt.Errorf("failed to create indexes for transfer_limit_locks: %w", err)
+    }
+
+    approvalColl := db.Collection("transfer_approvals")
+    approvalIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"approvalid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"status", 1}, {"requestedat", 1}},
+        },
+        {
+            Keys:    bson.D{{"reference", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"reference": bson.M{"$exists": true}}),
+        },
+    }
+
+    if _, err := approvalColl.Indexes().CreateMany(ctx, approvalIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for transfer_approvals: %w", err)
+    }
+
+    // 8. scheduled_transfers Indexes
+    schedColl := db.Collection("scheduled_transfers")
+    schedIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"scheduleid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"status", 1}, {"nextrunat", 1}},
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"created_at", -1}},
+        },
+    }
+
+    if _, err := schedColl.Indexes().CreateMany(ctx, schedIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for scheduled_transfers: %w", err)
+    }
+
+    // 9. gl_journal_entries Indexes
+    glColl := db.Collection("gl_journal_entries")
+    glIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"entryid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"sourcetype", 1}, {"sourceref", 1}},
+        },
+        {
+            Keys: bson.D{{"lines.accountcode", 1}, {"lines.subaccount", 1}},
+        },
+        {
+            Keys: bson.D{{"entrydate", -1}},
+        },
+    }
+
+    if _, err := glColl.Indexes().CreateMany(ctx, glIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for gl_journal_entries: %w", err)
+    }
+
+    // 10. loan_payments Indexes
+    loanPayColl := db.Collection("loan_payments")
+    loanPayIndexes := []mongo.IndexModel{
+        {
+            Keys: bson.D{{"applicationid", 1}, {"paymentdate", 1}},
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"paymentdate", 1}},
+        },
+    }
+
+    if _, err := loanPayColl.Indexes().CreateMany(ctx, loanPayIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for loan_payments: %w", err)
+    }
+
+    // 11. reconciliation_reports Indexes
+    reconColl := db.Collection("reconciliation_reports")
+    reconIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"reportid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"generatedat", -1}},
+        },
+    }
+
+    if _, err := reconColl.Indexes().CreateMany(ctx, reconIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for reconciliation_reports: %w", err)
+    }
+
+    // 12. statements Indexes
+    stmtColl := db.Collection("statements")
+    stmtIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"statementid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"generatedat", -1}},
+        },
+    }
+
+    if _, err := stmtColl.Indexes().CreateMany(ctx, stmtIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for statements: %w", err)
+    }
+
+    // 13. fixed_deposit_products / fixed_deposit_interest Indexes
+    fdProductIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"productcode", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+    }
+
+    if _, err := db.Collection("fixed_deposit_products").Indexes().CreateMany(ctx, fdProductIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for fixed_deposit_products: %w", err)
+    }
+
+    fdInterestIndexes := []mongo.IndexModel{
+        {
+            Keys: bson.D{{"accountid", 1}, {"paidat", -1}},
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"paidat", -1}},
+        },
+    }
+
+    if _, err := db.Collection("fixed_deposit_interest").Indexes().CreateMany(ctx, fdInterestIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for fixed_deposit_interest: %w", err)
+    }
+
+    // 14. share_accounts / share_transactions Indexes
+    shareAccIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"memberid", 1}, {"sharetypeid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"sharetypeid", 1}},
+        },
+    }
+
+    if _, err := db.Collection("share_accounts").Indexes().CreateMany(ctx, shareAccIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for share_accounts: %w", err)
+    }
+
+    shareTxIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"transactionid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"datetime", -1}},
+        },
+    }
+
+    if _, err := db.Collection("share_transactions").Indexes().CreateMany(ctx, shareTxIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for share_transactions: %w", err)
+    }
+
+    // 15. share_type_prices Indexes
+    sharePriceIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"sharetypeid", 1}, {"effectivefrom", -1}},
+            Options: options.Index().SetUnique(true),
+        },
+    }
+
+    if _, err := db.Collection("share_type_prices").Indexes().CreateMany(ctx, sharePriceIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for share_type_prices: %w", err)
+    }
+
+    // 16. dividend_rates / dividend_payments Indexes
+    dividendRunIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"fiscalyear", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+    }
+
+    if _, err := db.Collection("dividend_rates").Indexes().CreateMany(ctx, dividendRunIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for dividend_rates: %w", err)
+    }
+
+    dividendPaymentIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"fiscalyear", 1}, {"memberid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"fiscalyear", 1}, {"status", 1}},
+        },
+    }
+
+    if _, err := db.Collection("dividend_payments").Indexes().CreateMany(ctx, dividendPaymentIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for dividend_payments: %w", err)
+    }
+
+    // 17. share_certificates Indexes
+    certificateIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"certificateid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"status", 1}},
+        },
+    }
+
+    if _, err := db.Collection("share_certificates").Indexes().CreateMany(ctx, certificateIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for share_certificates: %w", err)
+    }
+
+    // 18. kyc_submissions Indexes
+    kycSubmissionIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"submissionid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            // One open submission per member
+            Keys:    bson.D{{"memberid", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "pending"}),
+        },
+        {
+            Keys: bson.D{{"memberid", 1}, {"submittedat", -1}},
+        },
+        {
+            // Officer review queue
+            Keys: bson.D{{"status", 1}, {"submittedat", 1}},
+        },
+    }
+
+    if _, err := db.Collection("kyc_submissions").Indexes().CreateMany(ctx, kycSubmissionIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for kyc_submissions: %w", err)
+    }
+
+    // 19. approval_requests Indexes
+    approvalRequestIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"requestid", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            // One pending request per action type and member
+            Keys:    bson.D{{"type", 1}, {"targetid", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "pending"}),
+        },
+        {
+            Keys: bson.D{{"status", 1}, {"requestedat", 1}},
+        },
+        {
+            Keys:    bson.D{{"reference", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"reference": bson.M{"$exists": true}}),
+        },
+    }
+
+    if _, err := db.Collection("approval_requests").Indexes().CreateMany(ctx, approvalRequestIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for approval_requests: %w", err)
+    }
+
+    // 20. banks Indexes
+    bankIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"code", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+    }
+
+    if _, err := db.Collection("banks").Indexes().CreateMany(ctx, bankIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for banks: %w", err)
+    }
+
+    // 21. documents Indexes
+    documentIndexes := []mongo.IndexModel{
+        {
+            Keys: bson.D{{"ref_id", 1}, {"category", 1}, {"version", -1}},
+        },
+        {
+            // One current version per slot (ref_id + category)
+            Keys:    bson.D{{"ref_id", 1}, {"category", 1}},
+            Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "current"}),
+        },
+    }
+
+    if _, err := db.Collection("documents").Indexes().CreateMany(ctx, documentIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for documents: %w", err)
+    }
+
+    // 22. document_uploads Indexes
+    documentUploadIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"upload_id", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"status", 1}, {"expires_at", 1}},
+        },
+    }
+
+    if _, err := db.Collection("document_uploads").Indexes().CreateMany(ctx, documentUploadIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for document_uploads: %w", err)
+    }
+
+    // 23. document_blobs Indexes
+    documentBlobIndexes := []mongo.IndexModel{
+        {
+            Keys:    bson.D{{"sha256", 1}},
+            Options: options.Index().SetUnique(true),
+        },
+        {
+            Keys: bson.D{{"r2_key", 1}},
+        },
+    }
+
+    if _, err := db.Collection("document_blobs").Indexes().CreateMany(ctx, documentBlobIndexes); err != nil {
+        return fmt.Errorf("failed to create indexes for document_blobs: %w", err)
+    }
+
+    // 24. officer_signatures Indexes
+    if _, err := db.Collection("officer_signatures").Indexes().CreateOne(ctx, mongo.IndexModel{
+        Keys:    bson.D{{"officerid", 1}},
+        Options: options.Index().SetUnique(true),
+    }); err != nil {
+        return fmt.Errorf("failed to create indexes for officer_signatures: %w", err)
+    }
+
     fmt.Println("Indexes ensured successfully")
     return nil
 }
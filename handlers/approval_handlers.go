@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// ApprovalDecisionRequest represents a second officer approving or rejecting a pending request
+type ApprovalDecisionRequest struct {
+	OfficerID string `json:"officer_id"`
+	RequestID string `json:"request_id"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// GetPendingApprovalsHandler lists requests waiting for a second officer (optional ?type=)
+func GetPendingApprovalsHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	requests, err := services.ListPendingApprovalRequests(ctx, db, c.QueryParam("type"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"count":  len(requests),
+		"data":   requests,
+	})
+}
+
+// ApproveRequestHandler approves a pending request and carries out the action. The approving
+// officer must differ from the one who made the request.
+func ApproveRequestHandler(c echo.Context) error {
+	var req ApprovalDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.RequestID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "request_id is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	approval, err := services.ApproveRequest(ctx, db, req.RequestID, req.OfficerID)
+	if err != nil {
+		if approval != nil {
+			// Approved, but the action itself failed; the request is marked failed
+			return c.JSON(http.StatusConflict, map[string]interface{}{
+				"error": err.Error(),
+				"data":  approval,
+			})
+		}
+		return c.JSON(approvalErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Request approved and applied",
+		"data":    approval,
+	})
+}
+
+// RejectRequestHandler declines a pending request
+func RejectRequestHandler(c echo.Context) error {
+	var req ApprovalDecisionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.RequestID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "request_id is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	if err := services.RejectRequest(ctx, db, req.RequestID, req.OfficerID, req.Reason); err != nil {
+		return c.JSON(approvalErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "Request rejected"})
+}
+
+func approvalErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrApprovalRequestNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
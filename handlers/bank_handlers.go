@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// SaveBankRequest represents an officer adding or updating a bank in the bank list
+type SaveBankRequest struct {
+	OfficerID      string `json:"officer_id"`
+	Code           string `json:"code"`
+	ShortName      string `json:"short_name"`
+	NameTH         string `json:"name_th"`
+	NameEN         string `json:"name_en"`
+	AccountLengths []int  `json:"account_lengths"`
+	Active         bool   `json:"active"`
+}
+
+// ListBanksHandler returns the banks members can choose for KYC
+func ListBanksHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	banks, err := services.ListBanks(ctx, db)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if c.QueryParam("active") == "true" {
+		active := banks[:0]
+		for _, b := range banks {
+			if b.Active {
+				active = append(active, b)
+			}
+		}
+		banks = active
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   banks,
+	})
+}
+
+// SaveBankHandler adds a bank or overrides a built-in one
+func SaveBankHandler(c echo.Context) error {
+	var req SaveBankRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	bank, err := services.SaveBank(ctx, db, services.Bank{
+		Code:           req.Code,
+		ShortName:      req.ShortName,
+		NameTH:         req.NameTH,
+		NameEN:         req.NameEN,
+		AccountLengths: req.AccountLengths,
+		Active:         req.Active,
+		UpdatedBy:      req.OfficerID,
+	})
+	if errors.Is(err, services.ErrInvalidBank) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   bank,
+	})
+}
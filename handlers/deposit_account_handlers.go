@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// OpenDepositAccountRequest represents a request to open a deposit account
+type OpenDepositAccountRequest struct {
+	MemberID    string `json:"memberid"`
+	AccountType string `json:"account_type"` // savings, fixed
+	AccountName string `json:"account_name"`
+}
+
+// AccountStatusRequest represents an officer status change on a deposit account
+type AccountStatusRequest struct {
+	OfficerID string `json:"officer_id"`
+	AccountID string `json:"account_id"`
+	Reason    string `json:"reason"`
+}
+
+// CloseDepositAccountRequest represents an officer closing a deposit account
+type CloseDepositAccountRequest struct {
+	OfficerID           string `json:"officer_id"`
+	AccountID           string `json:"account_id"`
+	Reason              string `json:"reason"`
+	Settlement          string `json:"settlement"` // transfer, cash (required when balance > 0)
+	SettlementAccountID string `json:"settlement_account_id,omitempty"`
+}
+
+// OpenDepositAccountHandler opens a new account with a generated account number
+func OpenDepositAccountHandler(c echo.Context) error {
+	var req OpenDepositAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	account, err := services.OpenDepositAccount(ctx, db, services.OpenAccountRequest{
+		MemberID:    req.MemberID,
+		AccountType: req.AccountType,
+		AccountName: req.AccountName,
+	})
+	if err != nil {
+		return c.JSON(depositAccountErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   account,
+	})
+}
+
+// FreezeDepositAccountHandler blocks all money movement on an account
+func FreezeDepositAccountHandler(c echo.Context) error {
+	return changeDepositAccountStatus(c, services.FreezeAccount)
+}
+
+// UnfreezeDepositAccountHandler returns a frozen account to active
+func UnfreezeDepositAccountHandler(c echo.Context) error {
+	return changeDepositAccountStatus(c, services.UnfreezeAccount)
+}
+
+// ReactivateDepositAccountHandler returns a dormant account to active
+func ReactivateDepositAccountHandler(c echo.Context) error {
+	return changeDepositAccountStatus(c, services.ReactivateAccount)
+}
+
+func changeDepositAccountStatus(c echo.Context, change func(ctx context.Context, db *mongo.Database, accountID, officerID, reason string) error) error {
+	var req AccountStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.AccountID == "" || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "account_id and reason are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	if err := change(ctx, db, req.AccountID, req.OfficerID, req.Reason); err != nil {
+		return c.JSON(depositAccountErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"account_id": req.AccountID,
+	})
+}
+
+// CloseDepositAccountHandler settles the balance and closes an account
+func CloseDepositAccountHandler(c echo.Context) error {
+	var req CloseDepositAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.AccountID == "" || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "account_id and reason are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	result, err := services.CloseDepositAccount(ctx, db, services.CloseAccountRequest{
+		AccountID:           req.AccountID,
+		OfficerID:           req.OfficerID,
+		Reason:              req.Reason,
+		Settlement:          req.Settlement,
+		SettlementAccountID: req.SettlementAccountID,
+	})
+	if err != nil {
+		return c.JSON(depositAccountErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+func depositAccountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownProduct), errors.Is(err, services.ErrSettlementRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAccountNotFound), errors.Is(err, services.ErrMemberNotFound),
+		errors.Is(err, services.ErrDestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidStatusChange), errors.Is(err, services.ErrAccountClosed):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAccountOwner), errors.Is(err, services.ErrAccountNotActive):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// PreviewDividendsRequest represents an officer previewing a fiscal year's dividends for the board
+type PreviewDividendsRequest struct {
+	OfficerID     string  `json:"officer_id"`
+	FiscalYear    int     `json:"fiscal_year"`    // Gregorian year the fiscal year ends in
+	DividendRate  float64 `json:"dividend_rate"`  // percent of average monthly share balance
+	PatronageRate float64 `json:"patronage_rate"` // percent of loan interest paid
+}
+
+// FinalizeDividendsRequest represents an officer paying out a board-approved preview
+type FinalizeDividendsRequest struct {
+	OfficerID       string `json:"officer_id"`
+	FiscalYear      int    `json:"fiscal_year"`
+	BoardResolution string `json:"board_resolution"`
+}
+
+// PreviewDividendsHandler computes per-member dividends and patronage refunds without paying them
+func PreviewDividendsHandler(c echo.Context) error {
+	var req PreviewDividendsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.FiscalYear < 2000 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fiscal_year is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	run, err := services.PreviewDividends(ctx, db, req.FiscalYear, req.DividendRate, req.PatronageRate, req.OfficerID)
+	if err != nil {
+		return c.JSON(dividendErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   run,
+	})
+}
+
+// FinalizeDividendsHandler approves the preview and pays it into members' savings accounts.
+// Calling it again retries members that could not be paid.
+func FinalizeDividendsHandler(c echo.Context) error {
+	var req FinalizeDividendsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.FiscalYear < 2000 || req.BoardResolution == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "fiscal_year and board_resolution are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	run, err := services.FinalizeDividends(ctx, db, req.FiscalYear, req.OfficerID, req.BoardResolution)
+	if err != nil {
+		return c.JSON(dividendErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   run,
+	})
+}
+
+// GetDividendRunHandler returns a fiscal year's run and its per-member figures
+func GetDividendRunHandler(c echo.Context) error {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid fiscal year"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	limit, _ := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if limit <= 0 {
+		limit = 500
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run, err := services.GetDividendRun(ctx, db, year)
+	if err != nil {
+		return c.JSON(dividendErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	payments, err := services.ListDividendPayments(ctx, db, year, c.QueryParam("status"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"data":     run,
+		"payments": payments,
+	})
+}
+
+// GetDividendSlipHandler renders a member's dividend slip as a PNG image
+func GetDividendSlipHandler(c echo.Context) error {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid fiscal year"})
+	}
+	memberID := c.QueryParam("memberid")
+	if memberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	payment, err := services.GetDividendPayment(ctx, db, year, memberID)
+	if err != nil {
+		return c.JSON(dividendErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	img, err := renderDividendSlip(payment, services.MemberName(ctx, db, memberID), maskAccountNumber(payment.AccountNumber))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.Blob(http.StatusOK, "image/png", img)
+}
+
+func dividendErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrDividendRate):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrDividendRunNotFound), errors.Is(err, services.ErrDividendNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrDividendFinalized), errors.Is(err, services.ErrFiscalYearOpen),
+		errors.Is(err, services.ErrStaleDividendRun):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
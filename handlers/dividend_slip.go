@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+
+	"loan-dynamic-api/models"
+)
+
+// docCanvas is a gg context with cached Thai font faces for single-page documents
+type docCanvas struct {
+	dc          *gg.Context
+	regularPath string
+	boldPath    string
+	faces       map[string]font.Face
+}
+
+func newDocCanvas(width, height int) *docCanvas {
+	regular, bold := thaiFontPaths()
+	dc := gg.NewContext(width, height)
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+	return &docCanvas{dc: dc, regularPath: regular, boldPath: bold, faces: map[string]font.Face{}}
+}
+
+func (c *docCanvas) setFont(size float64, bold bool) {
+	path := c.regularPath
+	if bold {
+		path = c.boldPath
+	}
+	key := fmt.Sprintf("%s|%.1f", path, size)
+	face, ok := c.faces[key]
+	if !ok {
+		var err error
+		face, err = gg.LoadFontFace(path, size)
+		if err != nil {
+			return
+		}
+		c.faces[key] = face
+	}
+	c.dc.SetFontFace(face)
+}
+
+// text draws a string; align is 0 for left, 0.5 for centered and 1 for right-aligned at x
+func (c *docCanvas) text(s string, x, y, size float64, rgb [3]float64, bold bool, align float64) {
+	c.setFont(size, bold)
+	c.dc.SetRGB(rgb[0], rgb[1], rgb[2])
+	c.dc.DrawStringAnchored(s, x, y, align, 0)
+}
+
+func (c *docCanvas) line(x1, y, x2 float64, rgb [3]float64, width float64) {
+	c.dc.SetRGB(rgb[0], rgb[1], rgb[2])
+	c.dc.SetLineWidth(width)
+	c.dc.DrawLine(x1, y, x2, y)
+	c.dc.Stroke()
+}
+
+func (c *docCanvas) png() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.dc.Image()); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderDividendSlip draws a member's dividend and patronage refund slip at the same
+// 3x scale as transfer slips
+func renderDividendSlip(p *models.DividendPayment, memberName, accountMasked string) ([]byte, error) {
+	const scale = 3.0
+	const width = int(350 * scale)
+	const height = int(460 * scale)
+	const padding = 20.0 * scale
+	right := float64(width) - padding
+	center := float64(width) / 2
+
+	c := newDocCanvas(width, height)
+
+	// === HEADER: logo + co-op name ===
+	y := 24.0 * scale
+	logoSize := uint(45 * scale)
+	if logo := loadCircularLogo(logoSize); logo != nil {
+		c.dc.DrawImage(logo, int(padding), int(y))
+	} else {
+		c.dc.SetRGB(stmtPrimary[0], stmtPrimary[1], stmtPrimary[2])
+		c.dc.DrawCircle(padding+float64(logoSize)/2, y+float64(logoSize)/2, float64(logoSize)/2)
+		c.dc.Fill()
+	}
+	c.text("สหกรณ์ รสพ.", padding+float64(logoSize)+12*scale, y+28*scale, 18*scale, stmtPrimary, true, 0)
+
+	// === TITLE ===
+	y += float64(logoSize) + 30*scale
+	c.text("ใบแจ้งเงินปันผลและเงินเฉลี่ยคืน", center, y, 18*scale, stmtText, true, 0.5)
+	y += 20 * scale
+	c.text(fmt.Sprintf("ปีบัญชี %d", p.FiscalYear+543), center, y, 13*scale, stmtSecondary, false, 0.5)
+
+	// === MEMBER ===
+	y += 28 * scale
+	if memberName != "" {
+		c.text(memberName, padding, y, 15*scale, stmtText, true, 0)
+		y += 18 * scale
+	}
+	c.text("เลขสมาชิก "+p.MemberID, padding, y, 13*scale, stmtSecondary, false, 0)
+
+	y += 16 * scale
+	c.line(padding, y, right, stmtDivider, 1*scale)
+
+	// === FIGURES ===
+	row := func(label, value string) {
+		y += 24 * scale
+		c.text(label, padding, y, 13*scale, stmtSecondary, false, 0)
+		c.text(value, right, y, 13*scale, stmtText, false, 1)
+	}
+	row("ทุนเรือนหุ้นเฉลี่ยรายเดือน", formatBaht(p.AverageShares)+" บาท")
+	row("อัตราเงินปันผล", fmt.Sprintf("%.2f%%", p.DividendRate))
+	row("เงินปันผล", formatBaht(p.Dividend)+" บาท")
+	row("ดอกเบี้ยเงินกู้ที่ชำระ", formatBaht(p.LoanInterestPaid)+" บาท")
+	row("อัตราเงินเฉลี่ยคืน", fmt.Sprintf("%.2f%%", p.PatronageRate))
+	row("เงินเฉลี่ยคืน", formatBaht(p.PatronageRefund)+" บาท")
+
+	y += 16 * scale
+	c.line(padding, y, right, stmtDivider, 1*scale)
+
+	// === TOTAL ===
+	y += 30 * scale
+	c.text("รวมรับ", padding, y, 15*scale, stmtText, true, 0)
+	c.text(formatBaht(p.Total)+" บาท", right, y, 22*scale, stmtPrimary, true, 1)
+
+	// === PAYMENT STATUS ===
+	y += 30 * scale
+	switch p.Status {
+	case "paid":
+		c.text("โอนเข้าบัญชี "+accountMasked, padding, y, 12*scale, stmtSecondary, false, 0)
+		if p.PaidAt != nil {
+			y += 16 * scale
+			c.text("วันที่ "+thaiDate(*p.PaidAt), padding, y, 12*scale, stmtSecondary, false, 0)
+		}
+	case "unpaid":
+		c.text("ยังไม่ได้รับโอน กรุณาติดต่อสหกรณ์", padding, y, 12*scale, stmtSecondary, false, 0)
+	default:
+		c.text("ประมาณการ รอที่ประชุมอนุมัติ", padding, y, 12*scale, stmtSecondary, false, 0)
+	}
+
+	return c.png()
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/config"
+)
+
+// documentBlob is one stored R2 object shared by every document with the same content
+// (document_blobs). RefCount is the number of documents pointing at it; the object is deleted
+// when the last one goes. Documents without a blob own their object outright.
+type documentBlob struct {
+	SHA256      string    `bson:"sha256"`
+	R2Key       string    `bson:"r2_key"`
+	Size        int64     `bson:"size"`
+	ContentType string    `bson:"content_type"`
+	RefCount    int64     `bson:"ref_count"`
+	CreatedAt   time.Time `bson:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at"`
+}
+
+// DocumentStorageRequest optionally limits the storage report to one ref_id
+type DocumentStorageRequest struct {
+	RefID string `json:"ref_id,omitempty"`
+}
+
+// storeDocumentBlob registers the object just uploaded for doc. If the same content is
+// already stored, the new object is deleted and doc points at the existing one instead.
+// Returns whether the content was a duplicate.
+func storeDocumentBlob(ctx context.Context, db *mongo.Database, client *s3.Client, bucket string, doc *DocumentMetadata) (bool, error) {
+	blobs := db.Collection("document_blobs")
+	// A retry covers another upload of the same content registering it first
+	for attempt := 0; attempt < 3; attempt++ {
+		now := time.Now()
+		var blob documentBlob
+		err := blobs.FindOneAndUpdate(ctx, bson.M{"sha256": doc.SHA256},
+			bson.M{"$inc": bson.M{"ref_count": 1}, "$set": bson.M{"updated_at": now}}).Decode(&blob)
+		if err == nil {
+			if blob.R2Key != doc.R2Key {
+				deleteDocumentObject(client, bucket, doc.R2Key)
+				doc.R2Key = blob.R2Key
+			}
+			return true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return false, fmt.Errorf("failed to look up content: %w", err)
+		}
+
+		_, err = blobs.InsertOne(ctx, documentBlob{
+			SHA256:      doc.SHA256,
+			R2Key:       doc.R2Key,
+			Size:        doc.Size,
+			ContentType: doc.ContentType,
+			RefCount:    1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+		if err == nil {
+			return false, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return false, fmt.Errorf("failed to register content: %w", err)
+		}
+	}
+	return false, errDocumentSlotBusy
+}
+
+// hashR2Object streams an object from R2 and returns its SHA-256 (hex). Used for files
+// that reached R2 without passing through the API, such as direct uploads.
+func hashR2Object(ctx context.Context, client *s3.Client, bucket, key string, size int64) (string, error) {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	defer out.Body.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(out.Body, size+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if n != size {
+		return "", fmt.Errorf("%s is %d bytes, expected %d", key, n, size)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addDocumentBlobRef adds a reference to the blob holding key, for a new document sharing an
+// existing one's file. Returns false if the key is not a shared blob.
+func addDocumentBlobRef(ctx context.Context, db *mongo.Database, sha256, key string) (bool, error) {
+	if sha256 == "" {
+		return false, nil
+	}
+	res, err := db.Collection("document_blobs").UpdateOne(ctx,
+		bson.M{"sha256": sha256, "r2_key": key, "ref_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"ref_count": 1}, "$set": bson.M{"updated_at": time.Now()}})
+	if err != nil {
+		return false, fmt.Errorf("failed to reference content: %w", err)
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// releaseDocumentObject drops doc's reference to its file and deletes the R2 object when no
+// document uses it any more. Documents stored before deduplication own their object and it
+// is deleted directly. Failures are logged; at worst an unreferenced object is left.
+func releaseDocumentObject(ctx context.Context, db *mongo.Database, client *s3.Client, bucket string, doc DocumentMetadata) {
+	if doc.SHA256 != "" {
+		blobs := db.Collection("document_blobs")
+		var blob documentBlob
+		err := blobs.FindOneAndUpdate(ctx,
+			bson.M{"sha256": doc.SHA256, "r2_key": doc.R2Key, "ref_count": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"ref_count": -1}, "$set": bson.M{"updated_at": time.Now()}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&blob)
+		if err == nil && blob.RefCount > 0 {
+			return
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Printf("Failed to release blob %s: %v", doc.SHA256, err)
+			return
+		}
+		// The last reference is gone. Only the caller that removes the blob deletes the file,
+		// so an upload that picked the blob up again in the meantime keeps it.
+		res, err := blobs.DeleteOne(ctx, bson.M{"sha256": doc.SHA256, "r2_key": doc.R2Key, "ref_count": bson.M{"$lte": 0}})
+		if err != nil {
+			log.Printf("Failed to remove blob %s: %v", doc.SHA256, err)
+			return
+		}
+		if res.DeletedCount == 0 {
+			if n, err := blobs.CountDocuments(ctx, bson.M{"r2_key": doc.R2Key}); err != nil || n > 0 {
+				return
+			}
+		}
+	}
+
+	_, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(doc.R2Key),
+	})
+	if err != nil {
+		log.Printf("Failed to delete %s from R2: %v", doc.R2Key, err)
+	}
+}
+
+// DocumentStorageHandler reports how much storage deduplication saves: the size of every
+// document against the size of the objects actually stored. Purged versions are not counted.
+func DocumentStorageHandler(c echo.Context) error {
+	var req DocumentStorageRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	match := bson.M{"purged": bson.M{"$ne": true}}
+	if req.RefID != "" {
+		match["ref_id"] = req.RefID
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":  "$r2_key",
+			"size": bson.M{"$first": "$size"},
+			"docs": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"documents":     bson.M{"$sum": "$docs"},
+			"logical_bytes": bson.M{"$sum": bson.M{"$multiply": bson.A{"$size", "$docs"}}},
+			"objects":       bson.M{"$sum": 1},
+			"stored_bytes":  bson.M{"$sum": "$size"},
+		}}},
+	}
+	cursor, err := db.Collection("documents").Aggregate(ctx, pipeline)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to query database"})
+	}
+	var totals []struct {
+		Documents    int64 `bson:"documents"`
+		LogicalBytes int64 `bson:"logical_bytes"`
+		Objects      int64 `bson:"objects"`
+		StoredBytes  int64 `bson:"stored_bytes"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decode results"})
+	}
+
+	report := map[string]interface{}{
+		"documents":     int64(0),
+		"objects":       int64(0),
+		"logical_bytes": int64(0),
+		"stored_bytes":  int64(0),
+		"saved_bytes":   int64(0),
+		"saved_percent": 0.0,
+	}
+	if len(totals) > 0 {
+		t := totals[0]
+		saved := t.LogicalBytes - t.StoredBytes
+		report["documents"] = t.Documents
+		report["objects"] = t.Objects
+		report["logical_bytes"] = t.LogicalBytes
+		report["stored_bytes"] = t.StoredBytes
+		report["saved_bytes"] = saved
+		if t.LogicalBytes > 0 {
+			report["saved_percent"] = math.Round(float64(saved)/float64(t.LogicalBytes)*10000) / 100
+		}
+	}
+	if req.RefID == "" {
+		shared, err := db.Collection("document_blobs").CountDocuments(ctx, bson.M{"ref_count": bson.M{"$gt": 1}})
+		if err == nil {
+			report["shared_objects"] = shared
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   report,
+	})
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -19,6 +19,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
 )
 
 // DocumentMetadata represents the metadata stored in MongoDB
@@ -34,15 +35,25 @@ type DocumentMetadata struct {
 	ContentType string             `bson:"content_type" json:"content_type"`
 	Size        int64              `bson:"size" json:"size"`
 	UploadDate  time.Time          `bson:"upload_date" json:"upload_date"`
+	SHA256      string             `bson:"sha256,omitempty" json:"sha256,omitempty"` // hex, computed while uploading
+
+	// Versioning within a slot (ref_id + category); empty for documents uploaded without one
+	Version      int        `bson:"version,omitempty" json:"version,omitempty"`
+	Status       string     `bson:"status,omitempty" json:"status,omitempty"` // current, superseded
+	SupersededAt *time.Time `bson:"superseded_at,omitempty" json:"superseded_at,omitempty"`
+	SupersededBy string     `bson:"superseded_by,omitempty" json:"superseded_by,omitempty"` // doc id of the next version
+	RestoredFrom string     `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	Purged       bool       `bson:"purged,omitempty" json:"purged,omitempty"` // file removed by retention, metadata kept
 }
 
 // Request structs
 type DocumentListRequest struct {
-	RefID       string `json:"ref_id"`
-	Category    string `json:"category,omitempty"`
-	Limit       int64  `json:"limit,omitempty"`
-	Skip        int64  `json:"skip,omitempty"`
-	IncludeData bool   `json:"include_data,omitempty"`
+	RefID             string `json:"ref_id"`
+	Category          string `json:"category,omitempty"`
+	Limit             int64  `json:"limit,omitempty"`
+	Skip              int64  `json:"skip,omitempty"`
+	IncludeData       bool   `json:"include_data,omitempty"`
+	IncludeSuperseded bool   `json:"include_superseded,omitempty"`
 }
 
 type DocumentGetRequest struct {
@@ -58,109 +69,155 @@ type DocumentDeleteRequest struct {
 
 const MaxFileSize = 10 * 1024 * 1024 // 10MB
 
-// DocumentUploadHandler handles document upload (Image/PDF)
+// DocumentUploadHandler handles document upload (Image/PDF). The file is streamed to R2 as
+// it arrives, so ref_id must come before the file in the form; other fields may follow it.
 func DocumentUploadHandler(c echo.Context) error {
-	// 1. Parse Multipart Form
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required", "details": err.Error()})
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
 	}
-
-	// Validate Size
-	if file.Size > MaxFileSize {
-		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (10MB)"})
+	bucket := config.GetR2Bucket()
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
 	}
 
-	src, err := file.Open()
+	// 1. Read the form part by part and stream the file to R2
+	reader, err := c.Request().MultipartReader()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to open file"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid multipart form", "details": err.Error()})
 	}
-	defer src.Close()
+	ctx := c.Request().Context()
 
-	refID := c.FormValue("ref_id")
-	if refID == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id is required"})
-	}
+	fields := map[string]string{}
+	var metadata *DocumentMetadata
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if metadata != nil {
+				deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+			}
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid multipart form", "details": err.Error()})
+		}
 
-	category := c.FormValue("category")
-	description := c.FormValue("description")
-	uploadedBy := c.FormValue("uploaded_by")
-	tags := c.FormValue("tags")
+		if part.FormName() != "file" {
+			value, err := readFormField(part)
+			if err != nil {
+				if metadata != nil {
+					deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+				}
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+			fields[part.FormName()] = value
+			continue
+		}
+		if metadata != nil {
+			deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Only one file can be uploaded at a time"})
+		}
 
-	// Read file content
-	buffer := bytes.NewBuffer(nil)
-	if _, err := io.Copy(buffer, src); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read file"})
-	}
-	fileBytes := buffer.Bytes()
+		refID := fields["ref_id"]
+		if refID == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id is required and must be sent before file"})
+		}
 
-	// 2. Upload to R2
-	r2Client := config.GetR2Client()
-	if r2Client == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
-	}
-	bucket := config.GetR2Bucket()
+		// Generate unique R2 key
+		ext := strings.ToLower(filepath.Ext(part.FileName()))
+		uniqueID := uuid.New().String()
+		r2Key := fmt.Sprintf("%s/%s%s", refID, uniqueID, ext)
+
+		contentType := part.Header.Get("Content-Type")
+		// Fallback/Ensure PDF/Image types
+		if contentType == "" || contentType == "application/octet-stream" {
+			if ext == ".pdf" {
+				contentType = "application/pdf"
+			} else if ext == ".png" {
+				contentType = "image/png"
+			} else if ext == ".jpg" || ext == ".jpeg" {
+				contentType = "image/jpeg"
+			}
+		}
+
+		stored, err := services.UploadToR2(ctx, r2Client, bucket, services.R2Upload{
+			Key:         r2Key,
+			ContentType: contentType,
+			Body:        part,
+			MaxSize:     MaxFileSize,
+		})
+		if errors.Is(err, services.ErrFileTooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (10MB)"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
+		}
 
-	// Generate unique R2 key
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	uniqueID := uuid.New().String()
-	r2Key := fmt.Sprintf("%s/%s%s", refID, uniqueID, ext)
-
-	contentType := file.Header.Get("Content-Type")
-	// Fallback/Ensure PDF/Image types
-	if contentType == "" || contentType == "application/octet-stream" {
-		if ext == ".pdf" {
-			contentType = "application/pdf"
-		} else if ext == ".png" {
-			contentType = "image/png"
-		} else if ext == ".jpg" || ext == ".jpeg" {
-			contentType = "image/jpeg"
+		metadata = &DocumentMetadata{
+			ID:          primitive.NewObjectID(),
+			RefID:       refID,
+			Filename:    uniqueID + ext,
+			R2Key:       r2Key,
+			ContentType: contentType,
+			Size:        stored.Size,
+			SHA256:      stored.SHA256,
 		}
 	}
+	if metadata == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required"})
+	}
 
-	_, err = r2Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(r2Key),
-		Body:        bytes.NewReader(fileBytes),
-		ContentType: aws.String(contentType),
-	})
+	// mode=replace makes this the current version of the ref_id + category slot
+	replace := fields["mode"] == "replace"
+	if replace && fields["category"] == "" {
+		deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "category is required to replace a document"})
+	}
+
+	// 2. Store identical content once
+	deduplicated, err := storeDocumentBlob(ctx, db, r2Client, bucket, metadata)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
+		deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to store document", "details": err.Error()})
 	}
 
 	// 3. Save to MongoDB
-	db := config.GetDatabase()
-	if db == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
-	}
 	collection := db.Collection("documents")
 
-	metadata := DocumentMetadata{
-		ID:          primitive.NewObjectID(),
-		RefID:       refID,
-		Filename:    uniqueID + ext,
-		Category:    category,
-		Description: description,
-		UploadedBy:  uploadedBy,
-		R2Key:       r2Key,
-		ContentType: contentType,
-		Size:        file.Size,
-		UploadDate:  time.Now(),
-	}
-	if tags != "" {
+	metadata.Category = fields["category"]
+	metadata.Description = fields["description"]
+	metadata.UploadedBy = fields["uploaded_by"]
+	metadata.UploadDate = time.Now()
+	if tags := fields["tags"]; tags != "" {
 		metadata.Tags = strings.Split(tags, ",")
 	}
 
-	_, err = collection.InsertOne(context.TODO(), metadata)
+	if replace {
+		err = saveDocumentVersion(ctx, db, metadata)
+	} else {
+		_, err = collection.InsertOne(ctx, metadata)
+	}
 	if err != nil {
+		// Do not leave an object nothing refers to
+		releaseDocumentObject(context.Background(), db, r2Client, bucket, *metadata)
+		if errors.Is(err, errDocumentSlotBusy) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save metadata", "details": err.Error()})
 	}
+	if replace {
+		applyDocumentRetention(ctx, db, metadata.RefID, metadata.Category)
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status":   "success",
-		"message":  "Document uploaded successfully",
-		"filename": metadata.Filename,
-		"doc_id":   metadata.ID.Hex(),
+		"status":       "success",
+		"message":      "Document uploaded successfully",
+		"filename":     metadata.Filename,
+		"doc_id":       metadata.ID.Hex(),
+		"version":      metadata.Version,
+		"sha256":       metadata.SHA256,
+		"deduplicated": deduplicated,
 	})
 }
 
@@ -185,6 +242,10 @@ func DocumentListHandler(c echo.Context) error {
 	if req.Category != "" {
 		filter["category"] = req.Category
 	}
+	// Older versions are listed through /document/versions
+	if !req.IncludeSuperseded {
+		filter["status"] = bson.M{"$ne": DocumentStatusSuperseded}
+	}
 
 	opts := options.Find().SetSort(bson.M{"upload_date": -1})
 	if req.Limit > 0 {
@@ -219,6 +280,10 @@ func DocumentListHandler(c echo.Context) error {
 			"content_type": doc.ContentType,
 			"size":         doc.Size,
 		}
+		if doc.Version > 0 {
+			item["version"] = doc.Version
+			item["status"] = doc.Status
+		}
 
 		if req.IncludeData {
 			presignClient := config.GetR2PresignClient()
@@ -357,23 +422,17 @@ func DocumentDeleteHandler(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Document not found"})
 	}
 
-	// Delete from R2
-	r2Client := config.GetR2Client()
-	if r2Client != nil {
-		bucket := config.GetR2Bucket()
-		_, err := r2Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(metadata.R2Key),
-		})
-		if err != nil {
-			fmt.Printf("Warning: Failed to delete from R2: %v\n", err)
-		}
-	}
-
-	_, err = collection.DeleteOne(context.TODO(), filter)
+	res, err := collection.DeleteOne(context.TODO(), bson.M{"_id": metadata.ID})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete from database"})
 	}
 
+	// Delete from R2 unless other documents share the file. Purged versions have no file left,
+	// and a concurrent delete of the same document has already released it.
+	r2Client := config.GetR2Client()
+	if r2Client != nil && !metadata.Purged && res.DeletedCount > 0 {
+		releaseDocumentObject(context.TODO(), db, r2Client, config.GetR2Bucket(), metadata)
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "Document deleted"})
 }
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// Direct uploads: the client asks for a presigned PUT URL, sends the file straight to R2,
+// then confirms. The API never holds the file, so size is not bound by the function limits.
+const (
+	// defaultDirectUploadMaxMB caps direct uploads. Override with DOCUMENT_DIRECT_UPLOAD_MAX_MB.
+	defaultDirectUploadMaxMB = 100
+	directUploadURLExpiry    = 15 * time.Minute
+)
+
+// Upload statuses in document_uploads
+const (
+	DocumentUploadPending   = "pending"
+	DocumentUploadConfirmed = "confirmed"
+	DocumentUploadRejected  = "rejected"
+	DocumentUploadExpired   = "expired" // set by the document_uploads job
+)
+
+// directUploadTypes are the content types accepted for direct uploads, with the key extension
+var directUploadTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// DocumentUpload is an issued upload URL waiting for its file (document_uploads). The
+// constraints are kept server-side so the confirm call cannot change them.
+type DocumentUpload struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UploadID    string             `bson:"upload_id" json:"upload_id"`
+	RefID       string             `bson:"ref_id" json:"ref_id"`
+	R2Key       string             `bson:"r2_key" json:"-"`
+	Filename    string             `bson:"filename" json:"filename"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Category    string             `bson:"category,omitempty" json:"category,omitempty"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Tags        []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	UploadedBy  string             `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"`
+	Replace     bool               `bson:"replace,omitempty" json:"replace,omitempty"`
+	Status      string             `bson:"status" json:"status"` // pending, confirmed, rejected, expired
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	DocID       string             `bson:"doc_id,omitempty" json:"doc_id,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"` // upload URL expiry
+	ConfirmedAt *time.Time         `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+// DocumentUploadURLRequest asks for a presigned PUT URL
+type DocumentUploadURLRequest struct {
+	RefID       string `json:"ref_id"`
+	Filename    string `json:"filename,omitempty"` // original name, for reference only
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+	Tags        string `json:"tags,omitempty"` // comma separated, as in the upload form
+	UploadedBy  string `json:"uploaded_by,omitempty"`
+	Mode        string `json:"mode,omitempty"` // "replace" to make this the current version of the slot
+}
+
+// DocumentUploadConfirmRequest completes a direct upload
+type DocumentUploadConfirmRequest struct {
+	RefID    string `json:"ref_id"`
+	UploadID string `json:"upload_id"`
+}
+
+var errDocumentUploadMismatch = errors.New("uploaded file does not match the requested upload")
+
+func directUploadMaxSize() int64 {
+	if mb, err := strconv.Atoi(os.Getenv("DOCUMENT_DIRECT_UPLOAD_MAX_MB")); err == nil && mb > 0 {
+		return int64(mb) * 1024 * 1024
+	}
+	return defaultDirectUploadMaxMB * 1024 * 1024
+}
+
+// DocumentUploadURLHandler issues a presigned PUT URL for one file. Content-Type and
+// Content-Length are part of the signature, so R2 refuses a different type or size.
+func DocumentUploadURLHandler(c echo.Context) error {
+	var req DocumentUploadURLRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.RefID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id is required"})
+	}
+	ext, ok := directUploadTypes[strings.ToLower(req.ContentType)]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "content_type must be application/pdf, image/jpeg or image/png"})
+	}
+	maxSize := directUploadMaxSize()
+	if req.Size <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "size is required"})
+	}
+	if req.Size > maxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("File size exceeds limit (%dMB)", maxSize/1024/1024)})
+	}
+	replace := req.Mode == "replace"
+	if replace && req.Category == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "category is required to replace a document"})
+	}
+	if e := strings.ToLower(filepath.Ext(req.Filename)); e == ".jpeg" && ext == ".jpg" {
+		ext = e
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	presignClient := config.GetR2PresignClient()
+	if presignClient == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	uniqueID := uuid.New().String()
+	now := time.Now()
+	upload := DocumentUpload{
+		ID:          primitive.NewObjectID(),
+		UploadID:    uuid.New().String(),
+		RefID:       req.RefID,
+		R2Key:       fmt.Sprintf("%s/%s%s", req.RefID, uniqueID, ext),
+		Filename:    uniqueID + ext,
+		ContentType: strings.ToLower(req.ContentType),
+		Size:        req.Size,
+		Category:    req.Category,
+		Description: req.Description,
+		UploadedBy:  req.UploadedBy,
+		Replace:     replace,
+		Status:      DocumentUploadPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(directUploadURLExpiry),
+	}
+	if req.Tags != "" {
+		upload.Tags = strings.Split(req.Tags, ",")
+	}
+
+	presigned, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(upload.R2Key),
+		ContentType:   aws.String(upload.ContentType),
+		ContentLength: aws.Int64(upload.Size),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = directUploadURLExpiry
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate presigned URL", "details": err.Error()})
+	}
+
+	if _, err := db.Collection("document_uploads").InsertOne(ctx, upload); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save upload", "details": err.Error()})
+	}
+
+	headers := map[string]string{}
+	for name, values := range presigned.SignedHeader {
+		if strings.EqualFold(name, "Host") || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"upload_id":  upload.UploadID,
+		"url":        presigned.URL,
+		"method":     presigned.Method,
+		"headers":    headers,
+		"expires_at": upload.ExpiresAt,
+		"max_size":   maxSize,
+	})
+}
+
+// DocumentUploadConfirmHandler checks the uploaded object with HeadObject, hashes it and
+// records it. A file of the wrong size or type is deleted and the upload is rejected. The
+// hash is computed here because the file never passed through the API; content that is
+// already stored is deduplicated as for /document/upload.
+func DocumentUploadConfirmHandler(c echo.Context) error {
+	var req DocumentUploadConfirmRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.RefID == "" || req.UploadID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id and upload_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	// Long enough to read back a file of DOCUMENT_DIRECT_UPLOAD_MAX_MB for hashing
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	uploads := db.Collection("document_uploads")
+	var upload DocumentUpload
+	err := uploads.FindOne(ctx, bson.M{"upload_id": req.UploadID, "ref_id": req.RefID}).Decode(&upload)
+	if err == mongo.ErrNoDocuments {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Upload not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to query database"})
+	}
+	switch upload.Status {
+	case DocumentUploadConfirmed:
+		// Confirming twice returns the same document
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"status":   "success",
+			"message":  "Document already confirmed",
+			"filename": upload.Filename,
+			"doc_id":   upload.DocID,
+		})
+	case DocumentUploadExpired:
+		return c.JSON(http.StatusGone, map[string]string{"error": "Upload has expired, request a new upload URL"})
+	case DocumentUploadRejected:
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": errDocumentUploadMismatch.Error(), "details": upload.Reason})
+	}
+	// Past the grace period the document_uploads job may delete the file at any time
+	deadline := upload.ExpiresAt.Add(services.DocumentUploadGracePeriod)
+	if time.Now().After(deadline) {
+		return c.JSON(http.StatusGone, map[string]string{"error": "Upload has expired, request a new upload URL"})
+	}
+
+	head, err := r2Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(upload.R2Key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "File has not been uploaded yet"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check uploaded file", "details": err.Error()})
+	}
+
+	var reason string
+	if size := aws.ToInt64(head.ContentLength); size != upload.Size {
+		reason = fmt.Sprintf("size is %d bytes, expected %d", size, upload.Size)
+	} else if ct := strings.ToLower(aws.ToString(head.ContentType)); ct != upload.ContentType {
+		reason = fmt.Sprintf("content type is %q, expected %q", ct, upload.ContentType)
+	}
+	if reason != "" {
+		r2Client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(upload.R2Key)})
+		uploads.UpdateOne(ctx, bson.M{"_id": upload.ID, "status": DocumentUploadPending},
+			bson.M{"$set": bson.M{"status": DocumentUploadRejected, "reason": reason}})
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": errDocumentUploadMismatch.Error(), "details": reason})
+	}
+
+	sum, err := hashR2Object(ctx, r2Client, bucket, upload.R2Key, upload.Size)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read uploaded file", "details": err.Error()})
+	}
+
+	metadata := DocumentMetadata{
+		ID:          primitive.NewObjectID(),
+		RefID:       upload.RefID,
+		Filename:    upload.Filename,
+		Category:    upload.Category,
+		Description: upload.Description,
+		Tags:        upload.Tags,
+		UploadedBy:  upload.UploadedBy,
+		R2Key:       upload.R2Key,
+		ContentType: upload.ContentType,
+		Size:        upload.Size,
+		SHA256:      sum,
+		UploadDate:  time.Now(),
+	}
+
+	// Claim the upload first so that two confirm calls cannot record the file twice
+	now := time.Now()
+	res, err := uploads.UpdateOne(ctx,
+		bson.M{"_id": upload.ID, "status": DocumentUploadPending, "expires_at": bson.M{"$gte": now.Add(-services.DocumentUploadGracePeriod)}},
+		bson.M{"$set": bson.M{"status": DocumentUploadConfirmed, "doc_id": metadata.ID.Hex(), "confirmed_at": now}})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update upload"})
+	}
+	if res.ModifiedCount == 0 {
+		if now.After(deadline) {
+			return c.JSON(http.StatusGone, map[string]string{"error": "Upload has expired, request a new upload URL"})
+		}
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Upload is already being confirmed"})
+	}
+
+	deduplicated, err := storeDocumentBlob(ctx, db, r2Client, bucket, &metadata)
+	if err != nil {
+		deleteDocumentObject(r2Client, bucket, metadata.R2Key)
+	} else {
+		if upload.Replace {
+			err = saveDocumentVersion(ctx, db, &metadata)
+		} else {
+			_, err = db.Collection("documents").InsertOne(ctx, metadata)
+		}
+		if err != nil {
+			releaseDocumentObject(context.Background(), db, r2Client, bucket, metadata)
+		}
+	}
+	if err != nil {
+		// The file may be gone (deduplicated or released), so the upload cannot be retried
+		uploads.UpdateOne(context.Background(), bson.M{"_id": upload.ID},
+			bson.M{"$set": bson.M{"status": DocumentUploadRejected, "reason": "failed to save document: " + err.Error()},
+				"$unset": bson.M{"doc_id": "", "confirmed_at": ""}})
+		if errors.Is(err, errDocumentSlotBusy) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error() + ", request a new upload URL"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save metadata, request a new upload URL", "details": err.Error()})
+	}
+	if upload.Replace {
+		applyDocumentRetention(ctx, db, upload.RefID, upload.Category)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":       "success",
+		"message":      "Document uploaded successfully",
+		"filename":     metadata.Filename,
+		"doc_id":       metadata.ID.Hex(),
+		"version":      metadata.Version,
+		"sha256":       metadata.SHA256,
+		"deduplicated": deduplicated,
+	})
+}
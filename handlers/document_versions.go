@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/config"
+)
+
+// Document version statuses. Documents uploaded without a slot have no status.
+const (
+	DocumentStatusCurrent    = "current"
+	DocumentStatusSuperseded = "superseded"
+)
+
+// defaultDocumentVersionsKept is how many superseded versions per slot keep their file in R2.
+// Override with DOCUMENT_VERSIONS_KEEP, or DOCUMENT_VERSIONS_KEEP_<CATEGORY> for one category.
+const defaultDocumentVersionsKept = 5
+
+var (
+	errDocumentSlotBusy    = errors.New("another version of this document is being saved, please retry")
+	errDocumentVersionGone = errors.New("this version's file has been removed by the retention rules")
+)
+
+// DocumentSlotRequest identifies a document slot (ref_id + category)
+type DocumentSlotRequest struct {
+	RefID    string `json:"ref_id"`
+	Category string `json:"category"`
+}
+
+// DocumentRestoreRequest restores an old version as the new current version
+type DocumentRestoreRequest struct {
+	RefID      string `json:"ref_id"`
+	Category   string `json:"category"`
+	DocID      string `json:"doc_id"`
+	UploadedBy string `json:"uploaded_by,omitempty"`
+}
+
+func documentVersionsKept(category string) int {
+	key := "DOCUMENT_VERSIONS_KEEP_" + strings.ToUpper(category)
+	for _, v := range []string{os.Getenv(key), os.Getenv("DOCUMENT_VERSIONS_KEEP")} {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultDocumentVersionsKept
+}
+
+// slotFilter matches every version in a slot. Documents uploaded without mode=replace have no
+// status and are never part of a slot, so replacing or retention cannot touch them.
+func slotFilter(refID, category string) bson.M {
+	return bson.M{
+		"ref_id":   refID,
+		"category": category,
+		"status":   bson.M{"$in": bson.A{DocumentStatusCurrent, DocumentStatusSuperseded}},
+	}
+}
+
+// saveDocumentVersion inserts doc as the current version of its slot and supersedes the
+// previous one in the same transaction. The unique partial index on current versions stops
+// two uploads from both becoming current.
+func saveDocumentVersion(ctx context.Context, db *mongo.Database, doc *DocumentMetadata) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		coll := db.Collection("documents")
+		// Numbered after the highest version so deleted versions do not cause repeats
+		var latest DocumentMetadata
+		err := coll.FindOne(sc, slotFilter(doc.RefID, doc.Category),
+			options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to find latest version: %w", err)
+		}
+
+		now := time.Now()
+		filter := slotFilter(doc.RefID, doc.Category)
+		filter["status"] = DocumentStatusCurrent
+		_, err = coll.UpdateMany(sc, filter, bson.M{"$set": bson.M{
+			"status":        DocumentStatusSuperseded,
+			"superseded_at": now,
+			"superseded_by": doc.ID.Hex(),
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to supersede previous version: %w", err)
+		}
+
+		doc.Version = latest.Version + 1
+		doc.Status = DocumentStatusCurrent
+		if _, err := coll.InsertOne(sc, doc); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errDocumentSlotBusy
+			}
+			return nil, fmt.Errorf("failed to save metadata: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// applyDocumentRetention deletes the R2 files of superseded versions beyond the number kept
+// for the category. The metadata stays as history, marked purged.
+func applyDocumentRetention(ctx context.Context, db *mongo.Database, refID, category string) {
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return
+	}
+	filter := slotFilter(refID, category)
+	filter["status"] = DocumentStatusSuperseded
+	filter["purged"] = bson.M{"$ne": true}
+	cursor, err := db.Collection("documents").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}, {Key: "upload_date", Value: -1}}).
+			SetSkip(int64(documentVersionsKept(category))))
+	if err != nil {
+		log.Printf("Document retention for %s/%s failed: %v", refID, category, err)
+		return
+	}
+	var old []DocumentMetadata
+	if err := cursor.All(ctx, &old); err != nil {
+		log.Printf("Document retention for %s/%s failed: %v", refID, category, err)
+		return
+	}
+
+	bucket := config.GetR2Bucket()
+	for _, doc := range old {
+		// Mark first so that the file is released once even if retention runs twice
+		res, err := db.Collection("documents").UpdateOne(ctx, bson.M{"_id": doc.ID, "purged": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{"purged": true, "purged_at": time.Now()}})
+		if err != nil {
+			log.Printf("Document retention: failed to mark %s purged: %v", doc.ID.Hex(), err)
+			continue
+		}
+		if res.ModifiedCount > 0 {
+			releaseDocumentObject(ctx, db, r2Client, bucket, doc)
+		}
+	}
+}
+
+// DocumentVersionsHandler lists every version in a slot, newest first
+func DocumentVersionsHandler(c echo.Context) error {
+	var req DocumentSlotRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.RefID == "" || req.Category == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id and category are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := db.Collection("documents").Find(ctx, slotFilter(req.RefID, req.Category),
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}, {Key: "upload_date", Value: -1}}))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to query database"})
+	}
+	versions := []DocumentMetadata{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decode results"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":        "success",
+		"versions_kept": documentVersionsKept(req.Category),
+		"data":          versions,
+	})
+}
+
+// DocumentRestoreHandler makes an old version current again. The restored version holds its
+// own reference to the file (or its own copy), so retention on the old version cannot remove it.
+func DocumentRestoreHandler(c echo.Context) error {
+	var req DocumentRestoreRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.RefID == "" || req.Category == "" || req.DocID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ref_id, category and doc_id are required"})
+	}
+	oid, err := primitive.ObjectIDFromHex(req.DocID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid doc_id"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := slotFilter(req.RefID, req.Category)
+	filter["_id"] = oid
+	var old DocumentMetadata
+	if err := db.Collection("documents").FindOne(ctx, filter).Decode(&old); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Document not found"})
+	}
+	if old.Status == DocumentStatusCurrent {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "This version is already current"})
+	}
+	if old.Purged {
+		return c.JSON(http.StatusGone, map[string]string{"error": errDocumentVersionGone.Error()})
+	}
+
+	ext := strings.ToLower(filepath.Ext(old.R2Key))
+	uniqueID := uuid.New().String()
+	r2Key := old.R2Key
+	// A deduplicated file gains a reference; a file the old version owns is copied
+	shared, err := addDocumentBlobRef(ctx, db, old.SHA256, old.R2Key)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !shared {
+		r2Key = fmt.Sprintf("%s/%s%s", req.RefID, uniqueID, ext)
+		_, err = r2Client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(r2Key),
+			CopySource: aws.String((&url.URL{Path: bucket + "/" + old.R2Key}).EscapedPath()),
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to copy document", "details": err.Error()})
+		}
+	}
+
+	doc := old
+	doc.ID = primitive.NewObjectID()
+	doc.Filename = uniqueID + ext
+	doc.R2Key = r2Key
+	doc.UploadedBy = req.UploadedBy
+	doc.UploadDate = time.Now()
+	doc.SupersededAt = nil
+	doc.SupersededBy = ""
+	doc.RestoredFrom = old.ID.Hex()
+	if err := saveDocumentVersion(ctx, db, &doc); err != nil {
+		releaseDocumentObject(context.Background(), db, r2Client, bucket, doc)
+		if errors.Is(err, errDocumentSlotBusy) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	applyDocumentRetention(ctx, db, req.RefID, req.Category)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"message":  fmt.Sprintf("Version %d restored as version %d", old.Version, doc.Version),
+		"filename": doc.Filename,
+		"doc_id":   doc.ID.Hex(),
+		"version":  doc.Version,
+	})
+}
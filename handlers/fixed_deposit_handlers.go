@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/models"
+	"loan-dynamic-api/services"
+)
+
+// SaveFixedDepositProductRequest represents an officer creating or updating a fixed deposit product
+type SaveFixedDepositProductRequest struct {
+	OfficerID string `json:"officer_id"`
+	models.FixedDepositProduct
+}
+
+// OpenFixedDepositRequest represents a member placing money on a fixed deposit
+type OpenFixedDepositRequest struct {
+	MemberID            string  `json:"memberid"`
+	ProductCode         string  `json:"product_code"`
+	Amount              float64 `json:"amount"`
+	FundingAccountID    string  `json:"funding_account_id"`
+	MaturityInstruction string  `json:"maturity_instruction"` // renew_all (default), renew_principal, payout
+	PayoutAccountID     string  `json:"payout_account_id,omitempty"`
+	AccountName         string  `json:"account_name,omitempty"`
+}
+
+// WithdrawFixedDepositRequest represents a member closing a fixed deposit before maturity
+type WithdrawFixedDepositRequest struct {
+	MemberID        string `json:"memberid"`
+	AccountID       string `json:"account_id"`
+	PayoutAccountID string `json:"payout_account_id,omitempty"`
+}
+
+// ListFixedDepositProductsHandler returns the fixed deposit products on offer
+func ListFixedDepositProductsHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	products, err := services.ListFixedDepositProducts(ctx, db, c.QueryParam("all") == "true")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   products,
+	})
+}
+
+// SaveFixedDepositProductHandler creates or updates a product. Existing deposits keep
+// their contract terms; a new rate applies from their next renewal.
+func SaveFixedDepositProductHandler(c echo.Context) error {
+	var req SaveFixedDepositProductRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	product, err := services.SaveFixedDepositProduct(ctx, db, req.FixedDepositProduct)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   product,
+	})
+}
+
+// OpenFixedDepositHandler opens a fixed deposit funded from the member's savings account
+func OpenFixedDepositHandler(c echo.Context) error {
+	var req OpenFixedDepositRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.ProductCode == "" || req.FundingAccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid, product_code and funding_account_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	account, err := services.OpenFixedDeposit(ctx, db, services.OpenFixedDepositRequest{
+		MemberID:            req.MemberID,
+		ProductCode:         req.ProductCode,
+		Amount:              req.Amount,
+		FundingAccountID:    req.FundingAccountID,
+		MaturityInstruction: req.MaturityInstruction,
+		PayoutAccountID:     req.PayoutAccountID,
+		AccountName:         req.AccountName,
+	})
+	if err != nil {
+		return c.JSON(fixedDepositErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   account,
+	})
+}
+
+// QuoteEarlyWithdrawalHandler shows what the member would receive if the deposit were closed today
+func QuoteEarlyWithdrawalHandler(c echo.Context) error {
+	memberID := c.QueryParam("memberid")
+	if memberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	quote, err := services.QuoteEarlyWithdrawal(ctx, db, c.Param("accountID"), memberID, time.Now())
+	if err != nil {
+		return c.JSON(fixedDepositErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   quote,
+	})
+}
+
+// WithdrawFixedDepositEarlyHandler closes a fixed deposit before maturity
+func WithdrawFixedDepositEarlyHandler(c echo.Context) error {
+	var req WithdrawFixedDepositRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.AccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid and account_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	quote, err := services.WithdrawFixedDepositEarly(ctx, db, req.AccountID, req.MemberID, req.PayoutAccountID)
+	if err != nil {
+		return c.JSON(fixedDepositErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   quote,
+	})
+}
+
+func fixedDepositErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, services.ErrInvalidMaturityOption),
+		errors.Is(err, services.ErrInsufficientBalance):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrNotFixedDeposit),
+		errors.Is(err, services.ErrSourceNotFound), errors.Is(err, services.ErrDestNotFound),
+		errors.Is(err, services.ErrMemberNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFixedDepositMatured):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAccountOwner), errors.Is(err, services.ErrAccountNotActive):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/nfnt/resize"
+	"golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("file is not a valid JPEG, PNG or WebP image")
+	ErrImageTooSmall    = errors.New("image resolution is too low")
+	ErrImageBlank       = errors.New("image is blank or nearly a single colour")
+	ErrImageBlurry      = errors.New("image is too blurry, please retake the photo")
+	ErrImageTooLarge    = errors.New("image resolution is too high")
+)
+
+// imageRules are the checks an uploaded image must pass
+type imageRules struct {
+	MinLongSide  int  // pixels, after orientation is applied
+	MinShortSide int  // pixels, after orientation is applied
+	CheckQuality bool // reject blank and blurry photos
+}
+
+// KYC documents must be readable by an officer; profile pictures and signatures only need to decode
+var (
+	kycImageRules       = imageRules{MinLongSide: 800, MinShortSide: 600, CheckQuality: true}
+	profileImageRules   = imageRules{MinLongSide: 200, MinShortSide: 200}
+	signatureImageRules = imageRules{MinLongSide: 200, MinShortSide: 50}
+)
+
+const (
+	// Below this luminance variance the picture is essentially one flat colour
+	minImageVariance = 100.0
+	// Variance of the Laplacian on the downscaled grey image; low values mean no sharp edges
+	minImageSharpness = 40.0
+	// Quality checks run on a copy no larger than this, so results do not depend on camera resolution
+	qualitySampleSize = 1000
+	// A small compressed file can declare a huge canvas; refuse it before allocating the pixels.
+	// 40 megapixels covers phone cameras and is about 160MB once decoded to RGBA.
+	maxImagePixels = 40_000_000
+)
+
+// inspectedImage is an upload that passed inspection, re-encoded without metadata
+type inspectedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// imageCodecs are the decoders for each sniffed format
+var imageCodecs = map[string]struct {
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+}{
+	"jpeg": {jpeg.Decode, jpeg.DecodeConfig},
+	"png":  {png.Decode, png.DecodeConfig},
+	"webp": {webp.Decode, webp.DecodeConfig},
+}
+
+// inspectImage checks that data really is a JPEG, PNG or WebP image by its magic bytes and
+// by decoding it, rotates it upright from the EXIF orientation, enforces the rules and
+// re-encodes it. Re-encoding drops EXIF (including GPS) and any other embedded metadata.
+// PNG stays PNG to keep transparency; JPEG and WebP are stored as JPEG. The dimensions are
+// read from the header first, and images over maxImagePixels are refused without decoding.
+func inspectImage(data []byte, rules imageRules) (*inspectedImage, error) {
+	format := sniffImageFormat(data)
+	codec, ok := imageCodecs[format]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+	cfg, err := codec.decodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d, at most %d megapixels allowed", ErrImageTooLarge, cfg.Width, cfg.Height, maxImagePixels/1_000_000)
+	}
+	img, err := codec.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	long, short := w, h
+	if short > long {
+		long, short = short, long
+	}
+	if long < rules.MinLongSide || short < rules.MinShortSide {
+		return nil, fmt.Errorf("%w: %dx%d, at least %dx%d required", ErrImageTooSmall, w, h, rules.MinLongSide, rules.MinShortSide)
+	}
+
+	if rules.CheckQuality {
+		variance, sharpness := imageQuality(img)
+		if variance < minImageVariance {
+			return nil, ErrImageBlank
+		}
+		if sharpness < minImageSharpness {
+			return nil, ErrImageBlurry
+		}
+	}
+
+	out := &inspectedImage{Width: w, Height: h}
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, img)
+		out.ContentType, out.Ext = "image/png", ".png"
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		out.ContentType, out.Ext = "image/jpeg", ".jpg"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	out.Data = buf.Bytes()
+	return out, nil
+}
+
+// sniffImageFormat identifies the image format from the leading bytes, ignoring the
+// file name and the Content-Type the client sent
+func sniffImageFormat(data []byte) string {
+	switch {
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		return "jpeg"
+	case len(data) >= 8 && bytes.Equal(data[:8], []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return "webp"
+	}
+	return ""
+}
+
+// imageQuality returns the luminance variance and the variance of the Laplacian of a
+// downscaled grey copy of the image
+func imageQuality(img image.Image) (variance, sharpness float64) {
+	b := img.Bounds()
+	if b.Dx() > qualitySampleSize || b.Dy() > qualitySampleSize {
+		if b.Dx() >= b.Dy() {
+			img = resize.Resize(qualitySampleSize, 0, img, resize.Bilinear)
+		} else {
+			img = resize.Resize(0, qualitySampleSize, img, resize.Bilinear)
+		}
+		b = img.Bounds()
+	}
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(gray, gray.Bounds(), img, b.Min, draw.Src)
+
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+	var sum, sumSq float64
+	for _, p := range gray.Pix {
+		v := float64(p)
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(gray.Pix))
+	variance = sumSq/n - (sum/n)*(sum/n)
+
+	if w < 3 || h < 3 {
+		return variance, 0
+	}
+	var lSum, lSumSq float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*gray.Stride + x
+			l := 4*float64(gray.Pix[i]) -
+				float64(gray.Pix[i-1]) - float64(gray.Pix[i+1]) -
+				float64(gray.Pix[i-gray.Stride]) - float64(gray.Pix[i+gray.Stride])
+			lSum += l
+			lSumSq += l * l
+		}
+	}
+	ln := float64((w - 2) * (h - 2))
+	sharpness = lSumSq/ln - (lSum/ln)*(lSum/ln)
+	return variance, sharpness
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, or 1 if there is none
+func jpegOrientation(data []byte) int {
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // image data starts, no EXIF before it
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 14 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation finds tag 0x0112 in IFD0 of a TIFF-structured EXIF block
+func exifOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// applyOrientation returns the image as it should be displayed for an EXIF orientation.
+// Pixels are copied directly between RGBA buffers; At/Set per pixel is far too slow for
+// camera-sized images.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 { // 5-8 swap width and height
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored and rotated 270 clockwise
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored and rotated 90 clockwise
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 270 clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], row[x*4:x*4+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// RunJobHandler triggers a background job once. Used by Vercel Cron, where no
+// long-running scheduler exists. Requires "Authorization: Bearer $CRON_SECRET".
+func RunJobHandler(c echo.Context) error {
+	secret := os.Getenv("CRON_SECRET")
+	token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	name := c.Param("name")
+	known := false
+	for _, n := range services.JobNames() {
+		known = known || n == name
+	}
+	if !known {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Unknown job",
+			"jobs":  services.JobNames(),
+		})
+	}
+
+	started := time.Now()
+	if err := services.RunJob(context.Background(), db, name); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":      "success",
+		"job":         name,
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
+}
@@ -2,29 +2,31 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
-	"fmt"
-	"io"
+	"errors"
 	"net/http"
-	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/mongo/options"
     
 	"loan-dynamic-api/config"
+	"loan-dynamic-api/models"
+	"loan-dynamic-api/services"
 )
 
 // SubmitKYC handles the KYC submission (Images + Bank Info)
 func SubmitKYC(c echo.Context) error {
-	// Parse Multipart Form
+	// Parse Multipart Form (three images of up to 5MB each plus the text fields)
+	limitRequestBody(c, 3*5*1024*1024+multipartOverhead)
 	_, err := c.MultipartForm()
+	if isBodyTooLarge(err) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Request exceeds 16MB"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to parse multipart form"})
 	}
@@ -78,111 +80,118 @@ func SubmitKYC(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "member_id is required"})
 	}
 
-	// 2. Prepare R2 Upload
-	r2Client := config.GetR2Client()
-	if r2Client == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	// Only one submission may wait for review at a time
+	if err := services.CheckKYCSubmissionAllowed(context.TODO(), db, memberID); err != nil {
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
 	}
-	bucket := config.GetR2Bucket()
 
     // Struct to hold file info
     type KYCFile struct {
         KeyName string
         FileKey string // Form field name
-        DBField string
     }
 
     filesToUpload := []KYCFile{
-        {KeyName: "id_card", FileKey: "id_card_image", DBField: "kyc_id_card_image_key"},
-        {KeyName: "bank_book", FileKey: "bank_book_image", DBField: "kyc_bank_book_image_key"},
-        {KeyName: "selfie", FileKey: "selfie_image", DBField: "kyc_selfie_image_key"},
+        {KeyName: "id_card", FileKey: "id_card_image"},
+        {KeyName: "bank_book", FileKey: "bank_book_image"},
+        {KeyName: "selfie", FileKey: "selfie_image"},
     }
 
-    updateFields := bson.M{
-        "bank_id": bankID,
-        "bank_account_no": bankAccountNo,
-        "kyc_status": "pending",
-        "kyc_submitted_at": time.Now(),
-    }
+	// 2. Validate every field before anything is uploaded
+	fieldErrors := map[string]string{}
+	details, err := services.ValidateKYCDetails(context.TODO(), db, memberID, services.KYCDetails{
+		CitizenID:     c.FormValue("citizen_id"),
+		BankID:        bankID,
+		BankAccountNo: bankAccountNo,
+	})
+	var validationErr *services.KYCValidationError
+	if errors.As(err, &validationErr) {
+		for field, msg := range validationErr.Fields {
+			fieldErrors[field] = msg
+		}
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
 
-	// 3. Loop Upload Files
-    for _, fInfo := range filesToUpload {
-        fileHeader, err := c.FormFile(fInfo.FileKey)
-        if err != nil {
-            // It's possible some files are optional? For now assume all required as per UI.
-             // If error is http.ErrMissingFile, maybe skip? But we enforce all 3 in UI.
-             // Let's log and continue or error?
-             // If UI requires it, we error here.
-             if err == http.ErrMissingFile {
-                 return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing file: " + fInfo.FileKey})
-             }
-             continue  // Skip validation error if something else?
-        }
-        
-        // Validate Size/Type
-        if fileHeader.Size > 5*1024*1024 {
-             return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fInfo.FileKey + " exceeds 5MB"})
-        }
-        
-        ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-        // Generate Key: kyc/<member_id>/<type>_<uuid><ext>
-        // Adding UUID to avoid cache issues or overwrites if retrying
-        newUUID := uuid.New().String()
-        r2Key := fmt.Sprintf("kyc/%s/%s_%s%s", memberID, fInfo.KeyName, newUUID, ext)
-        
-        src, err := fileHeader.Open()
-        if err != nil {
-             return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to open " + fInfo.FileKey})
-        }
-        defer src.Close()
-        
-        buffer := bytes.NewBuffer(nil)
-        if _, err := io.Copy(buffer, src); err != nil {
-             return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read " + fInfo.FileKey})
-        }
+	images := map[string]*inspectedImage{}
+	for _, fInfo := range filesToUpload {
+		fileHeader, err := c.FormFile(fInfo.FileKey)
+		if err != nil {
+			fieldErrors[fInfo.FileKey] = "file is required"
+			continue
+		}
+		data, err := readFormFile(fileHeader, 5*1024*1024)
+		if errors.Is(err, services.ErrFileTooLarge) {
+			fieldErrors[fInfo.FileKey] = "file exceeds 5MB"
+			continue
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read " + fInfo.FileKey})
+		}
+		// Content checks run on the bytes; the file name and Content-Type are not trusted
+		img, err := inspectImage(data, kycImageRules)
+		if err != nil {
+			fieldErrors[fInfo.FileKey] = err.Error()
+			continue
+		}
+		images[fInfo.FileKey] = img
+	}
 
-        contentType := fileHeader.Header.Get("Content-Type")
-        if contentType == "" { contentType = "image/jpeg" } // Default
-
-        _, err = r2Client.PutObject(context.TODO(), &s3.PutObjectInput{
-            Bucket:      aws.String(bucket),
-            Key:         aws.String(r2Key),
-            Body:        bytes.NewReader(buffer.Bytes()),
-            ContentType: aws.String(contentType),
-        })
-        if err != nil {
-             return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload " + fInfo.KeyName})
-        }
-        
-        // Add to update map
-        updateFields[fInfo.DBField] = r2Key
-        updateFields[fInfo.DBField + "_url"] = "" // We store Key, URL can be generated presigned
-    }
-    
-    // 4. Update Database
-	db := config.GetDatabase()
-	if db == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	if len(fieldErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Validation failed",
+			"fields": fieldErrors,
+		})
 	}
-	collection := db.Collection("members")
 
-    // We assume memberid is the unique business key strings
-	filter := bson.M{"memberid": memberID}
-	update := bson.M{"$set": updateFields}
+	// 3. Prepare R2 Upload
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
 
-	result, err := collection.UpdateOne(context.TODO(), filter, update)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database update failed", "details": err.Error()})
+	submission := &models.KYCSubmission{
+		MemberID:      memberID,
+		CitizenID:     details.CitizenID,
+		BankID:        details.BankID,
+		BankAccountNo: details.BankAccountNo,
+	}
+	imageKeys := map[string]*string{
+		"id_card_image":   &submission.IDCardImageKey,
+		"bank_book_image": &submission.BankBookImageKey,
+		"selfie_image":    &submission.SelfieImageKey,
+	}
+	kycImages := make([]services.KYCImage, 0, len(filesToUpload))
+	for _, fInfo := range filesToUpload {
+		img := images[fInfo.FileKey]
+		kycImages = append(kycImages, services.KYCImage{
+			Name:        fInfo.KeyName,
+			Data:        img.Data,
+			ContentType: img.ContentType,
+			Ext:         img.Ext,
+			Target:      imageKeys[fInfo.FileKey],
+		})
 	}
 
-	if result.MatchedCount == 0 {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Member not found"})
+	// Score the selfie against the ID card photo for the reviewer
+	submission.FaceMatch = services.MatchKYCFaces(context.TODO(), images["selfie_image"].Data, images["id_card_image"].Data)
+
+	// 4. Stage the images, record the attempt in kyc_submissions and on the member
+	// document, then promote the images; nothing is kept if any step fails
+	if err := services.StoreKYCSubmission(context.TODO(), db, r2Client, bucket, submission, kycImages); err != nil {
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status": "success",
+		"status":  "success",
 		"message": "KYC submitted successfully",
-        "data": updateFields,
+		"data":    submission,
 	})
 }
 
@@ -200,6 +209,7 @@ func GetPendingKYC(c echo.Context) error {
         "memberid": 1,
         "name_th": 1, 
         "kyc_submitted_at": 1,
+        "kyc_face_match": 1,
     }
 
 	cursor, err := collection.Find(context.TODO(), filter, options.Find().SetProjection(projection))
@@ -224,6 +234,9 @@ func GetKYCDetail(c echo.Context) error {
     }
 
 	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
 	collection := db.Collection("members")
 
 	filter := bson.M{"memberid": memberID}
@@ -256,18 +269,55 @@ func GetKYCDetail(c echo.Context) error {
         }
     }
 
+	// Every attempt with its review, newest first, each with its own image links
+	submissions, err := services.ListKYCSubmissions(context.TODO(), db, memberID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	history := make([]map[string]interface{}, 0, len(submissions))
+	for _, sub := range submissions {
+		subImages := map[string]string{}
+		if presignClient != nil {
+			for name, key := range map[string]string{
+				"id_card":   sub.IDCardImageKey,
+				"bank_book": sub.BankBookImageKey,
+				"selfie":    sub.SelfieImageKey,
+			} {
+				if key == "" {
+					continue
+				}
+				req, err := presignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(key),
+				}, func(opts *s3.PresignOptions) {
+					opts.Expires = 1 * time.Hour
+				})
+				if err == nil {
+					subImages[name] = req.URL
+				}
+			}
+		}
+		history = append(history, map[string]interface{}{
+			"submission": sub,
+			"images":     subImages,
+		})
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
         "member": member,
         "images": images,
+		"submissions": history,
     })
 }
 
 // ReviewKYC handles approval or rejection
 type KYCReviewRequest struct {
     MemberID  string `json:"member_id"`
+    OfficerID string `json:"officer_id"`
     Status    string `json:"status"` // 'verified' or 'rejected'
     Reason    string `json:"reason,omitempty"`
     IsOfficer bool   `json:"is_officer"`
+	IDCardExpiry string `json:"id_card_expiry,omitempty"` // YYYY-MM-DD, caps how long the verification lasts
 }
 
 func ReviewKYC(c echo.Context) error {
@@ -281,39 +331,164 @@ func ReviewKYC(c echo.Context) error {
     }
 
 	db := config.GetDatabase()
-	collection := db.Collection("members")
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
 
-	fmt.Printf("DEBUG: ReviewKYC for MemberID: %s, Status: %s, IsOfficer: %v\n", req.MemberID, req.Status, req.IsOfficer)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	filter := bson.M{"memberid": req.MemberID}
-	
-	updateFields := bson.M{
-		"kyc_status": req.Status,
-		"kyc_reviewed_at": time.Now(),
-		"kyc_reject_reason": req.Reason,
-		"updatedat": time.Now(),
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	outcome, err := services.ReviewKYC(ctx, db, services.KYCReview{
+		MemberID:       req.MemberID,
+		OfficerID:      req.OfficerID,
+		Status:         req.Status,
+		Reason:         req.Reason,
+		PromoteOfficer: req.IsOfficer,
+		IDCardExpiry:   req.IDCardExpiry,
+	})
+	if err != nil {
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	// Promotions and higher-risk verifications wait for a second officer
+	if outcome.PendingApprovalID != "" {
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"status":      "pending_approval",
+			"message":     "KYC verification requires approval by a second officer",
+			"approval_id": outcome.PendingApprovalID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "KYC status updated",
+		"data":    outcome.Submission,
+	})
+}
+
+func kycErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidKYCDecision), errors.Is(err, services.ErrKYCRejectReasonEmpty),
+		errors.Is(err, services.ErrInvalidIDCardExpiry), errors.Is(err, services.ErrInvalidKYCQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrMemberNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrKYCSubmissionOpen), errors.Is(err, services.ErrNoOpenKYCSubmission),
+		errors.Is(err, services.ErrApprovalPending), errors.Is(err, services.ErrKYCClaimedByOther),
+		errors.Is(err, services.ErrKYCClaimNotHeld):
+		return http.StatusConflict
 	}
+	return http.StatusInternalServerError
+}
 
-	// If approved and designated as officer, change role
-	if req.Status == "verified" && req.IsOfficer {
-		fmt.Printf("DEBUG: Promoting Member %s to officer\n", req.MemberID)
-		updateFields["role"] = "officer"
-	} else {
-		fmt.Printf("DEBUG: NOT promoting. Status: %s, IsOfficer: %v\n", req.Status, req.IsOfficer)
+// GetKYCQueue returns the officer review queue: pending submissions with paging, filters,
+// time in queue, SLA state and who is working on each one
+func GetKYCQueue(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
 	}
 
-	update := bson.M{
-		"$set": updateFields,
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	officerID := c.QueryParam("officer_id")
+	if err := services.RequireOfficer(ctx, db, officerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
 	}
 
-	result, err := collection.UpdateOne(context.TODO(), filter, update)
+	page, _ := strconv.ParseInt(c.QueryParam("page"), 10, 64)
+	limit, _ := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	queue, err := services.ListKYCQueue(ctx, db, services.KYCQueueFilter{
+		OfficerID:     officerID,
+		SubmittedFrom: c.QueryParam("submitted_from"),
+		SubmittedTo:   c.QueryParam("submitted_to"),
+		Branch:        c.QueryParam("branch"),
+		Claim:         c.QueryParam("claim"),
+		SLABreached:   c.QueryParam("sla_breached") == "true",
+		Search:        c.QueryParam("q"),
+		Newest:        c.QueryParam("sort") == "newest",
+		Page:          page,
+		Limit:         limit,
+	})
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Update failed"})
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   queue,
+	})
+}
+
+// KYCClaimRequest identifies the submission an officer claims or releases
+type KYCClaimRequest struct {
+	OfficerID    string `json:"officer_id"`
+	SubmissionID string `json:"submission_id"`
+}
+
+// ClaimKYCSubmissionHandler reserves a submission for the officer. Call again to keep the
+// claim alive; it lapses after KYC_CLAIM_TIMEOUT_MINUTES without activity.
+func ClaimKYCSubmissionHandler(c echo.Context) error {
+	var req KYCClaimRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	sub, err := services.ClaimKYCSubmission(ctx, db, req.SubmissionID, req.OfficerID)
+	if err != nil {
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
 	}
-    
-    if result.MatchedCount == 0 {
-        return c.JSON(http.StatusNotFound, map[string]string{"error": "Member not found"})
-    }
 
-	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "KYC status updated"})
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   sub,
+	})
+}
+
+// ReleaseKYCClaimHandler hands a claimed submission back to the queue
+func ReleaseKYCClaimHandler(c echo.Context) error {
+	var req KYCClaimRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	if err := services.ReleaseKYCClaim(ctx, db, req.SubmissionID, req.OfficerID); err != nil {
+		return c.JSON(kycErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Submission released",
+	})
 }
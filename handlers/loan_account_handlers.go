@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// LoanDisburseRequest represents an officer paying out an approved loan
+type LoanDisburseRequest struct {
+	OfficerID     string `json:"officer_id"`
+	ApplicationID string `json:"application_id"`
+	AccountID     string `json:"account_id"`
+}
+
+// LoanRepayRequest represents a loan repayment from a deposit account
+type LoanRepayRequest struct {
+	ApplicationID string  `json:"application_id"`
+	AccountID     string  `json:"account_id"`
+	Amount        float64 `json:"amount"`
+}
+
+// DisburseLoanHandler pays an approved loan into the borrower's deposit account
+func DisburseLoanHandler(c echo.Context) error {
+	var req LoanDisburseRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.ApplicationID == "" || req.AccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "application_id and account_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	amount, err := services.DisburseLoan(ctx, db, req.ApplicationID, req.AccountID, req.OfficerID)
+	if err != nil {
+		return c.JSON(loanErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":           "success",
+		"message":          "Loan disbursed successfully",
+		"disbursed_amount": amount,
+	})
+}
+
+// RepayLoanHandler takes a loan repayment from the member's deposit account
+func RepayLoanHandler(c echo.Context) error {
+	var req LoanRepayRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.ApplicationID == "" || req.AccountID == "" || req.Amount <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "application_id, account_id and valid amount are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := services.RepayLoan(ctx, db, req.ApplicationID, req.AccountID, req.Amount)
+	if err != nil {
+		return c.JSON(loanErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Loan repayment recorded",
+		"data":    result,
+	})
+}
+
+// loanErrorStatus maps loan service errors to HTTP status codes
+func loanErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrLoanNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrLoanNotApproved),
+		errors.Is(err, services.ErrLoanNotDisbursed),
+		errors.Is(err, services.ErrRepaymentTooLarge):
+		return http.StatusConflict
+	}
+	return transferErrorStatus(err)
+}
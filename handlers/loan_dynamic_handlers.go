@@ -13,6 +13,7 @@ import (
     "go.mongodb.org/mongo-driver/mongo/options"
 
     "loan-dynamic-api/config"
+    "loan-dynamic-api/services"
 )
 
 // DynamicGatewayRequest represents dynamic request from frontend
@@ -98,6 +99,16 @@ func LoanDynamicCreate(c echo.Context) error {
         })
     }
 
+    // ฟิลด์ที่เปลี่ยนได้เฉพาะผ่าน endpoint เฉพาะ (ดู protectedFields)
+    stripProtectedFields(req.Collection, req.Data)
+    if req.Collection == "loan_applications" && hasLoanServiceStatus(req.Data) {
+        return c.JSON(http.StatusForbidden, map[string]interface{}{
+            "status":  "error",
+            "code":    403,
+            "message": "Loans are disbursed and closed only through /loan/disburse and /loan/repay",
+        })
+    }
+
     // เตรียม database และ context
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
@@ -372,6 +383,23 @@ func LoanDynamicUpdate(c echo.Context) error {
         })
     }
 
+    // ฟิลด์ที่เปลี่ยนได้เฉพาะผ่าน endpoint เฉพาะ (ดู protectedFields)
+    stripProtectedFields(req.Collection, req.Data)
+    if req.Upsert && filterHasProtectedFields(req.Collection, req.Filter) {
+        return c.JSON(http.StatusForbidden, map[string]interface{}{
+            "status":  "error",
+            "code":    403,
+            "message": "Upsert filter may not set protected fields",
+        })
+    }
+    if req.Collection == "loan_applications" && (hasLoanServiceStatus(req.Data) || (req.Upsert && hasLoanServiceStatus(req.Filter))) {
+        return c.JSON(http.StatusForbidden, map[string]interface{}{
+            "status":  "error",
+            "code":    403,
+            "message": "Loans are disbursed and closed only through /loan/disburse and /loan/repay",
+        })
+    }
+
     // เพิ่ม updated timestamp
     req.Data["updatedat"] = time.Now()
 
@@ -392,7 +420,8 @@ func LoanDynamicUpdate(c echo.Context) error {
     collection := db.Collection(req.Collection)
     opts := options.Update().SetUpsert(req.Upsert)
 
-    result, err := collection.UpdateOne(ctx, req.Filter, update, opts)
+    filter := guardLoanStatusFilter(req.Collection, req.Filter, req.Data)
+    result, err := collection.UpdateOne(ctx, filter, update, opts)
     if err != nil {
         return c.JSON(http.StatusInternalServerError, map[string]interface{}{
             "status":  "error",
@@ -561,8 +590,11 @@ func checkTransactionKYC(data map[string]interface{}) error {
         return fmt.Errorf("member profile not found")
     }
 
-    kycStatus, _ := member["kyc_status"].(string)
-    if kycStatus != "verified" {
+    // Expired verifications count as unverified, even before the expiry job has run
+    if !services.MemberKYCVerified(member, time.Now()) {
+        if kycStatus, _ := member["kyc_status"].(string); kycStatus == services.KYCStatusExpired || kycStatus == services.KYCStatusVerified {
+            return fmt.Errorf("KYC verification has expired, please verify your identity again")
+        }
         return fmt.Errorf("KYC verification required for this transaction")
     }
 
@@ -3,11 +3,9 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,6 +15,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
 )
 
 const MaxProfileImageSize = 5 * 1024 * 1024 // 5MB
@@ -24,45 +23,32 @@ const MaxProfileImageSize = 5 * 1024 * 1024 // 5MB
 // UploadProfileImageHandler handles profile image upload for members
 func UploadProfileImageHandler(c echo.Context) error {
 	// 1. Parse Multipart Form
+	limitRequestBody(c, MaxProfileImageSize+multipartOverhead)
 	file, err := c.FormFile("file")
+	if isBodyTooLarge(err) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (5MB)"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required", "details": err.Error()})
 	}
 
-	// Validate Size
-	if file.Size > MaxProfileImageSize {
-		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (5MB)"})
-	}
-
 	memberID := c.FormValue("memberid")
 	if memberID == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
 	}
 
-	// Validate file type
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".webp": true,
-	}
-	if !allowedExts[ext] {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Only JPG, PNG, and WebP images are allowed"})
+	// Validate the content: the extension and Content-Type header are not trusted
+	fileBytes, err := readFormFile(file, MaxProfileImageSize)
+	if errors.Is(err, services.ErrFileTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (5MB)"})
 	}
-
-	src, err := file.Open()
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to open file"})
-	}
-	defer src.Close()
-
-	// Read file content
-	buffer := bytes.NewBuffer(nil)
-	if _, err := io.Copy(buffer, src); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read file"})
 	}
-	fileBytes := buffer.Bytes()
+	img, err := inspectImage(fileBytes, profileImageRules)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 
 	// 2. Upload to R2
 	r2Client := config.GetR2Client()
@@ -72,25 +58,12 @@ func UploadProfileImageHandler(c echo.Context) error {
 	bucket := config.GetR2Bucket()
 
 	// Generate R2 key for profile image
-	r2Key := fmt.Sprintf("members/%s/profile%s", memberID, ext)
-
-	contentType := file.Header.Get("Content-Type")
-	// Fallback/Ensure image types
-	if contentType == "" || contentType == "application/octet-stream" {
-		if ext == ".png" {
-			contentType = "image/png"
-		} else if ext == ".jpg" || ext == ".jpeg" {
-			contentType = "image/jpeg"
-		} else if ext == ".webp" {
-			contentType = "image/webp"
-		}
-	}
+	r2Key := fmt.Sprintf("members/%s/profile%s", memberID, img.Ext)
 
-	_, err = r2Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(r2Key),
-		Body:        bytes.NewReader(fileBytes),
-		ContentType: aws.String(contentType),
+	stored, err := services.UploadToR2(context.TODO(), r2Client, bucket, services.R2Upload{
+		Key:         r2Key,
+		ContentType: img.ContentType,
+		Body:        bytes.NewReader(img.Data),
 	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
@@ -124,6 +97,7 @@ func UploadProfileImageHandler(c echo.Context) error {
 	update := bson.M{
 		"$set": bson.M{
 			"profile_image_key":        r2Key,
+			"profile_image_sha256":     stored.SHA256,
 			"profile_image_updated_at": time.Now(),
 		},
 	}
@@ -2,16 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 
 	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
 )
 
 // InternalTransferRequest represents the payload for internal transfer
@@ -72,149 +72,94 @@ func PerformInternalTransfer(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// 1. Get Source Account
-	var sourceAccount bson.M
-	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": sourceAccountID}).Decode(&sourceAccount)
+	outcome, err := services.SubmitTransfer(ctx, db, services.TransferRequest{
+		SourceAccountID: sourceAccountID,
+		DestAccountID:   destAccountID,
+		Amount:          amount,
+		Description:     description,
+	})
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Source account not found"})
+		response := map[string]interface{}{"error": err.Error()}
+		if outcome != nil && outcome.Limits != nil {
+			response["limits"] = outcome.Limits
+		}
+		return c.JSON(transferErrorStatus(err), response)
 	}
 
-	// 2. Check Balance
-	sourceBalance, _ := sourceAccount["balance"].(float64)
-	if sourceBalance < amount {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+	// Above the approval threshold the transfer waits for an officer
+	if outcome.PendingApprovalID != "" {
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"status":      "pending_approval",
+			"message":     "Transfer requires officer approval",
+			"approval_id": outcome.PendingApprovalID,
+			"limits":      outcome.Limits,
+		})
 	}
 
-	// 3. Get Destination Account
-	var destAccount bson.M
-	err = db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": destAccountID}).Decode(&destAccount)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Destination account not found"})
-	}
+	result := outcome.Result
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":         "success",
+		"message":        "Transfer completed successfully",
+		"transaction_id": result.SourceTxID,
+		"balance_after":  result.SourceBalanceAfter(),
+		"limits":         outcome.Limits,
+		"slip_info":      buildTransferSlip(result),
+	})
+}
 
-	destBalance, _ := destAccount["balance"].(float64)
-
-	// Since we are not using full transactions to keep it simple and portable (Atlas free tier might not support or setup is complex), 
-	// we use individual updates. For production, please use mongo.Session if supported.
-	
-	// Generate transaction IDs and timestamp outside session for scoping
-	now := time.Now()
-	sourceTxID := fmt.Sprintf("TXN-OUT-%d", now.UnixNano())
-	destTxID := fmt.Sprintf("TXN-IN-%d", now.UnixNano())
-
-	session, err := db.Client().StartSession()
-	if err == nil {
-		defer session.EndSession(ctx)
-		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-			if err := session.StartTransaction(); err != nil {
-				return err
-			}
-
-			// A. Deduct from Source
-			_, err = db.Collection("deposit_accounts").UpdateOne(sc, 
-				bson.M{"accountid": sourceAccountID}, 
-				bson.M{"$inc": bson.M{"balance": -amount}})
-			if err != nil {
-				session.AbortTransaction(sc)
-				return err
-			}
-
-			// B. Add to Destination
-			_, err = db.Collection("deposit_accounts").UpdateOne(sc, 
-				bson.M{"accountid": destAccountID}, 
-				bson.M{"$inc": bson.M{"balance": amount}})
-			if err != nil {
-				session.AbortTransaction(sc)
-				return err
-			}
-
-			// C. Create Transactions
-
-			sourceTx := bson.M{
-				"transactionid": sourceTxID,
-				"accountid":     sourceAccountID,
-				"type":          "transfer_out",
-				"amount":        amount,
-				"balanceafter":  sourceBalance - amount,
-				"datetime":       now,
-				"description":   fmt.Sprintf("%s (โอนให้ %s)", description, destAccount["accountname"]),
-				"referenceno":   destAccountID,
-				"status":        "completed",
-			}
-
-			destTx := bson.M{
-				"transactionid": destTxID,
-				"accountid":     destAccountID,
-				"type":          "transfer_in",
-				"amount":        amount,
-				"balanceafter":  destBalance + amount,
-				"datetime":       now,
-				"description":   fmt.Sprintf("%s (รับจาก %s)", description, sourceAccount["accountname"]),
-				"referenceno":   sourceAccountID,
-				"status":        "completed",
-			}
-
-			_, err = db.Collection("deposit_transactions").InsertMany(sc, []interface{}{sourceTx, destTx})
-			if err != nil {
-				session.AbortTransaction(sc)
-				return err
-			}
-
-			// // D. Notification for Receiver
-			// receiverMemberID, _ := destAccount["memberid"].(string)
-			// if receiverMemberID != "" {
-			// 	notification := bson.M{
-			// 		"notificationid": fmt.Sprintf("NOTI-%d", now.UnixNano()),
-			// 		"memberid":       receiverMemberID,
-			// 		"title":          "เงินเข้าบัญชี",
-			// 		"message":        fmt.Sprintf("คุณได้รับเงินโอนจำนวน %.2f บาท จาก %s", amount, sourceAccount["accountname"]),
-			// 		"type":           "success",
-			// 		"read":           false,
-			// 		"createdat":      now,
-			// 	}
-			// 	db.Collection("notifications").InsertOne(sc, notification)
-			// }
-
-			return session.CommitTransaction(sc)
-		})
+// transferErrorStatus maps transfer service errors to HTTP status codes
+func transferErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAmount),
+		errors.Is(err, services.ErrSameAccount),
+		errors.Is(err, services.ErrInsufficientBalance):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSourceNotFound),
+		errors.Is(err, services.ErrDestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAccountNotActive),
+		errors.Is(err, services.ErrLimitExceeded),
+		errors.Is(err, services.ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrApprovalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrDuplicateTransfer):
+		return http.StatusConflict
 	}
+	return http.StatusInternalServerError
+}
 
-	// Format masked account numbers for PDPA
-	maskAccount := func(accNo interface{}) string {
-		s, _ := accNo.(string)
-		if len(s) < 7 {
-			return s
-		}
-		return fmt.Sprintf("%s-xxx-%s", s[:3], s[len(s)-4:])
+// maskAccountNumber hides the middle of an account number for PDPA
+func maskAccountNumber(accNo interface{}) string {
+	s, _ := accNo.(string)
+	if len(s) < 7 {
+		return s
 	}
-	
+	return fmt.Sprintf("%s-xxx-%s", s[:3], s[len(s)-4:])
+}
+
+// buildTransferSlip creates the slip info from the committed transfer
+func buildTransferSlip(result *services.TransferResult) *SlipInfo {
 	qrVerifyBase := os.Getenv("QR_VERIFY_BASE_URL")
 	if qrVerifyBase == "" {
 		qrVerifyBase = "https://coopapp.com"
 	}
-	
-	slipInfo := &SlipInfo{
-		TransactionRef:  sourceTxID,
-		TransactionDate: now,
+
+	return &SlipInfo{
+		TransactionRef:  result.SourceTxID,
+		TransactionDate: result.DateTime,
 		Sender: AccountInfo{
-			Name:            fmt.Sprintf("%v", sourceAccount["accountname"]),
-			AccountNoMasked: maskAccount(sourceAccount["accountnumber"]),
+			Name:            fmt.Sprintf("%v", result.SourceAccount["accountname"]),
+			AccountNoMasked: maskAccountNumber(result.SourceAccount["accountnumber"]),
 			BankName:        "Coop Saving",
 		},
 		Receiver: AccountInfo{
-			Name:            fmt.Sprintf("%v", destAccount["accountname"]),
-			AccountNoMasked: maskAccount(destAccount["accountnumber"]),
+			Name:            fmt.Sprintf("%v", result.DestAccount["accountname"]),
+			AccountNoMasked: maskAccountNumber(result.DestAccount["accountnumber"]),
 			BankName:        "Coop Saving",
 			BankCode:        "COOP",
 		},
-		Amount:    amount,
-		QRPayload: fmt.Sprintf("%s/verify?ref=%s", qrVerifyBase, sourceTxID),
+		Amount:    result.Amount,
+		QRPayload: fmt.Sprintf("%s/verify?ref=%s", qrVerifyBase, result.SourceTxID),
 	}
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status":         "success",
-		"message":        "Transfer completed successfully",
-		"transaction_id": sourceTxID,
-		"slip_info":      slipInfo,
-	})
 }
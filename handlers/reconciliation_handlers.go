@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// RunReconciliationHandler recomputes balances from transaction history and stores a report
+func RunReconciliationHandler(c echo.Context) error {
+	var req struct {
+		OfficerID string `json:"officer_id"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	report, err := services.ReconcileBalances(ctx, db, req.OfficerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// ListReconciliationReportsHandler lists report summaries, newest first
+func ListReconciliationReportsHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	limit, _ := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if limit <= 0 {
+		limit = 30
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reports, err := services.ListReconciliationReports(ctx, db, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"count":  len(reports),
+		"data":   reports,
+	})
+}
+
+// GetReconciliationReportHandler returns one full report
+func GetReconciliationReportHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	report, err := services.GetReconciliationReport(ctx, db, c.Param("reportID"))
+	if err != nil {
+		if errors.Is(err, services.ErrReportNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// DownloadReconciliationReportCSV streams a report as CSV for officers
+func DownloadReconciliationReportCSV(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := services.GetReconciliationReport(ctx, db, c.Param("reportID"))
+	if err != nil {
+		if errors.Is(err, services.ErrReportNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	filename := fmt.Sprintf("reconciliation_%s_%s.csv", report.ReportDate, report.ReportID)
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// UTF-8 BOM so Excel shows Thai text correctly
+	c.Response().Write([]byte("\xEF\xBB\xBF"))
+
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{"issue", "accountid", "accountnumber", "transactionid", "datetime", "type", "amount",
+		"stored_or_previous_balance", "computed_or_expected_balance", "actual_balance_after", "difference", "reason"})
+
+	for _, m := range report.Mismatches {
+		w.Write([]string{"balance_mismatch", m.AccountID, m.AccountNumber, "", "", "", "",
+			money(m.StoredBalance), money(m.ComputedBalance), "", money(m.Difference),
+			fmt.Sprintf("%d transactions", m.TransactionCount)})
+	}
+	for _, b := range report.ChainBreaks {
+		w.Write([]string{"chain_break", b.AccountID, "", b.TransactionID, b.DateTime.Format(time.RFC3339), b.Type,
+			money(b.Amount), money(b.PreviousBalanceAfter), money(b.ExpectedBalanceAfter), money(b.ActualBalanceAfter),
+			money(b.ActualBalanceAfter - b.ExpectedBalanceAfter), b.Reason})
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/models"
+	"loan-dynamic-api/services"
+)
+
+// ScheduledTransferCreateRequest represents a new standing order
+type ScheduledTransferCreateRequest struct {
+	MemberID        string     `json:"memberid"`
+	SourceAccountID string     `json:"source_account_id"`
+	DestAccountID   string     `json:"dest_account_id"`
+	Amount          float64    `json:"amount"`
+	Description     string     `json:"description"`
+	Frequency       string     `json:"frequency"` // once, daily, weekly, monthly
+	DayOfMonth      int        `json:"day_of_month,omitempty"`
+	DayOfWeek       int        `json:"day_of_week,omitempty"`
+	RunAt           time.Time  `json:"run_at"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+}
+
+// ScheduledTransferRequest identifies a member's standing order
+type ScheduledTransferRequest struct {
+	MemberID   string `json:"memberid"`
+	ScheduleID string `json:"schedule_id,omitempty"`
+}
+
+// CreateScheduledTransfer creates a one-off or recurring transfer instruction
+func CreateScheduledTransfer(c echo.Context) error {
+	var req ScheduledTransferCreateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.SourceAccountID == "" || req.DestAccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid, source_account_id and dest_account_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	schedule, err := services.CreateScheduledTransfer(ctx, db, models.ScheduledTransfer{
+		MemberID:        req.MemberID,
+		SourceAccountID: req.SourceAccountID,
+		DestAccountID:   req.DestAccountID,
+		Amount:          req.Amount,
+		Description:     req.Description,
+		Frequency:       req.Frequency,
+		DayOfMonth:      req.DayOfMonth,
+		DayOfWeek:       req.DayOfWeek,
+		RunAt:           req.RunAt,
+		EndDate:         req.EndDate,
+	})
+	if err != nil {
+		status := transferErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"status": "success",
+		"data":   schedule,
+	})
+}
+
+// ListScheduledTransfers lists a member's standing orders
+func ListScheduledTransfers(c echo.Context) error {
+	var req ScheduledTransferRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	schedules, err := services.ListScheduledTransfers(ctx, db, req.MemberID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"count":  len(schedules),
+		"data":   schedules,
+	})
+}
+
+// CancelScheduledTransfer cancels a member's active standing order
+func CancelScheduledTransfer(c echo.Context) error {
+	var req ScheduledTransferRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.ScheduleID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid and schedule_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.CancelScheduledTransfer(ctx, db, req.MemberID, req.ScheduleID); err != nil {
+		if errors.Is(err, services.ErrScheduleNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "Scheduled transfer cancelled"})
+}
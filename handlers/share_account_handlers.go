@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// BuySharesRequest represents a member buying shares from a deposit account
+type BuySharesRequest struct {
+	MemberID    string `json:"memberid"`
+	ShareTypeID string `json:"share_type_id"`
+	Units       int64  `json:"units"`
+	AccountID   string `json:"account_id"`
+}
+
+// RedeemSharesRequest represents an officer redeeming a member's shares into a deposit account
+type RedeemSharesRequest struct {
+	OfficerID   string `json:"officer_id"`
+	MemberID    string `json:"memberid"`
+	ShareTypeID string `json:"share_type_id"`
+	Units       int64  `json:"units"`
+	AccountID   string `json:"account_id"`
+	Reason      string `json:"reason"`
+}
+
+// BuySharesHandler buys shares at the current share type price
+func BuySharesHandler(c echo.Context) error {
+	var req BuySharesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.ShareTypeID == "" || req.AccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid, share_type_id and account_id are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	record, err := services.BuyShares(ctx, db, services.ShareOrder{
+		MemberID:    req.MemberID,
+		ShareTypeID: req.ShareTypeID,
+		Units:       req.Units,
+		AccountID:   req.AccountID,
+	})
+	if err != nil {
+		return c.JSON(shareErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   record,
+	})
+}
+
+// RedeemSharesHandler returns shares to the co-op at their paid-up value
+func RedeemSharesHandler(c echo.Context) error {
+	var req RedeemSharesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.ShareTypeID == "" || req.AccountID == "" || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid, share_type_id, account_id and reason are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	record, err := services.RedeemShares(ctx, db, services.ShareOrder{
+		MemberID:    req.MemberID,
+		ShareTypeID: req.ShareTypeID,
+		Units:       req.Units,
+		AccountID:   req.AccountID,
+		OfficerID:   req.OfficerID,
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		return c.JSON(shareErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   record,
+	})
+}
+
+// GetShareHoldingsHandler returns a member's share counts and value per share type
+func GetShareHoldingsHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	holdings, err := services.GetShareHoldings(ctx, db, c.Param("memberID"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   holdings,
+	})
+}
+
+// GetShareTransactionsHandler lists a member's share purchases and redemptions
+func GetShareTransactionsHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	limit, _ := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if limit <= 0 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	txs, err := services.ListShareTransactions(ctx, db, c.Param("memberID"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   txs,
+	})
+}
+
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidShareUnits), errors.Is(err, services.ErrInsufficientBalance):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrShareTypeNotFound), errors.Is(err, services.ErrShareAccountMissing),
+		errors.Is(err, services.ErrSourceNotFound), errors.Is(err, services.ErrDestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrShareTypeInactive), errors.Is(err, services.ErrInsufficientShares),
+		errors.Is(err, services.ErrBelowMinimumShares), errors.Is(err, services.ErrSharesPledged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAccountOwner), errors.Is(err, services.ErrAccountNotActive):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// maxSignatureImageSize caps an officer's signature upload
+const maxSignatureImageSize = 2 * 1024 * 1024
+
+// UploadOfficerSignatureHandler stores the signature image printed on share certificates
+// (multipart: officer_id, file). Only officers can set a signature, and the image is
+// checked and re-encoded like profile pictures.
+func UploadOfficerSignatureHandler(c echo.Context) error {
+	limitRequestBody(c, maxSignatureImageSize+multipartOverhead)
+	file, err := c.FormFile("file")
+	if isBodyTooLarge(err) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (2MB)"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required"})
+	}
+	officerID := c.FormValue("officer_id")
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	r2Client := config.GetR2Client()
+	if r2Client == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, officerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	data, err := readFormFile(file, maxSignatureImageSize)
+	if errors.Is(err, services.ErrFileTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File size exceeds limit (2MB)"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read file"})
+	}
+	img, err := inspectImage(data, signatureImageRules)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	key := fmt.Sprintf("signatures/%s/%s%s", officerID, uuid.New().String(), img.Ext)
+	stored, err := services.UploadToR2(ctx, r2Client, bucket, services.R2Upload{
+		Key:         key,
+		ContentType: img.ContentType,
+		Body:        bytes.NewReader(img.Data),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
+	}
+
+	sig := services.OfficerSignature{
+		OfficerID:  officerID,
+		R2Key:      key,
+		SHA256:     stored.SHA256,
+		UploadedAt: time.Now(),
+	}
+	previous, err := services.SaveOfficerSignature(ctx, db, sig)
+	if err != nil {
+		deleteDocumentObject(r2Client, bucket, key)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if previous != "" && previous != key {
+		deleteDocumentObject(r2Client, bucket, previous)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Signature saved",
+		"data":    sig,
+	})
+}
+
+// IssueShareCertificateRequest represents an officer issuing a share certificate to a member
+type IssueShareCertificateRequest struct {
+	OfficerID string `json:"officer_id"`
+	MemberID  string `json:"memberid"`
+}
+
+// IssueShareCertificateHandler renders a share certificate PDF signed by the officer, stores it
+// in R2, lists it in the member's documents and returns a presigned download URL
+func IssueShareCertificateHandler(c echo.Context) error {
+	var req IssueShareCertificateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	r2Client := config.GetR2Client()
+	presignClient := config.GetR2PresignClient()
+	if r2Client == nil || presignClient == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	// 1. Officer signature image
+	sigKey, err := services.OfficerSignatureKey(ctx, db, req.OfficerID)
+	if err != nil {
+		return c.JSON(certificateErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+	obj, err := r2Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(sigKey),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load signature", "details": err.Error()})
+	}
+	signature, _, err := image.Decode(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Signature must be a PNG or JPEG image"})
+	}
+
+	// 2. Render the certificate
+	cert, err := services.PrepareShareCertificate(ctx, db, req.MemberID, req.OfficerID)
+	if err != nil {
+		return c.JSON(certificateErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	qrVerifyBase := os.Getenv("QR_VERIFY_BASE_URL")
+	if qrVerifyBase == "" {
+		qrVerifyBase = "https://coopapp.com"
+	}
+	verifyURL := fmt.Sprintf("%s/verify?certificate=%s", qrVerifyBase, cert.CertificateID)
+
+	pdfBytes, err := renderShareCertificate(cert, signature, services.MemberName(ctx, db, req.OfficerID), verifyURL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	sum := sha256.Sum256(pdfBytes)
+	cert.SHA256 = hex.EncodeToString(sum[:])
+
+	// 3. Upload to R2
+	filename := cert.CertificateID + ".pdf"
+	cert.R2Key = fmt.Sprintf("certificates/%s/%s", req.MemberID, filename)
+	_, err = r2Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(cert.R2Key),
+		Body:        bytes.NewReader(pdfBytes),
+		ContentType: aws.String("application/pdf"),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
+	}
+
+	// 4. List it in the member's documents and record the certificate for verification
+	doc := DocumentMetadata{
+		ID:          primitive.NewObjectID(),
+		RefID:       req.MemberID,
+		Filename:    filename,
+		Category:    "share_certificate",
+		Description: "ใบหุ้นเลขที่ " + cert.CertificateNo,
+		UploadedBy:  req.OfficerID,
+		R2Key:       cert.R2Key,
+		ContentType: "application/pdf",
+		Size:        int64(len(pdfBytes)),
+		UploadDate:  cert.IssuedAt,
+	}
+	if _, err := db.Collection("documents").InsertOne(ctx, doc); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save metadata", "details": err.Error()})
+	}
+	cert.DocumentID = doc.ID.Hex()
+	if err := services.SaveShareCertificate(ctx, db, cert); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// 5. Presigned download URL
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(bucket),
+		Key:                        aws.String(cert.R2Key),
+		ResponseContentDisposition: aws.String(fmt.Sprintf("attachment; filename=\"%s\"", filename)),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = 15 * time.Minute
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate download URL"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"url":        presigned.URL,
+		"expires_in": 900,
+		"data":       cert,
+	})
+}
+
+// VerifyShareCertificateHandler returns the details of a certificate for its verification QR code.
+// A certificate replaced by a newer one is reported as no longer valid.
+func VerifyShareCertificateHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cert, err := services.GetShareCertificate(ctx, db, c.Param("certificateID"))
+	if err != nil {
+		return c.JSON(certificateErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"valid":  cert.Status == "valid",
+		"data":   cert,
+	})
+}
+
+func certificateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrMemberNotFound), errors.Is(err, services.ErrCertificateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNoShares), errors.Is(err, services.ErrNoSignature):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
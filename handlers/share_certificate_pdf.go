@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/nfnt/resize"
+	"github.com/skip2/go-qrcode"
+
+	"loan-dynamic-api/models"
+)
+
+// Certificates are drawn on landscape A4 at 150 DPI, like statement pages
+const (
+	certWidth  = 1754
+	certHeight = 1240
+)
+
+// renderShareCertificate draws the certificate and wraps it in a single-page PDF
+func renderShareCertificate(cert *models.ShareCertificate, signature image.Image, officerName, verifyURL string) ([]byte, error) {
+	c := newDocCanvas(certWidth, certHeight)
+	w, h := float64(certWidth), float64(certHeight)
+	center := w / 2
+
+	// === BORDER: thick outer, thin inner ===
+	c.dc.SetRGB(stmtPrimary[0], stmtPrimary[1], stmtPrimary[2])
+	c.dc.SetLineWidth(10)
+	c.dc.DrawRectangle(40, 40, w-80, h-80)
+	c.dc.Stroke()
+	c.dc.SetLineWidth(2)
+	c.dc.DrawRectangle(62, 62, w-124, h-124)
+	c.dc.Stroke()
+
+	// === HEADER ===
+	if logo := loadCircularLogo(130); logo != nil {
+		c.dc.DrawImageAnchored(logo, int(center), 170, 0.5, 0.5)
+	}
+	c.text("เลขที่ "+cert.CertificateNo, w-110, 130, 26, stmtSecondary, false, 1)
+	c.text("สหกรณ์ รสพ.", center, 290, 44, stmtPrimary, true, 0.5)
+	c.text("ใบหุ้น", center, 380, 68, stmtText, true, 0.5)
+
+	// === BODY ===
+	c.text("ขอรับรองว่า", center, 480, 30, stmtSecondary, false, 0.5)
+	name := cert.MemberName
+	if name == "" {
+		name = cert.MemberID
+	}
+	c.text(name, center, 560, 50, stmtText, true, 0.5)
+	c.text("เลขทะเบียนสมาชิก "+cert.MemberID, center, 615, 28, stmtSecondary, false, 0.5)
+
+	units := strings.TrimSuffix(formatBaht(float64(cert.Units)), ".00")
+	c.text(fmt.Sprintf("เป็นผู้ถือหุ้นของสหกรณ์ จำนวน %s หุ้น", units), center, 700, 34, stmtText, false, 0.5)
+	c.text(fmt.Sprintf("มูลค่าหุ้นที่ชำระแล้ว %s บาท", formatBaht(cert.Value)), center, 765, 38, stmtPrimary, true, 0.5)
+	c.text("ออกให้ ณ วันที่ "+thaiDate(cert.IssuedAt), center, 830, 28, stmtSecondary, false, 0.5)
+
+	// === VERIFICATION QR (bottom left) ===
+	qr, err := qrcode.New(verifyURL, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	qr.DisableBorder = true
+	c.dc.DrawImage(qr.Image(190), 130, int(h)-340)
+	c.text("สแกนเพื่อตรวจสอบใบหุ้น", 130, h-120, 20, stmtSecondary, false, 0)
+	c.text(cert.CertificateID, 130, h-92, 16, stmtSecondary, false, 0)
+
+	// === SIGNATURE (bottom right) ===
+	sigCenter := w - 380
+	lineY := h - 200
+	if signature != nil {
+		b := signature.Bounds()
+		scaled := resize.Thumbnail(380, 150, signature, resize.Lanczos3)
+		if b.Dx() < 380 && b.Dy() < 150 {
+			scaled = signature
+		}
+		c.dc.DrawImageAnchored(scaled, int(sigCenter), int(lineY)-10, 0.5, 1)
+	}
+	c.line(sigCenter-220, lineY, sigCenter+220, stmtSecondary, 2)
+	if officerName != "" {
+		c.text("( "+officerName+" )", sigCenter, lineY+45, 26, stmtText, false, 0.5)
+	}
+	c.text("เจ้าหน้าที่ผู้มีอำนาจลงนาม", sigCenter, lineY+85, 22, stmtSecondary, false, 0.5)
+
+	// === PDF ===
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, c.dc.Image(), &jpeg.Options{Quality: 92}); err != nil {
+		return nil, fmt.Errorf("failed to encode certificate: %w", err)
+	}
+
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetTitle("Share Certificate "+cert.CertificateNo, true)
+	pdf.SetAuthor("สหกรณ์ รสพ.", true)
+	opts := gofpdf.ImageOptions{ImageType: "JPG"}
+	pdf.AddPage()
+	pdf.RegisterImageOptionsReader("certificate", opts, &img)
+	pdf.ImageOptions("certificate", 0, 0, 297, 210, false, opts, 0, "")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("failed to build PDF: %w", err)
+	}
+	return out.Bytes(), nil
+}
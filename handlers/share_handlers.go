@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"loan-dynamic-api/config"
 	"loan-dynamic-api/models"
+	"loan-dynamic-api/services"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -24,14 +28,31 @@ func CreateShareType(c echo.Context) error {
 		})
 	}
 
+	shareType.Name = strings.TrimSpace(shareType.Name)
+	if shareType.Price <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": services.ErrInvalidSharePrice.Error(),
+		})
+	}
+
 	shareType.ID = primitive.NewObjectID()
 	shareType.Status = "active" // Default to active
+	shareType.StatusReason = ""
+	shareType.StatusHistory = nil
 	shareType.CreatedAt = time.Now()
 	shareType.UpdatedAt = time.Now()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := services.ValidateShareTypeName(ctx, config.GetDatabase(), shareType.Name, primitive.NilObjectID); err != nil {
+		return c.JSON(shareTypeErrorStatus(err), map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
 	collection := config.GetDatabase().Collection("share_types")
 	_, err := collection.InsertOne(ctx, shareType)
 	if err != nil {
@@ -42,6 +63,15 @@ func CreateShareType(c echo.Context) error {
 		})
 	}
 
+	// Start the price history so later price changes keep the original price
+	if err := services.EnsureSharePriceHistory(ctx, config.GetDatabase(), &shareType); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"status":  "error",
+			"message": "Failed to record share price",
+			"error":   err.Error(),
+		})
+	}
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"status": "success",
 		"data":   shareType,
@@ -67,11 +97,57 @@ func UpdateShareType(c echo.Context) error {
 		})
 	}
 
-	updateData["updated_at"] = time.Now()
+	// Status changes go through activate/deactivate/delete so they are audited
+	if _, ok := updateData["status"]; ok {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "status cannot be updated directly, use the activate, deactivate or delete endpoints",
+		})
+	}
+	delete(updateData, "_id")
+	delete(updateData, "id")
+	delete(updateData, "status_reason")
+	delete(updateData, "status_history")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if rawName, ok := updateData["name"]; ok {
+		name, _ := rawName.(string)
+		name = strings.TrimSpace(name)
+		if err := services.ValidateShareTypeName(ctx, config.GetDatabase(), name, objectID); err != nil {
+			return c.JSON(shareTypeErrorStatus(err), map[string]interface{}{
+				"status":  "error",
+				"message": err.Error(),
+			})
+		}
+		updateData["name"] = name
+	}
+
+	// Price changes go through the price history instead of overwriting the price
+	if rawPrice, ok := updateData["price"]; ok {
+		price, _ := rawPrice.(float64)
+		effective, _ := updateData["effective_from"].(string)
+		effectiveFrom, err := services.ParseEffectiveFrom(effective)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  "error",
+				"message": err.Error(),
+			})
+		}
+		if _, err := services.SetShareTypePrice(ctx, config.GetDatabase(), id, price, effectiveFrom, "", ""); err != nil {
+			return c.JSON(shareTypeErrorStatus(err), map[string]interface{}{
+				"status":  "error",
+				"message": "Failed to update share price",
+				"error":   err.Error(),
+			})
+		}
+		delete(updateData, "price")
+		delete(updateData, "effective_from")
+	}
+
+	updateData["updated_at"] = time.Now()
+
 	collection := config.GetDatabase().Collection("share_types")
 	filter := bson.M{"_id": objectID}
 	update := bson.M{"$set": updateData}
@@ -139,28 +215,51 @@ func GetShareTypes(c echo.Context) error {
 	})
 }
 
-// DeleteShareType - Soft delete share type
+// ShareTypeStatusRequest represents an officer activating, deactivating or deleting a share type
+type ShareTypeStatusRequest struct {
+	OfficerID string `json:"officer_id" query:"officer_id"`
+	Reason    string `json:"reason" query:"reason"`
+	MigrateTo string `json:"migrate_to,omitempty" query:"migrate_to"` // delete only: share type to move holdings to
+}
+
+// DeleteShareType - Soft delete share type. Refused while members hold the type unless
+// migrate_to names an active share type to move their holdings to.
 func DeleteShareType(c echo.Context) error {
-	id := c.Param("id")
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
+	var req ShareTypeStatusRequest
+	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"status":  "error",
-			"message": "Invalid ID format",
+			"message": "Invalid request body",
+		})
+	}
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "reason is required",
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "error",
+			"message": "Database not connected",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	collection := config.GetDatabase().Collection("share_types")
-	
-    // Soft delete by setting status to inactive
-    update := bson.M{"$set": bson.M{"status": "inactive", "updated_at": time.Now()}}
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
-	
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	migrated, err := services.DeleteShareType(ctx, db, c.Param("id"), req.MigrateTo, req.OfficerID, req.Reason)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		return c.JSON(shareTypeErrorStatus(err), map[string]interface{}{
 			"status":  "error",
 			"message": "Failed to delete share type",
 			"error":   err.Error(),
@@ -168,7 +267,78 @@ func DeleteShareType(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"status": "success",
-		"message": "Share type deleted successfully (soft delete)",
+		"status":            "success",
+		"message":           "Share type deleted successfully (soft delete)",
+		"migrated_holdings": migrated,
+	})
+}
+
+// ActivateShareType reopens an inactive or deleted share type for purchases
+func ActivateShareType(c echo.Context) error {
+	return changeShareTypeStatus(c, services.ActivateShareType)
+}
+
+// DeactivateShareType stops new purchases of a share type; existing holdings are kept
+func DeactivateShareType(c echo.Context) error {
+	return changeShareTypeStatus(c, services.DeactivateShareType)
+}
+
+func changeShareTypeStatus(c echo.Context, change func(ctx context.Context, db *mongo.Database, shareTypeID, officerID, reason string) error) error {
+	var req ShareTypeStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "Invalid request body",
+		})
+	}
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"status":  "error",
+			"message": "reason is required",
+		})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "error",
+			"message": "Database not connected",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	if err := change(ctx, db, c.Param("id"), req.OfficerID, req.Reason); err != nil {
+		return c.JSON(shareTypeErrorStatus(err), map[string]interface{}{
+			"status":  "error",
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": "Share type status updated successfully",
 	})
 }
+
+func shareTypeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrShareTypeNameRequired), errors.Is(err, services.ErrInvalidSharePrice),
+		errors.Is(err, services.ErrPriceInPast), errors.Is(err, services.ErrInvalidMigrationTarget):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrShareTypeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrDuplicateShareTypeName), errors.Is(err, services.ErrShareTypeHeld),
+		errors.Is(err, services.ErrInvalidShareTypeStatusChange), errors.Is(err, services.ErrDuplicatePriceEntry):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
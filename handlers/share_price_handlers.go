@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// SetSharePriceRequest represents an officer setting or scheduling a share type price
+type SetSharePriceRequest struct {
+	OfficerID     string  `json:"officer_id"`
+	Price         float64 `json:"price"`
+	EffectiveFrom string  `json:"effective_from,omitempty"` // YYYY-MM-DD or RFC 3339, empty or today = now
+	Note          string  `json:"note,omitempty"`
+}
+
+// GetSharePriceTimelineHandler returns the past and scheduled prices of a share type
+func GetSharePriceTimelineHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	timeline, err := services.GetSharePriceTimeline(ctx, db, c.Param("id"))
+	if err != nil {
+		return c.JSON(sharePriceErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   timeline,
+	})
+}
+
+// SetSharePriceHandler sets a new share type price now or schedules it for a future date
+func SetSharePriceHandler(c echo.Context) error {
+	var req SetSharePriceRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	effectiveFrom, err := services.ParseEffectiveFrom(req.EffectiveFrom)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	entry, err := services.SetShareTypePrice(ctx, db, c.Param("id"), req.Price, effectiveFrom, req.Note, req.OfficerID)
+	if err != nil {
+		return c.JSON(sharePriceErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   entry,
+	})
+}
+
+func sharePriceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidSharePrice), errors.Is(err, services.ErrPriceInPast):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrShareTypeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrDuplicatePriceEntry):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
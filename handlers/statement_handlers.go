@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/models"
+	"loan-dynamic-api/services"
+)
+
+// GenerateStatementHandler renders a PDF statement for a deposit account or loan,
+// stores it in R2 and returns a presigned download URL
+func GenerateStatementHandler(c echo.Context) error {
+	var req struct {
+		MemberID    string `json:"memberid"`
+		AccountType string `json:"account_type"` // deposit, loan
+		AccountID   string `json:"account_id"`   // deposit accountid or loan applicationid
+		From        string `json:"from"`         // YYYY-MM-DD
+		To          string `json:"to"`           // YYYY-MM-DD
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.AccountID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid and account_id are required"})
+	}
+	if req.AccountType == "" {
+		req.AccountType = "deposit"
+	}
+
+	from, to, err := services.ParseStatementPeriod(req.From, req.To)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+	r2Client := config.GetR2Client()
+	presignClient := config.GetR2PresignClient()
+	if r2Client == nil || presignClient == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "R2 storage not configured"})
+	}
+	bucket := config.GetR2Bucket()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	data, err := services.BuildStatement(ctx, db, req.AccountType, req.AccountID, req.MemberID, from, to)
+	if err != nil {
+		return c.JSON(statementErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	// 1. Render pages and assemble the PDF
+	statementID := "STM-" + uuid.New().String()
+	generatedAt := time.Now()
+
+	qrVerifyBase := os.Getenv("QR_VERIFY_BASE_URL")
+	if qrVerifyBase == "" {
+		qrVerifyBase = "https://coopapp.com"
+	}
+	verifyURL := fmt.Sprintf("%s/verify?statement=%s", qrVerifyBase, statementID)
+
+	renderer, err := newStatementRenderer(data, statementID, verifyURL, generatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	pdfBytes, pageCount, err := renderer.Render()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	sum := sha256.Sum256(pdfBytes)
+
+	// 2. Upload to R2
+	r2Key := fmt.Sprintf("statements/%s/%s.pdf", req.MemberID, statementID)
+	_, err = r2Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(r2Key),
+		Body:        bytes.NewReader(pdfBytes),
+		ContentType: aws.String("application/pdf"),
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload to storage", "details": err.Error()})
+	}
+
+	// 3. Record the statement so the QR code can be verified
+	record := &models.Statement{
+		StatementID:    statementID,
+		MemberID:       req.MemberID,
+		AccountType:    data.AccountType,
+		AccountID:      data.AccountID,
+		AccountMasked:  maskAccountNumber(data.AccountNumber),
+		AccountName:    data.AccountName,
+		PeriodFrom:     data.From,
+		PeriodTo:       data.To,
+		OpeningBalance: data.OpeningBalance,
+		ClosingBalance: data.ClosingBalance,
+		LineCount:      len(data.Lines),
+		PageCount:      pageCount,
+		SHA256:         hex.EncodeToString(sum[:]),
+		R2Key:          r2Key,
+		GeneratedAt:    generatedAt,
+	}
+	if data.AccountType == "loan" {
+		record.AccountMasked = data.AccountNumber
+	}
+	if err := services.SaveStatement(ctx, db, record); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	// 4. Presigned download URL
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     aws.String(bucket),
+		Key:                        aws.String(r2Key),
+		ResponseContentDisposition: aws.String(fmt.Sprintf("attachment; filename=\"%s.pdf\"", statementID)),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = 15 * time.Minute
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate download URL"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"url":        presigned.URL,
+		"expires_in": 900,
+		"data":       record,
+	})
+}
+
+// VerifyStatementHandler returns the details of a statement for its verification QR code.
+// The endpoint is public, so only what is printed on the PDF is returned: the account
+// number stays masked and the member and account ids are left out.
+func VerifyStatementHandler(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	record, err := services.GetStatement(ctx, db, c.Param("statementID"))
+	if err != nil {
+		return c.JSON(statementErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"valid":  true,
+		"data": map[string]interface{}{
+			"statement_id":    record.StatementID,
+			"account_type":    record.AccountType,
+			"account_masked":  record.AccountMasked,
+			"account_name":    record.AccountName,
+			"period_from":     record.PeriodFrom,
+			"period_to":       record.PeriodTo,
+			"opening_balance": record.OpeningBalance,
+			"closing_balance": record.ClosingBalance,
+			"line_count":      record.LineCount,
+			"page_count":      record.PageCount,
+			"sha256":          record.SHA256,
+			"generated_at":    record.GeneratedAt,
+		},
+	})
+}
+
+func statementErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrStatementPeriod), errors.Is(err, services.ErrUnknownAccountType):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotAccountOwner):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrAccountNotFound), errors.Is(err, services.ErrStatementNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
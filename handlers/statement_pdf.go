@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/nfnt/resize"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+
+	"loan-dynamic-api/services"
+)
+
+// Statement pages are rendered with gg at 150 DPI on A4 (so Thai text shapes the
+// same way as on slips) and each page image is placed into the PDF.
+const (
+	stmtPageWidth  = 1240
+	stmtPageHeight = 1754
+	stmtMargin     = 80.0
+	stmtRowHeight  = 46.0
+	stmtFooterTop  = float64(stmtPageHeight) - 220
+)
+
+var thaiShortMonths = []string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.", "ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."}
+
+// thaiDate formats a date in the Buddhist era, e.g. "5 มี.ค. 2568"
+func thaiDate(t time.Time) string {
+	t = t.In(services.Bangkok)
+	return fmt.Sprintf("%d %s %d", t.Day(), thaiShortMonths[t.Month()-1], t.Year()+543)
+}
+
+// statementRenderer draws statement pages with cached font faces
+type statementRenderer struct {
+	data        *services.StatementData
+	statementID string
+	verifyURL   string
+	generatedAt time.Time
+	regularPath string
+	boldPath    string
+	faces       map[string]font.Face
+	logo        image.Image
+	qr          image.Image
+}
+
+// thaiFontPaths finds the regular and bold Sarabun fonts, as the slip renderer does
+func thaiFontPaths() (string, string) {
+	fontPath := "./assets/fonts/Sarabun.ttf"
+	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
+		fontPath = "/app/assets/fonts/Sarabun.ttf"
+	}
+	if _, err := os.Stat(fontPath); os.IsNotExist(err) {
+		fontPath = "/System/Library/Fonts/Supplemental/Arial Unicode.ttf"
+	}
+
+	boldFontPath := "./assets/fonts/Sarabun Bold.ttf"
+	if _, err := os.Stat(boldFontPath); os.IsNotExist(err) {
+		boldFontPath = "/app/assets/fonts/Sarabun Bold.ttf"
+	}
+	if _, err := os.Stat(boldFontPath); os.IsNotExist(err) {
+		boldFontPath = fontPath
+	}
+	return fontPath, boldFontPath
+}
+
+func newStatementRenderer(data *services.StatementData, statementID, verifyURL string, generatedAt time.Time) (*statementRenderer, error) {
+	fontPath, boldFontPath := thaiFontPaths()
+
+	qr, err := qrcode.New(verifyURL, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	qr.DisableBorder = true
+
+	return &statementRenderer{
+		data:        data,
+		statementID: statementID,
+		verifyURL:   verifyURL,
+		generatedAt: generatedAt,
+		regularPath: fontPath,
+		boldPath:    boldFontPath,
+		faces:       map[string]font.Face{},
+		logo:        loadCircularLogo(90),
+		qr:          qr.Image(150),
+	}, nil
+}
+
+// loadCircularLogo returns the co-op logo cropped to a circle, or nil if it is missing
+func loadCircularLogo(size uint) image.Image {
+	logoPath := "./assets/pic/logoCoop.jpg"
+	if _, err := os.Stat(logoPath); os.IsNotExist(err) {
+		logoPath = "/app/assets/pic/logoCoop.jpg"
+	}
+	f, err := os.Open(logoPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	img, err := jpeg.Decode(f)
+	if err != nil {
+		return nil
+	}
+
+	// Center crop to a square
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		img = sub.SubImage(image.Rect(x0, y0, x0+side, y0+side))
+	}
+
+	resized := resize.Resize(size, size, img, resize.Lanczos3)
+	dc := gg.NewContext(int(size), int(size))
+	dc.DrawCircle(float64(size)/2, float64(size)/2, float64(size)/2)
+	dc.Clip()
+	dc.DrawImage(resized, 0, 0)
+	return dc.Image()
+}
+
+func (r *statementRenderer) setFont(dc *gg.Context, size float64, bold bool) {
+	path := r.regularPath
+	if bold {
+		path = r.boldPath
+	}
+	key := fmt.Sprintf("%s|%.1f", path, size)
+	face, ok := r.faces[key]
+	if !ok {
+		var err error
+		face, err = gg.LoadFontFace(path, size)
+		if err != nil {
+			return
+		}
+		r.faces[key] = face
+	}
+	dc.SetFontFace(face)
+}
+
+// text draws a string; align is 0 for left, 1 for right-aligned at x
+func (r *statementRenderer) text(dc *gg.Context, s string, x, y, size float64, rgb [3]float64, bold bool, align float64) {
+	r.setFont(dc, size, bold)
+	dc.SetRGB(rgb[0], rgb[1], rgb[2])
+	dc.DrawStringAnchored(s, x, y, align, 0)
+}
+
+// fit shortens s with an ellipsis until it is narrower than maxWidth
+func (r *statementRenderer) fit(dc *gg.Context, s string, size, maxWidth float64) string {
+	r.setFont(dc, size, false)
+	if w, _ := dc.MeasureString(s); w <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if w, _ := dc.MeasureString(string(runes) + "…"); w <= maxWidth {
+			break
+		}
+	}
+	return string(runes) + "…"
+}
+
+var (
+	stmtPrimary   = [3]float64{0.0, 0.424, 0.278}   // #006C47
+	stmtText      = [3]float64{0.129, 0.129, 0.129} // #212121
+	stmtSecondary = [3]float64{0.459, 0.459, 0.459} // #757575
+	stmtDivider   = [3]float64{0.878, 0.878, 0.878} // #E0E0E0
+	stmtStripe    = [3]float64{0.961, 0.973, 0.965}
+)
+
+// pages splits the statement lines into pages. The first page also holds the summary box.
+func (r *statementRenderer) pages() [][]services.StatementLine {
+	firstRows := int((stmtFooterTop - r.tableTop(true) - stmtRowHeight) / stmtRowHeight)
+	otherRows := int((stmtFooterTop - r.tableTop(false) - stmtRowHeight) / stmtRowHeight)
+
+	lines := r.data.Lines
+	pages := [][]services.StatementLine{}
+	n := firstRows
+	for {
+		if len(lines) <= n {
+			pages = append(pages, lines)
+			break
+		}
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+		n = otherRows
+	}
+	return pages
+}
+
+func (r *statementRenderer) tableTop(first bool) float64 {
+	if first {
+		return 560
+	}
+	return 360
+}
+
+// Render draws every page and returns the assembled PDF and its page count
+func (r *statementRenderer) Render() ([]byte, int, error) {
+	pages := r.pages()
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Statement "+r.statementID, true)
+	pdf.SetAuthor("สหกรณ์ รสพ.", true)
+	pdf.SetCreator("coopapi", true)
+	pdf.SetCreationDate(r.generatedAt)
+
+	for i, rows := range pages {
+		img := r.renderPage(i, len(pages), rows)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, 0, fmt.Errorf("failed to encode page %d: %w", i+1, err)
+		}
+
+		name := fmt.Sprintf("page-%d", i+1)
+		opts := gofpdf.ImageOptions{ImageType: "JPG"}
+		pdf.AddPage()
+		pdf.RegisterImageOptionsReader(name, opts, &buf)
+		pdf.ImageOptions(name, 0, 0, 210, 297, false, opts, 0, "")
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, 0, fmt.Errorf("failed to build PDF: %w", err)
+	}
+	return out.Bytes(), len(pages), nil
+}
+
+func (r *statementRenderer) renderPage(index, total int, rows []services.StatementLine) image.Image {
+	dc := gg.NewContext(stmtPageWidth, stmtPageHeight)
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+
+	right := float64(stmtPageWidth) - stmtMargin
+	d := r.data
+
+	// === HEADER: logo, co-op name, title ===
+	if r.logo != nil {
+		dc.DrawImage(r.logo, int(stmtMargin), 60)
+	} else {
+		dc.SetRGB(stmtPrimary[0], stmtPrimary[1], stmtPrimary[2])
+		dc.DrawCircle(stmtMargin+45, 105, 45)
+		dc.Fill()
+	}
+	r.text(dc, "สหกรณ์ รสพ.", stmtMargin+110, 118, 34, stmtPrimary, true, 0)
+
+	title := "รายการเดินบัญชีเงินฝาก"
+	if d.AccountType == "loan" {
+		title = "รายการเดินบัญชีเงินกู้"
+	}
+	r.text(dc, title, right, 100, 30, stmtText, true, 1)
+	r.text(dc, "STATEMENT OF ACCOUNT", right, 136, 18, stmtSecondary, false, 1)
+
+	dc.SetRGB(stmtPrimary[0], stmtPrimary[1], stmtPrimary[2])
+	dc.SetLineWidth(3)
+	dc.DrawLine(stmtMargin, 175, right, 175)
+	dc.Stroke()
+
+	// === ACCOUNT DETAILS ===
+	y := 225.0
+	accountLabel, accountNo := "เลขที่บัญชี", maskAccountNumber(d.AccountNumber)
+	if d.AccountType == "loan" {
+		accountLabel, accountNo = "เลขที่สัญญา", d.AccountNumber
+	}
+	r.text(dc, "ชื่อบัญชี", stmtMargin, y, 20, stmtSecondary, false, 0)
+	r.text(dc, r.fit(dc, d.AccountName, 22, 520), stmtMargin+150, y, 22, stmtText, true, 0)
+	r.text(dc, "เลขที่สมาชิก", 760, y, 20, stmtSecondary, false, 0)
+	r.text(dc, d.MemberID, right, y, 22, stmtText, true, 1)
+
+	y += 40
+	r.text(dc, accountLabel, stmtMargin, y, 20, stmtSecondary, false, 0)
+	r.text(dc, accountNo, stmtMargin+150, y, 22, stmtText, true, 0)
+	r.text(dc, "รอบระยะเวลา", 760, y, 20, stmtSecondary, false, 0)
+	r.text(dc, fmt.Sprintf("%s - %s", thaiDate(d.From), thaiDate(d.To)), right, y, 22, stmtText, true, 1)
+
+	// === SUMMARY (first page only) ===
+	if index == 0 {
+		boxTop := 310.0
+		dc.SetRGB(stmtStripe[0], stmtStripe[1], stmtStripe[2])
+		dc.DrawRoundedRectangle(stmtMargin, boxTop, right-stmtMargin, 190, 12)
+		dc.Fill()
+
+		debitLabel, creditLabel, balanceLabel := "รวมถอน/โอนออก", "รวมฝาก/รับโอน", "ยอดคงเหลือ"
+		if d.AccountType == "loan" {
+			debitLabel, creditLabel, balanceLabel = "รวมรับเงินกู้", "รวมชำระ", "เงินต้นคงค้าง"
+		}
+		cells := []struct {
+			label string
+			value float64
+		}{
+			{"ยอดยกมา " + thaiDate(d.From), d.OpeningBalance},
+			{debitLabel, d.TotalDebit},
+			{creditLabel, d.TotalCredit},
+			{balanceLabel + " " + thaiDate(d.To), d.ClosingBalance},
+		}
+		cellWidth := (right - stmtMargin) / float64(len(cells))
+		for i, cell := range cells {
+			x := stmtMargin + cellWidth*float64(i) + 24
+			r.text(dc, cell.label, x, boxTop+70, 18, stmtSecondary, false, 0)
+			r.text(dc, formatBaht(cell.value), x, boxTop+125, 28, stmtText, true, 0)
+		}
+		r.text(dc, fmt.Sprintf("จำนวน %d รายการ", len(d.Lines)), stmtMargin+24, boxTop+170, 16, stmtSecondary, false, 0)
+	}
+
+	// === TABLE ===
+	debitHead, creditHead, balanceHead := "ถอน", "ฝาก", "คงเหลือ"
+	if d.AccountType == "loan" {
+		debitHead, creditHead, balanceHead = "รับเงินกู้", "ชำระ", "เงินต้นคงค้าง"
+	}
+	colDate := stmtMargin + 12
+	colDesc := stmtMargin + 190
+	colDebit := 850.0
+	colCredit := 1010.0
+	colBalance := right - 12
+
+	y = r.tableTop(index == 0)
+	dc.SetRGB(stmtPrimary[0], stmtPrimary[1], stmtPrimary[2])
+	dc.DrawRectangle(stmtMargin, y, right-stmtMargin, stmtRowHeight)
+	dc.Fill()
+	white := [3]float64{1, 1, 1}
+	r.text(dc, "วันที่", colDate, y+31, 18, white, true, 0)
+	r.text(dc, "รายการ", colDesc, y+31, 18, white, true, 0)
+	r.text(dc, debitHead, colDebit, y+31, 18, white, true, 1)
+	r.text(dc, creditHead, colCredit, y+31, 18, white, true, 1)
+	r.text(dc, balanceHead, colBalance, y+31, 18, white, true, 1)
+	y += stmtRowHeight
+
+	if len(rows) == 0 && index == 0 {
+		r.text(dc, "ไม่มีรายการเคลื่อนไหวในรอบระยะเวลานี้", float64(stmtPageWidth)/2, y+60, 20, stmtSecondary, false, 0.5)
+	}
+	for i, line := range rows {
+		if i%2 == 1 {
+			dc.SetRGB(stmtStripe[0], stmtStripe[1], stmtStripe[2])
+			dc.DrawRectangle(stmtMargin, y, right-stmtMargin, stmtRowHeight)
+			dc.Fill()
+		}
+		t := line.Date.In(services.Bangkok)
+		r.text(dc, fmt.Sprintf("%s %02d:%02d", thaiDate(t), t.Hour(), t.Minute()), colDate, y+30, 16, stmtText, false, 0)
+		r.text(dc, r.fit(dc, line.Description, 16, colDebit-colDesc-150), colDesc, y+30, 16, stmtText, false, 0)
+		if line.Debit > 0 {
+			r.text(dc, formatBaht(line.Debit), colDebit, y+30, 16, stmtText, false, 1)
+		}
+		if line.Credit > 0 {
+			r.text(dc, formatBaht(line.Credit), colCredit, y+30, 16, stmtText, false, 1)
+		}
+		r.text(dc, formatBaht(line.Balance), colBalance, y+30, 16, stmtText, true, 1)
+		y += stmtRowHeight
+	}
+
+	// === FOOTER: verification QR, document number, page number ===
+	dc.SetRGB(stmtDivider[0], stmtDivider[1], stmtDivider[2])
+	dc.SetLineWidth(2)
+	dc.DrawLine(stmtMargin, stmtFooterTop, right, stmtFooterTop)
+	dc.Stroke()
+
+	footerY := stmtFooterTop + 30
+	if r.qr != nil {
+		dc.DrawImage(r.qr, int(stmtMargin), int(footerY))
+	}
+	textX := stmtMargin + 175
+	r.text(dc, "สแกน QR เพื่อตรวจสอบความถูกต้องของเอกสาร", textX, footerY+35, 18, stmtText, true, 0)
+	r.text(dc, "เลขที่เอกสาร "+r.statementID, textX, footerY+70, 16, stmtSecondary, false, 0)
+	gen := r.generatedAt.In(services.Bangkok)
+	r.text(dc, fmt.Sprintf("ออกให้เมื่อ %s %02d:%02d น.", thaiDate(gen), gen.Hour(), gen.Minute()), textX, footerY+100, 16, stmtSecondary, false, 0)
+	r.text(dc, "เอกสารนี้จัดทำโดยระบบคอมพิวเตอร์ ไม่ต้องลงลายมือชื่อ", textX, footerY+130, 16, stmtSecondary, false, 0)
+	r.text(dc, fmt.Sprintf("หน้า %d / %d", index+1, total), right, footerY+35, 18, stmtText, true, 1)
+
+	return dc.Image()
+}
+
+// formatBaht formats an amount with thousands separators, e.g. 12,345.50
+func formatBaht(v float64) string {
+	neg := v < 0
+	if neg {
+		v = -v
+	}
+	s := fmt.Sprintf("%.2f", v)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	out := ""
+	for len(intPart) > 3 {
+		out = "," + intPart[len(intPart)-3:] + out
+		intPart = intPart[:len(intPart)-3]
+	}
+	out = intPart + out + frac
+	if neg {
+		return "-" + out
+	}
+	return out
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/services"
+)
+
+// TransferLimitOverrideRequest represents an officer override of a member's limits
+type TransferLimitOverrideRequest struct {
+	OfficerID         string  `json:"officer_id"`
+	MemberID          string  `json:"memberid"`
+	PerTransaction    float64 `json:"per_transaction"`
+	Daily             float64 `json:"daily"`
+	Monthly           float64 `json:"monthly"`
+	ApprovalThreshold float64 `json:"approval_threshold"`
+	Reason            string  `json:"reason"`
+}
+
+// TransferApprovalRequest represents an officer decision on a parked transfer
+type TransferApprovalRequest struct {
+	OfficerID  string `json:"officer_id"`
+	ApprovalID string `json:"approval_id"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// GetMemberTransferLimits returns the effective limits and usage for a member
+func GetMemberTransferLimits(c echo.Context) error {
+	memberID := c.Param("memberID")
+	if memberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Member ID required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	status, err := services.GetLimitStatus(ctx, db, memberID, c.QueryParam("account_type"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"data":   status,
+	})
+}
+
+// OverrideMemberTransferLimits requests member-specific limits, applied after a second officer approves
+func OverrideMemberTransferLimits(c echo.Context) error {
+	var req TransferLimitOverrideRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid and reason are required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	approvalID, err := services.RequestMemberLimitOverride(ctx, db, services.MemberLimitOverride{
+		MemberID: req.MemberID,
+		Limits: services.TransferLimits{
+			PerTransaction:    req.PerTransaction,
+			Daily:             req.Daily,
+			Monthly:           req.Monthly,
+			ApprovalThreshold: req.ApprovalThreshold,
+		},
+		Reason:    req.Reason,
+		UpdatedBy: req.OfficerID,
+	})
+	if errors.Is(err, services.ErrApprovalPending) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Overrides take effect once a second officer approves them
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"status":      "pending_approval",
+		"message":     "Transfer limit override requires approval by a second officer",
+		"approval_id": approvalID,
+	})
+}
+
+// RemoveMemberTransferLimitOverride reverts a member to the configured limits
+func RemoveMemberTransferLimitOverride(c echo.Context) error {
+	var req TransferLimitOverrideRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.MemberID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "memberid is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	removed, err := services.RemoveMemberLimitOverride(ctx, db, req.MemberID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !removed {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "No override found for member"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "Transfer limit override removed"})
+}
+
+// GetPendingTransferApprovals lists transfers waiting for officer approval
+func GetPendingTransferApprovals(c echo.Context) error {
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	approvals, err := services.ListPendingTransferApprovals(ctx, db)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"count":  len(approvals),
+		"data":   approvals,
+	})
+}
+
+// ApproveTransferHandler executes a transfer that was routed to officer approval
+func ApproveTransferHandler(c echo.Context) error {
+	var req TransferApprovalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.ApprovalID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "approval_id is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	result, err := services.ApproveTransfer(ctx, db, req.ApprovalID, req.OfficerID)
+	if err != nil {
+		return c.JSON(transferErrorStatus(err), map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":         "success",
+		"message":        "Transfer approved and completed",
+		"transaction_id": result.SourceTxID,
+		"slip_info":      buildTransferSlip(result),
+	})
+}
+
+// RejectTransferHandler declines a transfer that was routed to officer approval
+func RejectTransferHandler(c echo.Context) error {
+	var req TransferApprovalRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.ApprovalID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "approval_id is required"})
+	}
+
+	db := config.GetDatabase()
+	if db == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Database not connected"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := services.RequireOfficer(ctx, db, req.OfficerID); err != nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	if err := services.RejectTransfer(ctx, db, req.ApprovalID, req.OfficerID, req.Reason); err != nil {
+		if errors.Is(err, services.ErrApprovalNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "success", "message": "Transfer rejected"})
+}
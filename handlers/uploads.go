@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/labstack/echo/v4"
+
+	"loan-dynamic-api/services"
+)
+
+// maxFormFieldSize caps a text field read from a streamed multipart form
+const maxFormFieldSize = 64 * 1024
+
+// multipartOverhead is the room left for text fields and part headers when a request
+// body is capped at the size of its files
+const multipartOverhead = 1024 * 1024
+
+// limitRequestBody caps the request body before the form is buffered with
+// c.MultipartForm or c.FormFile; reading past max fails with *http.MaxBytesError.
+func limitRequestBody(c echo.Context, max int64) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, max)
+}
+
+// isBodyTooLarge reports whether err came from the limitRequestBody cap
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// readFormFile reads an uploaded multipart file into memory, failing with
+// services.ErrFileTooLarge once more than max bytes have been read. The size the client
+// declared is not trusted.
+func readFormFile(fh *multipart.FileHeader, max int64) ([]byte, error) {
+	src, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, services.ErrFileTooLarge
+	}
+	return data, nil
+}
+
+// readFormField reads a text part of a streamed multipart form
+func readFormField(part *multipart.Part) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxFormFieldSize {
+		return "", errors.New(part.FormName() + " is too long")
+	}
+	return string(data), nil
+}
+
+// deleteDocumentObject removes an uploaded object that no record will refer to. It runs on
+// its own context because the request may already have been cancelled.
+func deleteDocumentObject(client *s3.Client, bucket, key string) {
+	_, err := client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("Failed to delete unreferenced object %s: %v", key, err)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // อนุญาตเฉพาะ collection ที่เกี่ยวข้องกับระบบสินเชื่อ
@@ -15,10 +16,6 @@ var allowedLoanCollections = map[string]bool{
 	"deposit_accounts":     true,
 	"deposit_transactions": true,
 	"members":              true,
-	"share_accounts":       true,
-	"share_transactions":   true,
-	"dividend_rates":       true,
-	"dividend_payments":    true,
 	"notifications":        true,
 }
 
@@ -27,6 +24,106 @@ func isCollectionAllowed(collection string) bool {
 	return allowedLoanCollections[collection]
 }
 
+// ฟิลด์ที่ gateway เขียนไม่ได้ แยกตาม collection (ชื่อที่ลงท้ายด้วย * คือ prefix)
+//   - members: role และ kyc_* เปลี่ยนได้เฉพาะผ่าน endpoint ของ KYC และเจ้าหน้าที่ (ต้องมีเจ้าหน้าที่คนที่สองอนุมัติ)
+//   - deposit_accounts: ยอดเงิน สถานะ (อายัด/พักบัญชี/ปิด) เลขบัญชี และเงื่อนไขเงินฝากประจำ เปลี่ยนได้เฉพาะผ่าน service ที่ลงบัญชีแยกประเภท
+//   - loan_applications: ยอดจ่ายและเงินต้นคงเหลือ เปลี่ยนได้เฉพาะผ่าน /loan/disburse และ /loan/repay
+var protectedFields = map[string][]string{
+	"members":           {"role", "kyc_*"},
+	"deposit_accounts":  {"balance", "status", "accounttype", "accountnumber", "fixeddeposit"},
+	"loan_applications": {"disbursedamount", "outstandingprincipal", "disbursedaccountid", "disbursedby", "disbursedat", "lastpaymentat", "closedat"},
+}
+
+// loanServiceStatuses are the loan statuses set only by DisburseLoan and RepayLoan.
+// Officers still approve loans through the gateway, so status itself stays writable.
+var loanServiceStatuses = []string{"DISBURSED", "disbursed", "CLOSED", "closed"}
+
+// hasLoanServiceStatus reports whether data or a filter sets status to a disbursed/closed value
+func hasLoanServiceStatus(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if status, ok := item.(string); ok && key == "status" {
+				for _, s := range loanServiceStatuses {
+					if strings.EqualFold(status, s) {
+						return true
+					}
+				}
+			}
+			if hasLoanServiceStatus(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasLoanServiceStatus(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// guardLoanStatusFilter keeps gateway updates of status or approvedamount away from
+// loans that are already disbursed or closed
+func guardLoanStatusFilter(collection string, filter, data map[string]interface{}) map[string]interface{} {
+	if collection != "loan_applications" {
+		return filter
+	}
+	_, setsStatus := data["status"]
+	_, setsAmount := data["approvedamount"]
+	if !setsStatus && !setsAmount {
+		return filter
+	}
+	return map[string]interface{}{
+		"$and": []interface{}{filter, map[string]interface{}{"status": map[string]interface{}{"$nin": loanServiceStatuses}}},
+	}
+}
+
+// isProtectedField reports whether key (or the top-level field of a dotted key) is protected
+func isProtectedField(collection, key string) bool {
+	key = strings.SplitN(key, ".", 2)[0]
+	for _, field := range protectedFields[collection] {
+		if prefix, ok := strings.CutSuffix(field, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// stripProtectedFields removes fields the generic gateway may not write
+func stripProtectedFields(collection string, data map[string]interface{}) {
+	for key := range data {
+		if isProtectedField(collection, key) {
+			delete(data, key)
+		}
+	}
+}
+
+// filterHasProtectedFields reports whether an upsert filter would write a protected field
+// into a new document
+func filterHasProtectedFields(collection string, filter interface{}) bool {
+	switch f := filter.(type) {
+	case map[string]interface{}:
+		for key, value := range f {
+			if isProtectedField(collection, key) || filterHasProtectedFields(collection, value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range f {
+			if filterHasProtectedFields(collection, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // ตรวจสอบขนาดข้อมูล
 func validateDataSize(data map[string]interface{}) error {
 	jsonData, err := json.Marshal(data)
@@ -1,6 +1,7 @@
 package main
 
 import (
+    "context"
     "log"
     "os"
 
@@ -8,6 +9,7 @@ import (
 
     "loan-dynamic-api/config"
     "loan-dynamic-api/routes"
+    "loan-dynamic-api/services"
 )
 
 func main() {
@@ -27,11 +29,28 @@ func main() {
         log.Printf("Warning: Failed to ensure indexes: %v", err)
     }
 
+    // Seed chart of accounts
+    if err := services.EnsureChartOfAccounts(context.Background(), config.GetDatabase()); err != nil {
+        log.Printf("Warning: Failed to seed chart of accounts: %v", err)
+    }
+
+    // Members left pending from before kyc_submissions existed
+    if err := services.BackfillOpenKYCSubmissions(context.Background(), config.GetDatabase()); err != nil {
+        log.Printf("Warning: Failed to backfill KYC submissions: %v", err)
+    }
+
     // Initialize R2 (Cloudflare)
     if err := config.InitR2(); err != nil {
         log.Printf("Warning: Failed to initialize R2: %v", err)
     }
 
+    // เริ่ม background jobs (scheduled transfers ฯลฯ)
+    if os.Getenv("DISABLE_JOBS") != "true" {
+        jobsCtx, stopJobs := context.WithCancel(context.Background())
+        defer stopJobs()
+        services.StartScheduler(jobsCtx, config.GetDatabase())
+    }
+
     // สร้าง Echo instance
     e := routes.NewEcho()
 
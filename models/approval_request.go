@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApprovalRequest is a sensitive officer action waiting for a second officer (approval_requests).
+// The action is carried out only when an officer other than RequestedBy approves it.
+type ApprovalRequest struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RequestID      string             `bson:"requestid" json:"request_id"`
+	Type           string             `bson:"type" json:"type"`          // kyc_verification, limit_override
+	TargetID       string             `bson:"targetid" json:"target_id"` // member the action applies to
+	Summary        string             `bson:"summary" json:"summary"`
+	Payload        bson.M             `bson:"payload" json:"payload"`
+	Status         string             `bson:"status" json:"status"` // pending, processing, completed, failed, rejected
+	RequestedBy    string             `bson:"requestedby" json:"requested_by"`
+	RequestedAt    time.Time          `bson:"requestedat" json:"requested_at"`
+	DecidedBy      string             `bson:"decidedby,omitempty" json:"decided_by,omitempty"`
+	DecidedAt      *time.Time         `bson:"decidedat,omitempty" json:"decided_at,omitempty"`
+	DecisionReason string             `bson:"decisionreason,omitempty" json:"decision_reason,omitempty"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+}
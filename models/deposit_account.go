@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DepositAccount is a member's deposit account (deposit_accounts).
+// Older rows created through the generic gateway may lack the lifecycle fields.
+type DepositAccount struct {
+	ID             primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	AccountID      string                `bson:"accountid" json:"accountid"`
+	AccountNumber  string                `bson:"accountnumber" json:"accountnumber"`
+	MemberID       string                `bson:"memberid" json:"memberid"`
+	AccountName    string                `bson:"accountname" json:"accountname"`
+	AccountType    string                `bson:"accounttype" json:"accounttype"` // savings, fixed
+	Balance        float64               `bson:"balance" json:"balance"`
+	Status         string                `bson:"status" json:"status"` // active, frozen, dormant, closed
+	StatusReason   string                `bson:"statusreason,omitempty" json:"status_reason,omitempty"`
+	StatusHistory  []AccountStatusChange `bson:"statushistory,omitempty" json:"status_history,omitempty"`
+	FixedDeposit   *FixedDepositTerms    `bson:"fixeddeposit,omitempty" json:"fixed_deposit,omitempty"`
+	OpenedAt       time.Time             `bson:"openedat" json:"opened_at"`
+	LastActivityAt time.Time             `bson:"lastactivityat" json:"last_activity_at"`
+	ClosedAt       *time.Time            `bson:"closedat,omitempty" json:"closed_at,omitempty"`
+	CreatedAt      time.Time             `bson:"createdat" json:"created_at"`
+	UpdatedAt      time.Time             `bson:"updatedat" json:"updated_at"`
+}
+
+// AccountStatusChange is one entry in a deposit account's or share type's status history
+type AccountStatusChange struct {
+	From      string    `bson:"from" json:"from"`
+	To        string    `bson:"to" json:"to"`
+	Reason    string    `bson:"reason" json:"reason"`
+	ChangedBy string    `bson:"changedby" json:"changed_by"` // officer id or "system"
+	ChangedAt time.Time `bson:"changedat" json:"changed_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DividendRun is the declared dividend and patronage refund for one fiscal year (dividend_rates).
+// A run is previewed for the board, approved, then paid.
+type DividendRun struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FiscalYear      int                `bson:"fiscalyear" json:"fiscal_year"`
+	PeriodStart     time.Time          `bson:"periodstart" json:"period_start"`
+	PeriodEnd       time.Time          `bson:"periodend" json:"period_end"`         // exclusive
+	DividendRate    float64            `bson:"dividendrate" json:"dividend_rate"`   // percent of average share balance
+	PatronageRate   float64            `bson:"patronagerate" json:"patronage_rate"` // percent of loan interest paid
+	Status          string             `bson:"status" json:"status"`                // preview, approved, paid
+	MemberCount     int                `bson:"membercount" json:"member_count"`
+	TotalDividend   float64            `bson:"totaldividend" json:"total_dividend"`
+	TotalPatronage  float64            `bson:"totalpatronage" json:"total_patronage"`
+	PaidCount       int                `bson:"paidcount" json:"paid_count"`
+	UnpaidCount     int                `bson:"unpaidcount" json:"unpaid_count"`
+	PreviewedBy     string             `bson:"previewedby" json:"previewed_by"`
+	PreviewedAt     time.Time          `bson:"previewedat" json:"previewed_at"`
+	ApprovedBy      string             `bson:"approvedby,omitempty" json:"approved_by,omitempty"`
+	BoardResolution string             `bson:"boardresolution,omitempty" json:"board_resolution,omitempty"`
+	ApprovedAt      *time.Time         `bson:"approvedat,omitempty" json:"approved_at,omitempty"`
+	PaidAt          *time.Time         `bson:"paidat,omitempty" json:"paid_at,omitempty"`
+	UpdatedAt       time.Time          `bson:"updatedat" json:"updated_at"`
+}
+
+// DividendPayment is one member's dividend and patronage refund for a fiscal year (dividend_payments)
+type DividendPayment struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FiscalYear       int                `bson:"fiscalyear" json:"fiscal_year"`
+	MemberID         string             `bson:"memberid" json:"memberid"`
+	MonthlyBalances  []float64          `bson:"monthlybalances" json:"monthly_balances"` // share value at each month end
+	AverageShares    float64            `bson:"averageshares" json:"average_share_balance"`
+	DividendRate     float64            `bson:"dividendrate" json:"dividend_rate"`
+	Dividend         float64            `bson:"dividend" json:"dividend"`
+	LoanInterestPaid float64            `bson:"loaninterestpaid" json:"loan_interest_paid"`
+	PatronageRate    float64            `bson:"patronagerate" json:"patronage_rate"`
+	PatronageRefund  float64            `bson:"patronagerefund" json:"patronage_refund"`
+	Total            float64            `bson:"total" json:"total"`
+	AccountID        string             `bson:"accountid,omitempty" json:"accountid,omitempty"` // deposit account paid into
+	AccountNumber    string             `bson:"accountnumber,omitempty" json:"-"`
+	Status           string             `bson:"status" json:"status"` // preview, paid, unpaid
+	FailureReason    string             `bson:"failurereason,omitempty" json:"failure_reason,omitempty"`
+	TransactionID    string             `bson:"transactionid,omitempty" json:"transactionid,omitempty"`
+	PaidAt           *time.Time         `bson:"paidat,omitempty" json:"paid_at,omitempty"`
+	CreatedAt        time.Time          `bson:"createdat" json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FixedDepositProduct is a fixed-term deposit offering (fixed_deposit_products).
+// Rates are annual percentages; interest is simple interest on actual days / 365.
+type FixedDepositProduct struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductCode         string             `bson:"productcode" json:"product_code"`
+	Name                string             `bson:"name" json:"name"`
+	TermMonths          int                `bson:"termmonths" json:"term_months"`
+	InterestRate        float64            `bson:"interestrate" json:"interest_rate"`
+	MinAmount           float64            `bson:"minamount" json:"min_amount"`
+	MaxAmount           float64            `bson:"maxamount,omitempty" json:"max_amount,omitempty"` // 0 = no maximum
+	WithholdingTaxRate  float64            `bson:"withholdingtaxrate" json:"withholding_tax_rate"`  // percent of interest, 0 = exempt
+	EarlyWithdrawalRate float64            `bson:"earlywithdrawalrate" json:"early_withdrawal_rate"`
+	MinHoldMonths       int                `bson:"minholdmonths" json:"min_hold_months"` // no interest when withdrawn earlier
+	Status              string             `bson:"status" json:"status"`                 // active, inactive
+	CreatedAt           time.Time          `bson:"createdat" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updatedat" json:"updated_at"`
+}
+
+// FixedDepositTerms are the contract terms stored on a fixed deposit account
+// (deposit_accounts.fixeddeposit). They are re-set on each renewal.
+type FixedDepositTerms struct {
+	ProductCode         string    `bson:"productcode" json:"product_code"`
+	Principal           float64   `bson:"principal" json:"principal"`
+	InterestRate        float64   `bson:"interestrate" json:"interest_rate"`
+	TermMonths          int       `bson:"termmonths" json:"term_months"`
+	WithholdingTaxRate  float64   `bson:"withholdingtaxrate" json:"withholding_tax_rate"`
+	StartDate           time.Time `bson:"startdate" json:"start_date"`
+	MaturityDate        time.Time `bson:"maturitydate" json:"maturity_date"`
+	EarlyWithdrawalRate float64   `bson:"earlywithdrawalrate" json:"early_withdrawal_rate"`
+	MinHoldMonths       int       `bson:"minholdmonths" json:"min_hold_months"`
+	MaturityInstruction string    `bson:"maturityinstruction" json:"maturity_instruction"` // renew_principal, renew_all, payout
+	PayoutAccountID     string    `bson:"payoutaccountid,omitempty" json:"payout_account_id,omitempty"`
+	Renewals            int       `bson:"renewals" json:"renewals"`
+}
+
+// FixedDepositInterest records interest paid on a fixed deposit, including tax withheld
+// (fixed_deposit_interest). Used for withholding tax certificates.
+type FixedDepositInterest struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AccountID    string             `bson:"accountid" json:"accountid"`
+	MemberID     string             `bson:"memberid" json:"memberid"`
+	Kind         string             `bson:"kind" json:"kind"` // maturity, early_withdrawal
+	PeriodStart  time.Time          `bson:"periodstart" json:"period_start"`
+	PeriodEnd    time.Time          `bson:"periodend" json:"period_end"`
+	Principal    float64            `bson:"principal" json:"principal"`
+	InterestRate float64            `bson:"interestrate" json:"interest_rate"`
+	GrossAmount  float64            `bson:"grossamount" json:"gross_amount"`
+	TaxWithheld  float64            `bson:"taxwithheld" json:"tax_withheld"`
+	NetAmount    float64            `bson:"netamount" json:"net_amount"`
+	Penalty      float64            `bson:"penalty" json:"penalty"` // contract interest forfeited on early withdrawal
+	PaidTo       string             `bson:"paidto" json:"paid_to"`
+	PaidAt       time.Time          `bson:"paidat" json:"paid_at"`
+}
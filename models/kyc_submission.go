@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KYCSubmission is one KYC attempt by a member (kyc_submissions). The member document keeps
+// the kyc_* fields of the latest attempt; earlier attempts and their reviews stay here.
+type KYCSubmission struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubmissionID     string             `bson:"submissionid" json:"submission_id"`
+	MemberID         string             `bson:"memberid" json:"memberid"`
+	Attempt          int                `bson:"attempt" json:"attempt"`
+	CitizenID        string             `bson:"citizenid" json:"citizen_id"`
+	BankID           string             `bson:"bankid" json:"bank_id"`
+	BankAccountNo    string             `bson:"bankaccountno" json:"bank_account_no"`
+	IDCardImageKey   string             `bson:"idcardimagekey" json:"id_card_image_key"`
+	BankBookImageKey string             `bson:"bankbookimagekey" json:"bank_book_image_key"`
+	SelfieImageKey   string             `bson:"selfieimagekey" json:"selfie_image_key"`
+	ImageSHA256      map[string]string  `bson:"imagesha256,omitempty" json:"image_sha256,omitempty"`
+	Status           string             `bson:"status" json:"status"` // pending, verified, rejected
+	SubmittedAt      time.Time          `bson:"submittedat" json:"submitted_at"`
+	ReviewedBy       string             `bson:"reviewedby,omitempty" json:"reviewed_by,omitempty"`
+	Reason           string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	ReviewedAt       *time.Time         `bson:"reviewedat,omitempty" json:"reviewed_at,omitempty"`
+	PromotedOfficer  bool               `bson:"promotedofficer,omitempty" json:"promoted_officer,omitempty"`
+	RiskLevel        string             `bson:"risklevel,omitempty" json:"risk_level,omitempty"` // low, medium, high
+	RiskReasons      []string           `bson:"riskreasons,omitempty" json:"risk_reasons,omitempty"`
+	ApprovalID       string             `bson:"approvalid,omitempty" json:"approval_id,omitempty"` // approval_requests entry while awaiting a second officer
+	ApprovedBy       string             `bson:"approvedby,omitempty" json:"approved_by,omitempty"`
+	IDCardExpiry     *time.Time         `bson:"idcardexpiry,omitempty" json:"id_card_expiry,omitempty"`
+	ExpiresAt        *time.Time         `bson:"expiresat,omitempty" json:"expires_at,omitempty"` // verification must be renewed by this date
+	FaceMatch        *FaceMatch         `bson:"facematch,omitempty" json:"face_match,omitempty"`
+	ClaimedBy        string             `bson:"claimedby,omitempty" json:"claimed_by,omitempty"` // officer working on the submission
+	ClaimedAt        *time.Time         `bson:"claimedat,omitempty" json:"claimed_at,omitempty"`
+	ClaimExpiresAt   *time.Time         `bson:"claimexpiresat,omitempty" json:"claim_expires_at,omitempty"`
+}
+
+// FaceMatch is an automated comparison of the selfie with the photo on the ID card. It is a
+// hint for the reviewing officer, not a decision.
+type FaceMatch struct {
+	Provider  string    `bson:"provider" json:"provider"`
+	Score     float64   `bson:"score" json:"score"` // 0 (different) to 1 (same)
+	Threshold float64   `bson:"threshold" json:"threshold"`
+	Passed    bool      `bson:"passed" json:"passed"`
+	Flags     []string  `bson:"flags,omitempty" json:"flags,omitempty"`
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+	CheckedAt time.Time `bson:"checkedat" json:"checked_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GLAccount is an entry in the chart of accounts (gl_accounts)
+type GLAccount struct {
+	Code          string `bson:"code" json:"code"`
+	Name          string `bson:"name" json:"name"`
+	Type          string `bson:"type" json:"type"`                    // asset, liability, equity, income, expense
+	NormalBalance string `bson:"normalbalance" json:"normal_balance"` // debit, credit
+}
+
+// JournalLine is one side of a journal entry. Exactly one of Debit and Credit is non-zero.
+// SubAccount identifies the member-level account (deposit accountid, loan applicationid, memberid).
+type JournalLine struct {
+	AccountCode string  `bson:"accountcode" json:"account_code"`
+	SubAccount  string  `bson:"subaccount,omitempty" json:"sub_account,omitempty"`
+	Debit       float64 `bson:"debit" json:"debit"`
+	Credit      float64 `bson:"credit" json:"credit"`
+	Memo        string  `bson:"memo,omitempty" json:"memo,omitempty"`
+}
+
+// JournalEntry is a balanced double-entry posting (gl_journal_entries)
+type JournalEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EntryID     string             `bson:"entryid" json:"entry_id"`
+	EntryDate   time.Time          `bson:"entrydate" json:"entry_date"`
+	Description string             `bson:"description" json:"description"`
+	SourceType  string             `bson:"sourcetype" json:"source_type"` // transfer, loan_disbursement, loan_repayment, ...
+	SourceRef   string             `bson:"sourceref" json:"source_ref"`
+	Lines       []JournalLine      `bson:"lines" json:"lines"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
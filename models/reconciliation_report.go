@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReconciliationReport is the stored result of one balance reconciliation run
+type ReconciliationReport struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReportID        string             `bson:"reportid" json:"report_id"`
+	ReportDate      string             `bson:"reportdate" json:"report_date"` // YYYY-MM-DD, Asia/Bangkok
+	GeneratedAt     time.Time          `bson:"generatedat" json:"generated_at"`
+	GeneratedBy     string             `bson:"generatedby" json:"generated_by"`
+	AccountsChecked int                `bson:"accountschecked" json:"accounts_checked"`
+	MismatchCount   int                `bson:"mismatchcount" json:"mismatch_count"`
+	ChainBreakCount int                `bson:"chainbreakcount" json:"chain_break_count"`
+	Truncated       bool               `bson:"truncated" json:"truncated"`
+	Mismatches      []BalanceMismatch  `bson:"mismatches" json:"mismatches,omitempty"`
+	ChainBreaks     []ChainBreak       `bson:"chainbreaks" json:"chain_breaks,omitempty"`
+}
+
+// BalanceMismatch is an account whose stored balance differs from its transaction history
+type BalanceMismatch struct {
+	AccountID        string  `bson:"accountid" json:"accountid"`
+	AccountNumber    string  `bson:"accountnumber" json:"accountnumber"`
+	StoredBalance    float64 `bson:"storedbalance" json:"stored_balance"`
+	ComputedBalance  float64 `bson:"computedbalance" json:"computed_balance"`
+	Difference       float64 `bson:"difference" json:"difference"`
+	TransactionCount int     `bson:"transactioncount" json:"transaction_count"`
+}
+
+// ChainBreak is a transaction whose balanceafter does not follow from the previous one
+type ChainBreak struct {
+	AccountID            string    `bson:"accountid" json:"accountid"`
+	TransactionID        string    `bson:"transactionid" json:"transactionid"`
+	DateTime             time.Time `bson:"datetime" json:"datetime"`
+	Type                 string    `bson:"type" json:"type"`
+	Amount               float64   `bson:"amount" json:"amount"`
+	PreviousBalanceAfter float64   `bson:"previousbalanceafter" json:"previous_balance_after"`
+	ExpectedBalanceAfter float64   `bson:"expectedbalanceafter" json:"expected_balance_after"`
+	ActualBalanceAfter   float64   `bson:"actualbalanceafter" json:"actual_balance_after"`
+	Reason               string    `bson:"reason" json:"reason"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ScheduledTransfer struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ScheduleID        string             `bson:"scheduleid" json:"schedule_id"`
+	MemberID          string             `bson:"memberid" json:"memberid"`
+	SourceAccountID   string             `bson:"sourceaccountid" json:"source_account_id"`
+	DestAccountID     string             `bson:"destaccountid" json:"dest_account_id"`
+	Amount            float64            `bson:"amount" json:"amount"`
+	Description       string             `bson:"description" json:"description"`
+	Frequency         string             `bson:"frequency" json:"frequency"`                         // once, daily, weekly, monthly
+	DayOfMonth        int                `bson:"dayofmonth,omitempty" json:"day_of_month,omitempty"` // monthly: 1-31, clamped to month end
+	DayOfWeek         int                `bson:"dayofweek,omitempty" json:"day_of_week,omitempty"`   // weekly: 0 = Sunday
+	RunAt             time.Time          `bson:"runat" json:"run_at"`                                // first run; time of day for recurring
+	EndDate           *time.Time         `bson:"enddate,omitempty" json:"end_date,omitempty"`
+	NextRunAt         time.Time          `bson:"nextrunat" json:"next_run_at"`
+	Status            string             `bson:"status" json:"status"` // active, completed, cancelled, failed
+	RetryCount        int                `bson:"retrycount" json:"retry_count"`
+	CurrentOccurrence *time.Time         `bson:"currentoccurrence,omitempty" json:"-"` // occurrence being retried
+	LastRunAt         *time.Time         `bson:"lastrunat,omitempty" json:"last_run_at,omitempty"`
+	LastResult        string             `bson:"lastresult,omitempty" json:"last_result,omitempty"`
+	LastError         string             `bson:"lasterror,omitempty" json:"last_error,omitempty"`
+	LastTransactionID string             `bson:"lasttransactionid,omitempty" json:"last_transaction_id,omitempty"`
+	LockedUntil       time.Time          `bson:"lockeduntil" json:"-"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareAccount is a member's holding of one share type (share_accounts).
+// Value is the paid-up amount, so units bought at different prices keep their cost.
+type ShareAccount struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	MemberID      string             `bson:"memberid" json:"memberid"`
+	ShareTypeID   string             `bson:"sharetypeid" json:"share_type_id"`
+	ShareTypeName string             `bson:"sharetypename" json:"share_type_name"`
+	Units         int64              `bson:"units" json:"units"`
+	Value         float64            `bson:"value" json:"value"`
+	CreatedAt     time.Time          `bson:"createdat" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updatedat" json:"updated_at"`
+}
+
+// ShareTransaction is one purchase or redemption of shares (share_transactions)
+type ShareTransaction struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TransactionID string             `bson:"transactionid" json:"transactionid"`
+	MemberID      string             `bson:"memberid" json:"memberid"`
+	ShareTypeID   string             `bson:"sharetypeid" json:"share_type_id"`
+	Type          string             `bson:"type" json:"type"` // purchase, redemption
+	Units         int64              `bson:"units" json:"units"`
+	UnitPrice     float64            `bson:"unitprice" json:"unit_price"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	UnitsAfter    int64              `bson:"unitsafter" json:"units_after"`
+	ValueAfter    float64            `bson:"valueafter" json:"value_after"`
+	AccountID     string             `bson:"accountid" json:"accountid"` // deposit account debited or credited
+	ProcessedBy   string             `bson:"processedby,omitempty" json:"processed_by,omitempty"`
+	Reason        string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	DateTime      time.Time          `bson:"datetime" json:"datetime"`
+	Status        string             `bson:"status" json:"status"`
+}
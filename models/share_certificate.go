@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareCertificate is an issued share certificate (share_certificates). Issuing a new
+// certificate supersedes the member's previous one.
+type ShareCertificate struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	CertificateID string             `bson:"certificateid" json:"certificate_id"`
+	CertificateNo string             `bson:"certificateno" json:"certificate_no"`
+	MemberID      string             `bson:"memberid" json:"memberid"`
+	MemberName    string             `bson:"membername" json:"member_name"`
+	Units         int64              `bson:"units" json:"units"`
+	Value         float64            `bson:"value" json:"value"`
+	IssuedAt      time.Time          `bson:"issuedat" json:"issued_at"`
+	IssuedBy      string             `bson:"issuedby" json:"issued_by"`
+	Status        string             `bson:"status" json:"status"` // valid, superseded
+	SHA256        string             `bson:"sha256" json:"sha256"`
+	DocumentID    string             `bson:"documentid,omitempty" json:"document_id,omitempty"` // entry in documents
+	R2Key         string             `bson:"r2key" json:"-"`
+}
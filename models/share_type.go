@@ -7,10 +7,12 @@ import (
 )
 
 type ShareType struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string             `bson:"name" json:"name"`
-	Price     float64            `bson:"price" json:"price"`
-	Status    string             `bson:"status" json:"status"` // active, inactive
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	Name          string                `bson:"name" json:"name"`
+	Price         float64               `bson:"price" json:"price"`
+	Status        string                `bson:"status" json:"status"` // active, inactive, deleted
+	StatusReason  string                `bson:"status_reason,omitempty" json:"status_reason,omitempty"`
+	StatusHistory []AccountStatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	CreatedAt     time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time             `bson:"updated_at" json:"updated_at"`
 }
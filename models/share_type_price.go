@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareTypePrice is one entry in a share type's price history (share_type_prices).
+// The price in effect at a time is the latest entry with EffectiveFrom <= that time;
+// entries in the future are scheduled changes.
+type ShareTypePrice struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ShareTypeID   string             `bson:"sharetypeid" json:"share_type_id"`
+	Price         float64            `bson:"price" json:"price"`
+	EffectiveFrom time.Time          `bson:"effectivefrom" json:"effective_from"`
+	Note          string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedBy     string             `bson:"createdby,omitempty" json:"created_by,omitempty"`
+	CreatedAt     time.Time          `bson:"createdat" json:"created_at"`
+}
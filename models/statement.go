@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statement records a generated account statement so it can be verified from its QR code
+type Statement struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StatementID    string             `bson:"statementid" json:"statement_id"`
+	MemberID       string             `bson:"memberid" json:"memberid"`
+	AccountType    string             `bson:"accounttype" json:"account_type"` // deposit, loan
+	AccountID      string             `bson:"accountid" json:"accountid"`
+	AccountMasked  string             `bson:"accountmasked" json:"account_masked"`
+	AccountName    string             `bson:"accountname" json:"account_name"`
+	PeriodFrom     time.Time          `bson:"periodfrom" json:"period_from"`
+	PeriodTo       time.Time          `bson:"periodto" json:"period_to"`
+	OpeningBalance float64            `bson:"openingbalance" json:"opening_balance"`
+	ClosingBalance float64            `bson:"closingbalance" json:"closing_balance"`
+	LineCount      int                `bson:"linecount" json:"line_count"`
+	PageCount      int                `bson:"pagecount" json:"page_count"`
+	SHA256         string             `bson:"sha256" json:"sha256"`
+	R2Key          string             `bson:"r2_key" json:"-"`
+	GeneratedAt    time.Time          `bson:"generatedat" json:"generated_at"`
+}
@@ -52,6 +52,11 @@ func setupRoutes(e *echo.Echo) {
 	v1.POST("/document/get", handlers.DocumentGetHandler)
 	v1.POST("/document/info", handlers.DocumentInfoHandler)
 	v1.POST("/document/delete", handlers.DocumentDeleteHandler)
+	v1.POST("/document/versions", handlers.DocumentVersionsHandler)
+	v1.POST("/document/restore", handlers.DocumentRestoreHandler)
+	v1.POST("/document/upload-url", handlers.DocumentUploadURLHandler)
+	v1.POST("/document/upload-confirm", handlers.DocumentUploadConfirmHandler)
+	v1.POST("/document/storage", handlers.DocumentStorageHandler)
 
 	// Member endpoints
 	v1.POST("/upload-profile-image", handlers.UploadProfileImageHandler)
@@ -60,10 +65,15 @@ func setupRoutes(e *echo.Echo) {
 	
 	// KYC
 	v1.POST("/member/kyc", handlers.SubmitKYC)
+	v1.GET("/banks", handlers.ListBanksHandler)
+	v1.POST("/officer/banks", handlers.SaveBankHandler)
 	
 	// Officer KYC (Should be protected by Officer Middleware in real implementation)
 	v1.GET("/officer/kyc/pending", handlers.GetPendingKYC)
 	v1.GET("/officer/kyc/detail/:memberID", handlers.GetKYCDetail)
+	v1.GET("/officer/kyc/queue", handlers.GetKYCQueue)
+	v1.POST("/officer/kyc/claim", handlers.ClaimKYCSubmissionHandler)
+	v1.POST("/officer/kyc/release", handlers.ReleaseKYCClaimHandler)
 	v1.POST("/officer/kyc/review", handlers.ReviewKYC)
 
 	// Share Management
@@ -71,10 +81,75 @@ func setupRoutes(e *echo.Echo) {
 	v1.POST("/share/update/:id", handlers.UpdateShareType)
 	v1.GET("/share/list", handlers.GetShareTypes)
 	v1.DELETE("/share/delete/:id", handlers.DeleteShareType)
+	v1.POST("/officer/share/activate/:id", handlers.ActivateShareType)
+	v1.POST("/officer/share/deactivate/:id", handlers.DeactivateShareType)
+	v1.GET("/share/prices/:id", handlers.GetSharePriceTimelineHandler)
+	v1.POST("/officer/share/prices/:id", handlers.SetSharePriceHandler)
+	v1.POST("/share/buy", handlers.BuySharesHandler)
+	v1.POST("/officer/share/redeem", handlers.RedeemSharesHandler)
+	v1.GET("/share/holdings/:memberID", handlers.GetShareHoldingsHandler)
+	v1.GET("/share/transactions/:memberID", handlers.GetShareTransactionsHandler)
+	v1.POST("/officer/signature", handlers.UploadOfficerSignatureHandler)
+	v1.POST("/officer/share/certificates/issue", handlers.IssueShareCertificateHandler)
+	v1.GET("/share/certificates/verify/:certificateID", handlers.VerifyShareCertificateHandler)
+
+	// Dividends & Patronage Refunds
+	v1.POST("/officer/dividends/preview", handlers.PreviewDividendsHandler)
+	v1.POST("/officer/dividends/finalize", handlers.FinalizeDividendsHandler)
+	v1.GET("/officer/dividends/:year", handlers.GetDividendRunHandler)
+	v1.GET("/dividends/slip/:year", handlers.GetDividendSlipHandler)
 
 	// Internal Payment / Transfer
 	v1.POST("/payment/internal", handlers.PerformInternalTransfer)
 
+	// Deposit Account Lifecycle
+	v1.POST("/deposit/accounts/open", handlers.OpenDepositAccountHandler)
+	v1.POST("/officer/deposit-accounts/freeze", handlers.FreezeDepositAccountHandler)
+	v1.POST("/officer/deposit-accounts/unfreeze", handlers.UnfreezeDepositAccountHandler)
+	v1.POST("/officer/deposit-accounts/reactivate", handlers.ReactivateDepositAccountHandler)
+	v1.POST("/officer/deposit-accounts/close", handlers.CloseDepositAccountHandler)
+
+	// Fixed Deposits
+	v1.GET("/fixed-deposits/products", handlers.ListFixedDepositProductsHandler)
+	v1.POST("/officer/fixed-deposits/products", handlers.SaveFixedDepositProductHandler)
+	v1.POST("/fixed-deposits/open", handlers.OpenFixedDepositHandler)
+	v1.GET("/fixed-deposits/:accountID/early-withdrawal", handlers.QuoteEarlyWithdrawalHandler)
+	v1.POST("/fixed-deposits/withdraw-early", handlers.WithdrawFixedDepositEarlyHandler)
+
+	// Scheduled / Recurring Transfers
+	v1.POST("/payment/scheduled/create", handlers.CreateScheduledTransfer)
+	v1.POST("/payment/scheduled/list", handlers.ListScheduledTransfers)
+	v1.POST("/payment/scheduled/cancel", handlers.CancelScheduledTransfer)
+
+	// Officer Transfer Limits & Approvals
+	v1.GET("/officer/transfer-limits/:memberID", handlers.GetMemberTransferLimits)
+	v1.POST("/officer/transfer-limits/override", handlers.OverrideMemberTransferLimits)
+	v1.POST("/officer/transfer-limits/override/remove", handlers.RemoveMemberTransferLimitOverride)
+	v1.GET("/officer/transfer-approvals/pending", handlers.GetPendingTransferApprovals)
+	v1.POST("/officer/transfer-approvals/approve", handlers.ApproveTransferHandler)
+	v1.POST("/officer/transfer-approvals/reject", handlers.RejectTransferHandler)
+
+	// Officer Four-Eyes Approvals
+	v1.GET("/officer/approvals/pending", handlers.GetPendingApprovalsHandler)
+	v1.POST("/officer/approvals/approve", handlers.ApproveRequestHandler)
+	v1.POST("/officer/approvals/reject", handlers.RejectRequestHandler)
+
+	// Loan Disbursement / Repayment
+	v1.POST("/loan/disburse", handlers.DisburseLoanHandler)
+	v1.POST("/loan/repay", handlers.RepayLoanHandler)
+
+	// General Ledger
+	v1.GET("/ledger/trial-balance", handlers.GetTrialBalance)
+	v1.GET("/ledger/verify", handlers.VerifyLedgerBalances)
+	v1.GET("/ledger/entries", handlers.GetJournalEntries)
+	v1.POST("/ledger/opening-balances", handlers.PostLedgerOpeningBalances)
+
+	// Officer Balance Reconciliation
+	v1.POST("/officer/reconciliation/run", handlers.RunReconciliationHandler)
+	v1.GET("/officer/reconciliation/reports", handlers.ListReconciliationReportsHandler)
+	v1.GET("/officer/reconciliation/reports/:reportID", handlers.GetReconciliationReportHandler)
+	v1.GET("/officer/reconciliation/reports/:reportID/csv", handlers.DownloadReconciliationReportCSV)
+
 	// Notification endpoints
 	v1.POST("/notification/get", handlers.GetNotifications)
 	v1.POST("/notification/add", handlers.AddNotification)
@@ -85,10 +160,18 @@ func setupRoutes(e *echo.Echo) {
 	// Slip Generation
 	v1.POST("/slip/generate", handlers.GenerateSlipHandler)
 	
+	// Account Statements (PDF)
+	v1.POST("/statements/generate", handlers.GenerateStatementHandler)
+	v1.GET("/statements/verify/:statementID", handlers.VerifyStatementHandler)
+
 	// QR Generation
 	v1.POST("/qr/generate", handlers.GenerateQRHandler)
 	v1.POST("/qr/delete", handlers.DeleteQRHandler)
 	
+	// Background jobs (Vercel Cron)
+	v1.GET("/jobs/:name/run", handlers.RunJobHandler)
+	v1.POST("/jobs/:name/run", handlers.RunJobHandler)
+
 	// Static file serving for storage
 	storageDir := os.Getenv("STORAGE_DIR")
 	if storageDir == "" {
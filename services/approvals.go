@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Actions that need a second officer before they take effect
+const (
+	ApprovalKYCVerification = "kyc_verification"
+	ApprovalLimitOverride   = "limit_override"
+)
+
+var (
+	ErrApprovalRequestNotFound = errors.New("approval request not found or already decided")
+	ErrSelfApproval            = errors.New("a different officer must approve this request")
+	ErrApprovalPending         = errors.New("an approval request for this member is already pending")
+)
+
+// createApprovalRequest parks an action for a second officer. The unique partial index on
+// pending requests allows one pending request per type and member.
+func createApprovalRequest(ctx context.Context, db *mongo.Database, req *models.ApprovalRequest) error {
+	req.RequestID = "APR-" + uuid.New().String()
+	req.Status = "pending"
+	req.RequestedAt = time.Now()
+	if _, err := db.Collection("approval_requests").InsertOne(ctx, req); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrApprovalPending
+		}
+		return fmt.Errorf("failed to save approval request: %w", err)
+	}
+	return nil
+}
+
+// ListPendingApprovalRequests returns requests waiting for a second officer, oldest first.
+// An empty type lists every type.
+func ListPendingApprovalRequests(ctx context.Context, db *mongo.Database, requestType string) ([]models.ApprovalRequest, error) {
+	filter := bson.M{"status": "pending"}
+	if requestType != "" {
+		filter["type"] = requestType
+	}
+	cursor, err := db.Collection("approval_requests").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "requestedat", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approval requests: %w", err)
+	}
+	requests := []models.ApprovalRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("failed to decode approval requests: %w", err)
+	}
+	return requests, nil
+}
+
+// ApproveRequest lets a second officer approve a pending request and carries out the action.
+// The request ends as completed, or failed with the error if the action could not be applied.
+func ApproveRequest(ctx context.Context, db *mongo.Database, requestID, officerID string) (*models.ApprovalRequest, error) {
+	var req models.ApprovalRequest
+	err := db.Collection("approval_requests").FindOne(ctx, bson.M{"requestid": requestID, "status": "pending"}).Decode(&req)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrApprovalRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval request: %w", err)
+	}
+	// Neither the requester nor the member the action applies to may approve it
+	if req.RequestedBy == officerID || req.TargetID == officerID {
+		return nil, ErrSelfApproval
+	}
+
+	now := time.Now()
+	err = db.Collection("approval_requests").FindOneAndUpdate(ctx,
+		bson.M{"requestid": requestID, "status": "pending", "requestedby": bson.M{"$ne": officerID}, "targetid": bson.M{"$ne": officerID}},
+		bson.M{"$set": bson.M{"status": "processing", "decidedby": officerID, "decidedat": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&req)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrApprovalRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim approval request: %w", err)
+	}
+
+	switch req.Type {
+	case ApprovalKYCVerification:
+		err = approveKYCVerification(ctx, db, &req, officerID)
+	case ApprovalLimitOverride:
+		err = approveLimitOverride(ctx, db, &req, officerID)
+	default:
+		err = fmt.Errorf("unknown approval request type %q", req.Type)
+	}
+
+	update := bson.M{"status": "completed"}
+	if err != nil {
+		update["status"] = "failed"
+		update["error"] = err.Error()
+		// Nothing was applied, so the submission goes back to the review queue
+		if req.Type == ApprovalKYCVerification {
+			if rerr := releaseKYCApproval(ctx, db, requestID); rerr != nil {
+				err = fmt.Errorf("%v; %w", err, rerr)
+			}
+		}
+	}
+	req.Status = update["status"].(string)
+	req.Error, _ = update["error"].(string)
+	if _, uerr := db.Collection("approval_requests").UpdateOne(ctx, bson.M{"requestid": requestID}, bson.M{"$set": update}); uerr != nil && err == nil {
+		err = fmt.Errorf("action applied but approval record update failed: %w", uerr)
+	}
+	return &req, err
+}
+
+// RejectRequest declines a pending request; the action is not carried out. The requesting
+// officer may reject their own request to withdraw it.
+func RejectRequest(ctx context.Context, db *mongo.Database, requestID, officerID, reason string) error {
+	var req models.ApprovalRequest
+	err := db.Collection("approval_requests").FindOneAndUpdate(ctx,
+		bson.M{"requestid": requestID, "status": "pending"},
+		bson.M{"$set": bson.M{
+			"status":         "rejected",
+			"decidedby":      officerID,
+			"decidedat":      time.Now(),
+			"decisionreason": reason,
+		}}).Decode(&req)
+	if err == mongo.ErrNoDocuments {
+		return ErrApprovalRequestNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reject approval request: %w", err)
+	}
+
+	// The submission can be reviewed again
+	if req.Type == ApprovalKYCVerification {
+		return releaseKYCApproval(ctx, db, requestID)
+	}
+	return nil
+}
+
+// releaseKYCApproval detaches a pending KYC submission from an approval request that will not
+// be carried out, so that it can be reviewed again
+func releaseKYCApproval(ctx context.Context, db *mongo.Database, requestID string) error {
+	_, err := db.Collection("kyc_submissions").UpdateOne(ctx,
+		bson.M{"approvalid": requestID, "status": KYCStatusPending},
+		bson.M{"$unset": bson.M{"approvalid": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to release KYC submission: %w", err)
+	}
+	return nil
+}
+
+// decodePayload converts a stored payload back into its typed form
+func decodePayload(payload bson.M, out interface{}) error {
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to read approval payload: %w", err)
+	}
+	if err := bson.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to read approval payload: %w", err)
+	}
+	return nil
+}
+
+// encodePayload stores a typed payload on an approval request
+func encodePayload(in interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode approval payload: %w", err)
+	}
+	var payload bson.M
+	if err := bson.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("failed to encode approval payload: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidBank = errors.New("bank code must be 3 digits with a name and at least one account number length")
+
+// Bank is a Thai bank members can register for KYC and payouts
+type Bank struct {
+	Code           string    `bson:"code" json:"code"` // Bank of Thailand 3-digit code
+	ShortName      string    `bson:"shortname" json:"short_name"`
+	NameTH         string    `bson:"name_th" json:"name_th"`
+	NameEN         string    `bson:"name_en" json:"name_en"`
+	AccountLengths []int     `bson:"accountlengths" json:"account_lengths"` // allowed account number digits
+	Active         bool      `bson:"active" json:"active"`
+	UpdatedBy      string    `bson:"updatedby,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt      time.Time `bson:"updatedat,omitempty" json:"updated_at,omitempty"`
+}
+
+// defaultThaiBanks apply when the banks collection has no row for the code
+var defaultThaiBanks = []Bank{
+	{Code: "002", ShortName: "BBL", NameTH: "ธนาคารกรุงเทพ", NameEN: "Bangkok Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "004", ShortName: "KBANK", NameTH: "ธนาคารกสิกรไทย", NameEN: "Kasikornbank", AccountLengths: []int{10}, Active: true},
+	{Code: "006", ShortName: "KTB", NameTH: "ธนาคารกรุงไทย", NameEN: "Krungthai Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "011", ShortName: "TTB", NameTH: "ธนาคารทหารไทยธนชาต", NameEN: "TMBThanachart Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "014", ShortName: "SCB", NameTH: "ธนาคารไทยพาณิชย์", NameEN: "Siam Commercial Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "022", ShortName: "CIMBT", NameTH: "ธนาคารซีไอเอ็มบี ไทย", NameEN: "CIMB Thai Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "024", ShortName: "UOBT", NameTH: "ธนาคารยูโอบี", NameEN: "United Overseas Bank (Thai)", AccountLengths: []int{10}, Active: true},
+	{Code: "025", ShortName: "BAY", NameTH: "ธนาคารกรุงศรีอยุธยา", NameEN: "Bank of Ayudhya", AccountLengths: []int{10}, Active: true},
+	{Code: "030", ShortName: "GSB", NameTH: "ธนาคารออมสิน", NameEN: "Government Savings Bank", AccountLengths: []int{12}, Active: true},
+	{Code: "033", ShortName: "GHB", NameTH: "ธนาคารอาคารสงเคราะห์", NameEN: "Government Housing Bank", AccountLengths: []int{12}, Active: true},
+	{Code: "034", ShortName: "BAAC", NameTH: "ธนาคารเพื่อการเกษตรและสหกรณ์การเกษตร", NameEN: "Bank for Agriculture and Agricultural Cooperatives", AccountLengths: []int{12}, Active: true},
+	{Code: "066", ShortName: "IBANK", NameTH: "ธนาคารอิสลามแห่งประเทศไทย", NameEN: "Islamic Bank of Thailand", AccountLengths: []int{10}, Active: true},
+	{Code: "067", ShortName: "TISCO", NameTH: "ธนาคารทิสโก้", NameEN: "TISCO Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "069", ShortName: "KKP", NameTH: "ธนาคารเกียรตินาคินภัทร", NameEN: "Kiatnakin Phatra Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "070", ShortName: "ICBCT", NameTH: "ธนาคารไอซีบีซี (ไทย)", NameEN: "ICBC (Thai)", AccountLengths: []int{10}, Active: true},
+	{Code: "071", ShortName: "TCRB", NameTH: "ธนาคารไทยเครดิต", NameEN: "Thai Credit Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "073", ShortName: "LHFG", NameTH: "ธนาคารแลนด์ แอนด์ เฮ้าส์", NameEN: "Land and Houses Bank", AccountLengths: []int{10}, Active: true},
+	{Code: "098", ShortName: "SME D", NameTH: "ธนาคารพัฒนาวิสาหกิจขนาดกลางและขนาดย่อมแห่งประเทศไทย", NameEN: "SME Development Bank", AccountLengths: []int{10}, Active: true},
+}
+
+// ListBanks returns the bank list: the built-in Thai banks with any rows in the banks
+// collection replacing or adding to them, ordered by code
+func ListBanks(ctx context.Context, db *mongo.Database) ([]Bank, error) {
+	byCode := map[string]Bank{}
+	for _, b := range defaultThaiBanks {
+		byCode[b.Code] = b
+	}
+
+	cursor, err := db.Collection("banks").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query banks: %w", err)
+	}
+	var stored []Bank
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode banks: %w", err)
+	}
+	for _, b := range stored {
+		byCode[b.Code] = b
+	}
+
+	banks := make([]Bank, 0, len(byCode))
+	for _, b := range byCode {
+		banks = append(banks, b)
+	}
+	sort.Slice(banks, func(i, j int) bool { return banks[i].Code < banks[j].Code })
+	return banks, nil
+}
+
+// SaveBank adds a bank or replaces a built-in one, e.g. to deactivate it or change lengths
+func SaveBank(ctx context.Context, db *mongo.Database, bank Bank) (*Bank, error) {
+	bank.Code = strings.TrimSpace(bank.Code)
+	if len(bank.Code) != 3 || !isDigits(bank.Code) || bank.NameTH == "" || len(bank.AccountLengths) == 0 {
+		return nil, ErrInvalidBank
+	}
+	for _, n := range bank.AccountLengths {
+		if n <= 0 {
+			return nil, ErrInvalidBank
+		}
+	}
+	bank.UpdatedAt = time.Now()
+
+	_, err := db.Collection("banks").UpdateOne(ctx,
+		bson.M{"code": bank.Code},
+		bson.M{"$set": bank},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save bank: %w", err)
+	}
+	return &bank, nil
+}
+
+// findBank matches a bank by code or short name, ignoring case
+func findBank(banks []Bank, id string) *Bank {
+	id = strings.TrimSpace(id)
+	for i := range banks {
+		if banks[i].Code == id || strings.EqualFold(banks[i].ShortName, id) {
+			return &banks[i]
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
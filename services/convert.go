@@ -0,0 +1,42 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toFloat converts a numeric value decoded from MongoDB into float64.
+// Balances written by older clients may be stored as int32/int64.
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	}
+	return 0
+}
+
+// roundMoney rounds an amount to satang (2 decimal places)
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// toTime converts a date decoded from MongoDB into time.Time
+func toTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t.Time()
+	case time.Time:
+		return t
+	}
+	return time.Time{}
+}
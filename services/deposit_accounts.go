@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Deposit account statuses
+const (
+	AccountStatusActive  = "active"
+	AccountStatusFrozen  = "frozen"
+	AccountStatusDormant = "dormant"
+	AccountStatusClosed  = "closed"
+)
+
+// Deposit account product types
+const (
+	AccountTypeSavings = "savings"
+	AccountTypeFixed   = "fixed"
+)
+
+// accountNumberPrefixes is the 3-digit prefix of generated account numbers per product.
+// A number is prefix + 6-digit running number + Luhn check digit.
+var accountNumberPrefixes = map[string]string{
+	AccountTypeSavings: "101",
+	AccountTypeFixed:   "201",
+}
+
+// defaultDormantAfterDays is used when DORMANT_AFTER_DAYS is not set
+const defaultDormantAfterDays = 365
+
+var (
+	ErrUnknownProduct       = errors.New("account_type must be savings or fixed")
+	ErrMemberNotFound       = errors.New("member not found")
+	ErrAccountClosed        = errors.New("account is already closed")
+	ErrInvalidStatusChange  = errors.New("status change not allowed")
+	ErrSettlementRequired   = errors.New("account has a balance; settlement must be transfer or cash")
+	ErrAccountNumberRunsOut = errors.New("account number range exhausted")
+)
+
+// OpenAccountRequest describes a new deposit account
+type OpenAccountRequest struct {
+	MemberID    string
+	AccountType string
+	AccountName string
+}
+
+// CloseAccountRequest describes an account closure. A remaining balance is
+// settled by transfer to another account of the same member or paid out in cash.
+type CloseAccountRequest struct {
+	AccountID           string
+	OfficerID           string
+	Reason              string
+	Settlement          string // transfer, cash
+	SettlementAccountID string
+}
+
+// CloseAccountResult describes a closed account and how its balance was settled
+type CloseAccountResult struct {
+	AccountID           string    `json:"accountid"`
+	SettledAmount       float64   `json:"settled_amount"`
+	Settlement          string    `json:"settlement,omitempty"`
+	SettlementAccountID string    `json:"settlement_account_id,omitempty"`
+	TransactionID       string    `json:"transaction_id,omitempty"`
+	ClosedAt            time.Time `json:"closed_at"`
+}
+
+// OpenDepositAccount creates an active, zero-balance account with a new account number
+func OpenDepositAccount(ctx context.Context, db *mongo.Database, req OpenAccountRequest) (*models.DepositAccount, error) {
+	if req.AccountType == AccountTypeFixed {
+		return nil, fmt.Errorf("%w: fixed deposits are opened through /fixed-deposits/open", ErrUnknownProduct)
+	}
+	account, err := newDepositAccount(ctx, db, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers imported through the gateway may already use part of the range,
+	// so skip ahead on a duplicate key.
+	for attempt := 0; attempt < 5; attempt++ {
+		number, err := nextAccountNumber(ctx, db, accountNumberPrefixes[req.AccountType])
+		if err != nil {
+			return nil, err
+		}
+		account.AccountNumber = number
+
+		_, err = db.Collection("deposit_accounts").InsertOne(ctx, account)
+		if err == nil {
+			return account, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to create account: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to allocate a unique account number")
+}
+
+// newDepositAccount validates the request and builds an active, zero-balance account
+// without an account number
+func newDepositAccount(ctx context.Context, db *mongo.Database, req OpenAccountRequest) (*models.DepositAccount, error) {
+	if _, ok := accountNumberPrefixes[req.AccountType]; !ok {
+		return nil, ErrUnknownProduct
+	}
+	if req.AccountName == "" {
+		return nil, fmt.Errorf("account_name is required")
+	}
+
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": req.MemberID}).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load member: %w", err)
+	}
+
+	now := time.Now()
+	return &models.DepositAccount{
+		AccountID:      "ACC-" + uuid.New().String(),
+		MemberID:       req.MemberID,
+		AccountName:    req.AccountName,
+		AccountType:    req.AccountType,
+		Status:         AccountStatusActive,
+		OpenedAt:       now,
+		LastActivityAt: now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// nextAccountNumber takes the next running number for a prefix from counters
+func nextAccountNumber(ctx context.Context, db *mongo.Database, prefix string) (string, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := db.Collection("counters").FindOneAndUpdate(ctx,
+		bson.M{"_id": "accountnumber-" + prefix},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate account number: %w", err)
+	}
+	if counter.Seq > 999999 {
+		return "", ErrAccountNumberRunsOut
+	}
+
+	body := fmt.Sprintf("%s%06d", prefix, counter.Seq)
+	return body + luhnCheckDigit(body), nil
+}
+
+// luhnCheckDigit computes the Luhn check digit for a string of digits
+func luhnCheckDigit(digits string) string {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+// FreezeAccount blocks all money movement on an account
+func FreezeAccount(ctx context.Context, db *mongo.Database, accountID, officerID, reason string) error {
+	return changeAccountStatus(ctx, db, accountID, AccountStatusFrozen, reason, officerID,
+		[]string{AccountStatusActive, AccountStatusDormant})
+}
+
+// UnfreezeAccount returns a frozen account to active
+func UnfreezeAccount(ctx context.Context, db *mongo.Database, accountID, officerID, reason string) error {
+	return changeAccountStatus(ctx, db, accountID, AccountStatusActive, reason, officerID,
+		[]string{AccountStatusFrozen})
+}
+
+// ReactivateAccount returns a dormant account to active
+func ReactivateAccount(ctx context.Context, db *mongo.Database, accountID, officerID, reason string) error {
+	return changeAccountStatus(ctx, db, accountID, AccountStatusActive, reason, officerID,
+		[]string{AccountStatusDormant})
+}
+
+// changeAccountStatus moves an account to a new status if its current status is in from.
+// Accounts without a status field count as active.
+func changeAccountStatus(ctx context.Context, db *mongo.Database, accountID, to, reason, changedBy string, from []string) error {
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": accountID}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+
+	current, _ := account["status"].(string)
+	if current == "" {
+		current = AccountStatusActive
+	}
+	allowed := false
+	for _, s := range from {
+		if s == current {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: account is %s", ErrInvalidStatusChange, current)
+	}
+
+	now := time.Now()
+	set := bson.M{"status": to, "statusreason": reason, "updatedat": now}
+	if to == AccountStatusActive {
+		// Restart the inactivity clock so the account is not marked dormant again at once
+		set["lastactivityat"] = now
+	}
+
+	// Conditional on the status read above so concurrent changes cannot interleave
+	res, err := db.Collection("deposit_accounts").UpdateOne(ctx,
+		bson.M{"accountid": accountID, "status": account["status"]},
+		bson.M{
+			"$set": set,
+			"$push": bson.M{"statushistory": models.AccountStatusChange{
+				From: current, To: to, Reason: reason, ChangedBy: changedBy, ChangedAt: now,
+			}},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to update account status: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: account status changed, please retry", ErrInvalidStatusChange)
+	}
+	return nil
+}
+
+// MarkDormantAccounts sets active savings accounts with no activity since the cutoff to dormant
+func MarkDormantAccounts(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	days := defaultDormantAfterDays
+	if v, err := strconv.Atoi(os.Getenv("DORMANT_AFTER_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	cutoff := now.AddDate(0, 0, -days)
+
+	filter := bson.M{
+		"status":      bson.M{"$in": bson.A{AccountStatusActive, nil}},
+		"accounttype": bson.M{"$ne": AccountTypeFixed},
+		"$or": bson.A{
+			bson.M{"lastactivityat": bson.M{"$lt": cutoff}},
+			bson.M{"lastactivityat": bson.M{"$exists": false}, "openedat": bson.M{"$lt": cutoff}},
+		},
+	}
+	cursor, err := db.Collection("deposit_accounts").Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"accountid": 1, "accountnumber": 1, "memberid": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query inactive accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	reason := fmt.Sprintf("no activity for %d days", days)
+	for cursor.Next(ctx) {
+		var acc bson.M
+		if err := cursor.Decode(&acc); err != nil {
+			return count, fmt.Errorf("failed to decode account: %w", err)
+		}
+		accountID, _ := acc["accountid"].(string)
+		err := changeAccountStatus(ctx, db, accountID, AccountStatusDormant, reason, "system",
+			[]string{AccountStatusActive})
+		if errors.Is(err, ErrInvalidStatusChange) {
+			continue // changed since the query
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+
+		memberID, _ := acc["memberid"].(string)
+		notifyMember(ctx, db, memberID, "บัญชีพักการเคลื่อนไหว",
+			fmt.Sprintf("บัญชี %v ไม่มีการเคลื่อนไหวเกิน %d วัน และถูกเปลี่ยนเป็นบัญชีพักการเคลื่อนไหว กรุณาติดต่อเจ้าหน้าที่เพื่อเปิดใช้งาน", acc["accountnumber"], days),
+			"account")
+	}
+	return count, cursor.Err()
+}
+
+func runDormantAccountsJob(ctx context.Context, db *mongo.Database) error {
+	n, err := MarkDormantAccounts(ctx, db, time.Now())
+	if n > 0 {
+		log.Printf("Dormant accounts: marked %d", n)
+	}
+	return err
+}
+
+// CloseDepositAccount settles the remaining balance and closes the account in one transaction
+func CloseDepositAccount(ctx context.Context, db *mongo.Database, req CloseAccountRequest) (*CloseAccountResult, error) {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var result *CloseAccountResult
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var account bson.M
+		err := db.Collection("deposit_accounts").FindOne(sc, bson.M{"accountid": req.AccountID}).Decode(&account)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAccountNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load account: %w", err)
+		}
+
+		if account["accounttype"] == AccountTypeFixed {
+			return nil, fmt.Errorf("%w: fixed deposits are closed at maturity or by early withdrawal", ErrInvalidStatusChange)
+		}
+
+		status, _ := account["status"].(string)
+		switch status {
+		case AccountStatusClosed:
+			return nil, ErrAccountClosed
+		case AccountStatusFrozen, "inactive":
+			return nil, fmt.Errorf("%w: account is %s", ErrInvalidStatusChange, status)
+		}
+		if status == "" {
+			status = AccountStatusActive
+		}
+
+		balance := roundMoney(toFloat(account["balance"]))
+		if balance > 0 && req.Settlement != "transfer" && req.Settlement != "cash" {
+			return nil, ErrSettlementRequired
+		}
+
+		// Zero the balance and close, conditional on nothing having changed since the read
+		now := time.Now()
+		res, err := db.Collection("deposit_accounts").UpdateOne(sc,
+			bson.M{"accountid": req.AccountID, "balance": account["balance"], "status": account["status"]},
+			bson.M{
+				"$set": bson.M{
+					"balance":      0.0,
+					"status":       AccountStatusClosed,
+					"statusreason": req.Reason,
+					"closedat":     now,
+					"closedby":     req.OfficerID,
+					"updatedat":    now,
+				},
+				"$push": bson.M{"statushistory": models.AccountStatusChange{
+					From: status, To: AccountStatusClosed, Reason: req.Reason, ChangedBy: req.OfficerID, ChangedAt: now,
+				}},
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to close account: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, fmt.Errorf("account changed while closing, please retry")
+		}
+
+		result = &CloseAccountResult{AccountID: req.AccountID, SettledAmount: balance, ClosedAt: now}
+		if balance == 0 {
+			return nil, nil
+		}
+		result.Settlement = req.Settlement
+		result.TransactionID = fmt.Sprintf("TXN-CLOSE-%d", now.UnixNano())
+
+		closingTx := bson.M{
+			"transactionid": result.TransactionID,
+			"accountid":     req.AccountID,
+			"amount":        balance,
+			"balanceafter":  0.0,
+			"datetime":      now,
+			"status":        "completed",
+		}
+		var lines []models.JournalLine
+
+		if req.Settlement == "transfer" {
+			if req.SettlementAccountID == "" || req.SettlementAccountID == req.AccountID {
+				return nil, fmt.Errorf("settlement_account_id must be another account of the member")
+			}
+			dest, err := creditAccount(sc, db, req.SettlementAccountID, balance, ErrDestNotFound)
+			if err != nil {
+				return nil, err
+			}
+			if dest["memberid"] != account["memberid"] {
+				return nil, ErrNotAccountOwner
+			}
+			result.SettlementAccountID = req.SettlementAccountID
+
+			closingTx["type"] = "transfer_out"
+			closingTx["description"] = fmt.Sprintf("ปิดบัญชี (โอนให้ %v)", dest["accountname"])
+			closingTx["referenceno"] = req.SettlementAccountID
+			destTx := bson.M{
+				"transactionid": fmt.Sprintf("TXN-IN-%d", now.UnixNano()),
+				"accountid":     req.SettlementAccountID,
+				"type":          "transfer_in",
+				"amount":        balance,
+				"balanceafter":  toFloat(dest["balance"]),
+				"datetime":      now,
+				"description":   fmt.Sprintf("รับโอนจากการปิดบัญชี %v", account["accountnumber"]),
+				"referenceno":   req.AccountID,
+				"status":        "completed",
+			}
+			if _, err := db.Collection("deposit_transactions").InsertMany(sc, []interface{}{closingTx, destTx}); err != nil {
+				return nil, fmt.Errorf("failed to record transactions: %w", err)
+			}
+			lines = []models.JournalLine{
+				{AccountCode: GLMemberDeposits, SubAccount: req.AccountID, Debit: balance},
+				{AccountCode: GLMemberDeposits, SubAccount: req.SettlementAccountID, Credit: balance},
+			}
+		} else {
+			closingTx["type"] = "withdrawal"
+			closingTx["description"] = "ปิดบัญชี (จ่ายเงินสด)"
+			if _, err := db.Collection("deposit_transactions").InsertOne(sc, closingTx); err != nil {
+				return nil, fmt.Errorf("failed to record transaction: %w", err)
+			}
+			lines = []models.JournalLine{
+				{AccountCode: GLMemberDeposits, SubAccount: req.AccountID, Debit: balance},
+				{AccountCode: GLCash, Credit: balance},
+			}
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "ปิดบัญชีเงินฝาก",
+			SourceType:  "account_closure",
+			SourceRef:   result.TransactionID,
+			Lines:       lines,
+		})
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
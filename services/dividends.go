@@ -0,0 +1,503 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+var (
+	ErrDividendRate        = errors.New("dividend_rate and patronage_rate must be between 0 and 100")
+	ErrDividendRunNotFound = errors.New("no dividend preview for this fiscal year")
+	ErrDividendFinalized   = errors.New("dividends for this fiscal year have already been approved")
+	ErrFiscalYearOpen      = errors.New("the fiscal year has not ended yet")
+	ErrStaleDividendRun    = errors.New("the preview was run before the fiscal year ended, run the preview again")
+	ErrDividendNotFound    = errors.New("no dividend for this member and fiscal year")
+)
+
+// fiscalYearStartMonth is the first month of the fiscal year (FISCAL_YEAR_START_MONTH, default January).
+// A fiscal year is named by the calendar year it ends in.
+func fiscalYearStartMonth() time.Month {
+	if m, err := strconv.Atoi(os.Getenv("FISCAL_YEAR_START_MONTH")); err == nil && m >= 1 && m <= 12 {
+		return time.Month(m)
+	}
+	return time.January
+}
+
+// FiscalYearPeriod returns the start and (exclusive) end of a fiscal year in Bangkok time
+func FiscalYearPeriod(year int) (time.Time, time.Time) {
+	month := fiscalYearStartMonth()
+	startYear := year
+	if month != time.January {
+		startYear = year - 1
+	}
+	start := time.Date(startYear, month, 1, 0, 0, 0, 0, Bangkok)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// PreviewDividends computes every member's dividend and patronage refund for the board.
+// Previews can be re-run with other rates until the run is approved.
+func PreviewDividends(ctx context.Context, db *mongo.Database, year int, dividendRate, patronageRate float64, officerID string) (*models.DividendRun, error) {
+	if dividendRate < 0 || dividendRate > 100 || patronageRate < 0 || patronageRate > 100 {
+		return nil, ErrDividendRate
+	}
+
+	existing, err := GetDividendRun(ctx, db, year)
+	if err != nil && !errors.Is(err, ErrDividendRunNotFound) {
+		return nil, err
+	}
+	if existing != nil && existing.Status != "preview" {
+		return nil, ErrDividendFinalized
+	}
+
+	start, end := FiscalYearPeriod(year)
+	payments, err := computeDividendPayments(ctx, db, year, start, end, dividendRate, patronageRate)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := &models.DividendRun{
+		FiscalYear:    year,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		DividendRate:  dividendRate,
+		PatronageRate: patronageRate,
+		Status:        "preview",
+		MemberCount:   len(payments),
+		PreviewedBy:   officerID,
+		PreviewedAt:   now,
+		UpdatedAt:     now,
+	}
+	docs := make([]interface{}, 0, len(payments))
+	for _, p := range payments {
+		run.TotalDividend += p.Dividend
+		run.TotalPatronage += p.PatronageRefund
+		docs = append(docs, p)
+	}
+	run.TotalDividend = roundMoney(run.TotalDividend)
+	run.TotalPatronage = roundMoney(run.TotalPatronage)
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		res, err := db.Collection("dividend_rates").UpdateOne(sc,
+			bson.M{"fiscalyear": year, "status": "preview"},
+			bson.M{"$set": run},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, ErrDividendFinalized
+			}
+			return nil, fmt.Errorf("failed to save dividend run: %w", err)
+		}
+		if res.MatchedCount == 0 && res.UpsertedCount == 0 {
+			return nil, ErrDividendFinalized
+		}
+
+		if _, err := db.Collection("dividend_payments").DeleteMany(sc, bson.M{"fiscalyear": year}); err != nil {
+			return nil, fmt.Errorf("failed to clear previous preview: %w", err)
+		}
+		if len(docs) > 0 {
+			if _, err := db.Collection("dividend_payments").InsertMany(sc, docs); err != nil {
+				return nil, fmt.Errorf("failed to save dividend preview: %w", err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// computeDividendPayments works out each member's share value at every month end of the
+// fiscal year (walking back from the current share accounts through share_transactions)
+// and the loan interest they paid in the year.
+func computeDividendPayments(ctx context.Context, db *mongo.Database, year int, start, end time.Time, dividendRate, patronageRate float64) ([]models.DividendPayment, error) {
+	current := map[string]float64{}
+	cursor, err := db.Collection("share_accounts").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"memberid": 1, "value": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share accounts: %w", err)
+	}
+	var holdings []models.ShareAccount
+	if err := cursor.All(ctx, &holdings); err != nil {
+		return nil, fmt.Errorf("failed to decode share accounts: %w", err)
+	}
+	for _, h := range holdings {
+		current[h.MemberID] += h.Value
+	}
+
+	// Share transactions since the start of the year, to undo movements after each month end
+	cursor, err = db.Collection("share_transactions").Find(ctx, bson.M{
+		"datetime": bson.M{"$gte": start},
+		"status":   "completed",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share transactions: %w", err)
+	}
+	var txs []models.ShareTransaction
+	if err := cursor.All(ctx, &txs); err != nil {
+		return nil, fmt.Errorf("failed to decode share transactions: %w", err)
+	}
+	movements := map[string][]models.ShareTransaction{}
+	for _, tx := range txs {
+		movements[tx.MemberID] = append(movements[tx.MemberID], tx)
+		if _, ok := current[tx.MemberID]; !ok {
+			current[tx.MemberID] = 0
+		}
+	}
+
+	interest, err := loanInterestPaid(ctx, db, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for memberID := range interest {
+		if _, ok := current[memberID]; !ok {
+			current[memberID] = 0
+		}
+	}
+
+	now := time.Now()
+	payments := []models.DividendPayment{}
+	for memberID, value := range current {
+		balances := make([]float64, 12)
+		var sum float64
+		for m := 0; m < 12; m++ {
+			monthEnd := start.AddDate(0, m+1, 0)
+			balance := value
+			for _, tx := range movements[memberID] {
+				if tx.DateTime.Before(monthEnd) {
+					continue
+				}
+				switch tx.Type {
+				case "redemption":
+					balance += tx.Amount
+				case "migration":
+					// moved between share types, the member's total is unchanged
+				default:
+					balance -= tx.Amount
+				}
+			}
+			if balance < 0 {
+				balance = 0
+			}
+			balances[m] = roundMoney(balance)
+			sum += balances[m]
+		}
+
+		average := roundMoney(sum / 12)
+		dividend := roundMoney(average * dividendRate / 100)
+		paid := roundMoney(interest[memberID])
+		patronage := roundMoney(paid * patronageRate / 100)
+		if dividend+patronage <= 0 {
+			continue
+		}
+
+		payment := models.DividendPayment{
+			FiscalYear:       year,
+			MemberID:         memberID,
+			MonthlyBalances:  balances,
+			AverageShares:    average,
+			DividendRate:     dividendRate,
+			Dividend:         dividend,
+			LoanInterestPaid: paid,
+			PatronageRate:    patronageRate,
+			PatronageRefund:  patronage,
+			Total:            roundMoney(dividend + patronage),
+			Status:           "preview",
+			CreatedAt:        now,
+		}
+		if accountID, accountNumber, err := dividendAccountFor(ctx, db, memberID); err == nil {
+			payment.AccountID = accountID
+			payment.AccountNumber = accountNumber
+		}
+		payments = append(payments, payment)
+	}
+	return payments, nil
+}
+
+// loanInterestPaid sums loan interest paid per member in [start, end)
+func loanInterestPaid(ctx context.Context, db *mongo.Database, start, end time.Time) (map[string]float64, error) {
+	cursor, err := db.Collection("loan_payments").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"paymentdate": bson.M{"$gte": start, "$lt": end},
+			"status":      "completed",
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$memberid", "interest": bson.M{"$sum": "$interest"}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum loan interest: %w", err)
+	}
+	var rows []bson.M
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode loan interest: %w", err)
+	}
+
+	result := map[string]float64{}
+	for _, row := range rows {
+		if memberID, ok := row["_id"].(string); ok {
+			result[memberID] = toFloat(row["interest"])
+		}
+	}
+	return result, nil
+}
+
+// dividendAccountFor picks the member's oldest savings account that can receive money
+// and returns its id and account number
+func dividendAccountFor(ctx context.Context, db *mongo.Database, memberID string) (string, string, error) {
+	var acc bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{
+		"memberid":    memberID,
+		"accounttype": bson.M{"$ne": AccountTypeFixed},
+		"status":      bson.M{"$nin": blockedAccountStatuses},
+	}, options.FindOne().SetSort(bson.D{{Key: "openedat", Value: 1}, {Key: "_id", Value: 1}})).Decode(&acc)
+	if err == mongo.ErrNoDocuments {
+		return "", "", fmt.Errorf("%w: member has no savings account that can receive money", ErrDestNotFound)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find deposit account: %w", err)
+	}
+	accountID, _ := acc["accountid"].(string)
+	accountNumber, _ := acc["accountnumber"].(string)
+	return accountID, accountNumber, nil
+}
+
+// GetDividendRun returns the dividend run of a fiscal year
+func GetDividendRun(ctx context.Context, db *mongo.Database, year int) (*models.DividendRun, error) {
+	var run models.DividendRun
+	err := db.Collection("dividend_rates").FindOne(ctx, bson.M{"fiscalyear": year}).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDividendRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dividend run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListDividendPayments returns the per-member figures of a fiscal year, largest first
+func ListDividendPayments(ctx context.Context, db *mongo.Database, year int, status string, limit int64) ([]models.DividendPayment, error) {
+	filter := bson.M{"fiscalyear": year}
+	if status != "" {
+		filter["status"] = status
+	}
+	cursor, err := db.Collection("dividend_payments").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "total", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dividend payments: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	payments := []models.DividendPayment{}
+	if err := cursor.All(ctx, &payments); err != nil {
+		return nil, fmt.Errorf("failed to decode dividend payments: %w", err)
+	}
+	return payments, nil
+}
+
+// GetDividendPayment returns one member's dividend for a fiscal year
+func GetDividendPayment(ctx context.Context, db *mongo.Database, year int, memberID string) (*models.DividendPayment, error) {
+	var p models.DividendPayment
+	err := db.Collection("dividend_payments").FindOne(ctx, bson.M{"fiscalyear": year, "memberid": memberID}).Decode(&p)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDividendNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dividend payment: %w", err)
+	}
+	return &p, nil
+}
+
+// FinalizeDividends approves the previewed figures and pays them into members' savings
+// accounts. Each member is paid in its own transaction; members that cannot be paid are
+// marked unpaid and retried by calling FinalizeDividends again. dividend_rates and
+// dividend_payments are written only by PreviewDividends and this function; the generic
+// gateway cannot reach them.
+func FinalizeDividends(ctx context.Context, db *mongo.Database, year int, officerID, boardResolution string) (*models.DividendRun, error) {
+	run, err := GetDividendRun(ctx, db, year)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == "paid" {
+		return nil, ErrDividendFinalized
+	}
+
+	now := time.Now()
+	if run.Status == "preview" {
+		if now.Before(run.PeriodEnd) {
+			return nil, ErrFiscalYearOpen
+		}
+		if run.PreviewedAt.Before(run.PeriodEnd) {
+			return nil, ErrStaleDividendRun
+		}
+		res, err := db.Collection("dividend_rates").UpdateOne(ctx,
+			bson.M{"fiscalyear": year, "status": "preview"},
+			bson.M{"$set": bson.M{
+				"status":          "approved",
+				"approvedby":      officerID,
+				"boardresolution": boardResolution,
+				"approvedat":      now,
+				"updatedat":       now,
+			}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to approve dividend run: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrDividendFinalized
+		}
+	}
+
+	cursor, err := db.Collection("dividend_payments").Find(ctx, bson.M{
+		"fiscalyear": year,
+		"status":     bson.M{"$in": []string{"preview", "unpaid"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dividend payments: %w", err)
+	}
+	var pending []models.DividendPayment
+	if err := cursor.All(ctx, &pending); err != nil {
+		return nil, fmt.Errorf("failed to decode dividend payments: %w", err)
+	}
+
+	for i := range pending {
+		p := &pending[i]
+		if err := payDividend(ctx, db, p); err != nil {
+			log.Printf("Dividend %d for %s not paid: %v", year, p.MemberID, err)
+			db.Collection("dividend_payments").UpdateOne(ctx,
+				bson.M{"_id": p.ID, "status": bson.M{"$ne": "paid"}},
+				bson.M{"$set": bson.M{"status": "unpaid", "failurereason": err.Error()}})
+			continue
+		}
+		notifyMember(ctx, db, p.MemberID, "ได้รับเงินปันผลและเงินเฉลี่ยคืน",
+			fmt.Sprintf("ปีบัญชี %d ได้รับเงินปันผล %.2f บาท และเงินเฉลี่ยคืน %.2f บาท รวม %.2f บาท เข้าบัญชีเงินฝากแล้ว",
+				year+543, p.Dividend, p.PatronageRefund, p.Total), "dividend")
+	}
+
+	paid, err := db.Collection("dividend_payments").CountDocuments(ctx, bson.M{"fiscalyear": year, "status": "paid"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count paid dividends: %w", err)
+	}
+	unpaid, err := db.Collection("dividend_payments").CountDocuments(ctx, bson.M{"fiscalyear": year, "status": bson.M{"$ne": "paid"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unpaid dividends: %w", err)
+	}
+	set := bson.M{"paidcount": int(paid), "unpaidcount": int(unpaid), "updatedat": time.Now()}
+	if unpaid == 0 {
+		set["status"] = "paid"
+		set["paidat"] = time.Now()
+	}
+	if _, err := db.Collection("dividend_rates").UpdateOne(ctx, bson.M{"fiscalyear": year}, bson.M{"$set": set}); err != nil {
+		return nil, fmt.Errorf("failed to update dividend run: %w", err)
+	}
+	return GetDividendRun(ctx, db, year)
+}
+
+// payDividend credits one member's dividend and patronage refund and posts
+// Dr retained earnings / Cr member deposits
+func payDividend(ctx context.Context, db *mongo.Database, p *models.DividendPayment) error {
+	accountID, accountNumber, err := dividendAccountFor(ctx, db, p.MemberID)
+	if err != nil {
+		return err
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+		txID := fmt.Sprintf("TXN-DIV-%d", now.UnixNano())
+		res, err := db.Collection("dividend_payments").UpdateOne(sc,
+			bson.M{"_id": p.ID, "status": bson.M{"$in": []string{"preview", "unpaid"}}},
+			bson.M{
+				"$set": bson.M{
+					"status":        "paid",
+					"accountid":     accountID,
+					"accountnumber": accountNumber,
+					"transactionid": txID,
+					"paidat":        now,
+				},
+				"$unset": bson.M{"failurereason": ""},
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update dividend payment: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, fmt.Errorf("dividend payment already processed")
+		}
+
+		account, err := creditAccount(sc, db, accountID, p.Total, ErrDestNotFound)
+		if err != nil {
+			return nil, err
+		}
+		balance := toFloat(account["balance"])
+
+		year := p.FiscalYear + 543
+		records := []interface{}{}
+		if p.Dividend > 0 {
+			records = append(records, bson.M{
+				"transactionid": txID,
+				"accountid":     accountID,
+				"type":          "dividend",
+				"amount":        p.Dividend,
+				"balanceafter":  roundMoney(balance - p.PatronageRefund),
+				"datetime":      now,
+				"description":   fmt.Sprintf("เงินปันผลปีบัญชี %d", year),
+				"referenceno":   fmt.Sprintf("DIV-%d", p.FiscalYear),
+				"status":        "completed",
+			})
+		}
+		if p.PatronageRefund > 0 {
+			records = append(records, bson.M{
+				"transactionid": txID + "-PR",
+				"accountid":     accountID,
+				"type":          "patronage_refund",
+				"amount":        p.PatronageRefund,
+				"balanceafter":  balance,
+				"datetime":      now,
+				"description":   fmt.Sprintf("เงินเฉลี่ยคืนปีบัญชี %d", year),
+				"referenceno":   fmt.Sprintf("DIV-%d", p.FiscalYear),
+				"status":        "completed",
+			})
+		}
+		if _, err := db.Collection("deposit_transactions").InsertMany(sc, records); err != nil {
+			return nil, fmt.Errorf("failed to record transactions: %w", err)
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: fmt.Sprintf("จ่ายเงินปันผลและเงินเฉลี่ยคืนปีบัญชี %d", year),
+			SourceType:  "dividend",
+			SourceRef:   txID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLRetainedEarnings, SubAccount: p.MemberID, Debit: p.Total},
+				{AccountCode: GLMemberDeposits, SubAccount: accountID, Credit: p.Total},
+			},
+		})
+		return nil, err
+	})
+	if err != nil {
+		return err
+	}
+	p.AccountID = accountID
+	p.AccountNumber = accountNumber
+	return nil
+}
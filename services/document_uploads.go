@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/config"
+)
+
+// DocumentUploadGracePeriod is how long after its URL expires a direct upload may still be
+// confirmed before the file is removed
+const DocumentUploadGracePeriod = time.Hour
+
+// runDocumentUploadsJob marks uploads that were never confirmed as expired and deletes their
+// files. Each upload is expired with a conditional update first, and only the files of
+// uploads that actually moved to expired are deleted, so a confirm that wins the race keeps
+// its file.
+func runDocumentUploadsJob(ctx context.Context, db *mongo.Database) error {
+	client := config.GetR2Client()
+	if client == nil {
+		return fmt.Errorf("R2 storage not configured")
+	}
+	bucket := config.GetR2Bucket()
+
+	filter := bson.M{
+		"status":     "pending",
+		"expires_at": bson.M{"$lt": time.Now().Add(-DocumentUploadGracePeriod)},
+	}
+	uploads := db.Collection("document_uploads")
+	cursor, err := uploads.Find(ctx, filter,
+		options.Find().SetProjection(bson.M{"r2_key": 1}).SetLimit(1000))
+	if err != nil {
+		return fmt.Errorf("failed to query document uploads: %w", err)
+	}
+	var candidates []struct {
+		ID    interface{} `bson:"_id"`
+		R2Key string      `bson:"r2_key"`
+	}
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return fmt.Errorf("failed to decode document uploads: %w", err)
+	}
+
+	keys := make([]string, 0, len(candidates))
+	for _, u := range candidates {
+		res, err := uploads.UpdateOne(ctx,
+			bson.M{"_id": u.ID, "status": filter["status"], "expires_at": filter["expires_at"]},
+			bson.M{"$set": bson.M{"status": "expired", "expired_at": time.Now()}})
+		if err != nil {
+			log.Printf("Document uploads: failed to expire upload %v: %v", u.ID, err)
+			continue
+		}
+		if res.ModifiedCount == 1 {
+			keys = append(keys, u.R2Key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// Most of these were never uploaded; deleting a missing key is not an error
+	deleteR2Objects(ctx, client, bucket, keys)
+	log.Printf("Document uploads: expired %d unconfirmed uploads", len(keys))
+	return nil
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nfnt/resize"
+
+	"loan-dynamic-api/models"
+)
+
+// Face match flags stored on the submission for reviewers
+const (
+	FaceFlagBelowThreshold  = "below_threshold"
+	FaceFlagSelfieNoFace    = "selfie_face_not_found"
+	FaceFlagIDCardNoFace    = "id_card_face_not_found"
+	FaceFlagProviderFailure = "provider_error"
+)
+
+// defaultFaceMatchThreshold is the score below which a submission is flagged. Override with
+// KYC_FACE_MATCH_THRESHOLD (0-1).
+const defaultFaceMatchThreshold = 0.5
+
+// FaceMatcher compares the face in a selfie with the photo on an ID card. Implementations
+// return a score between 0 and 1 and any flags worth showing to the reviewer.
+type FaceMatcher interface {
+	Match(ctx context.Context, selfie, idCard image.Image) (score float64, flags []string, err error)
+}
+
+// faceMatchers are the available providers, selected with KYC_FACE_MATCH_PROVIDER
+// (default "local", "none" turns matching off)
+var faceMatchers = map[string]FaceMatcher{
+	"local": localFaceMatcher{},
+}
+
+// RegisterFaceMatcher adds or replaces a face match provider, e.g. a hosted verification API
+func RegisterFaceMatcher(name string, m FaceMatcher) {
+	faceMatchers[name] = m
+}
+
+func faceMatchThreshold() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("KYC_FACE_MATCH_THRESHOLD"), 64); err == nil && v >= 0 && v <= 1 {
+		return v
+	}
+	return defaultFaceMatchThreshold
+}
+
+// MatchKYCFaces scores a selfie against an ID card photo with the configured provider.
+// Provider errors are recorded on the result rather than returned, so a submission is never
+// refused because matching was unavailable. Returns nil when matching is turned off.
+func MatchKYCFaces(ctx context.Context, selfieData, idCardData []byte) *models.FaceMatch {
+	name := os.Getenv("KYC_FACE_MATCH_PROVIDER")
+	if name == "" {
+		name = "local"
+	}
+	if name == "none" {
+		return nil
+	}
+
+	result := &models.FaceMatch{
+		Provider:  name,
+		Threshold: faceMatchThreshold(),
+		CheckedAt: time.Now(),
+	}
+	fail := func(err error) *models.FaceMatch {
+		log.Printf("Face match (%s) failed: %v", name, err)
+		result.Flags = append(result.Flags, FaceFlagProviderFailure)
+		result.Error = err.Error()
+		return result
+	}
+
+	matcher, ok := faceMatchers[name]
+	if !ok {
+		return fail(fmt.Errorf("unknown face match provider %q", name))
+	}
+	selfie, _, err := image.Decode(bytes.NewReader(selfieData))
+	if err != nil {
+		return fail(fmt.Errorf("failed to decode selfie: %w", err))
+	}
+	idCard, _, err := image.Decode(bytes.NewReader(idCardData))
+	if err != nil {
+		return fail(fmt.Errorf("failed to decode ID card: %w", err))
+	}
+
+	score, flags, err := matcher.Match(ctx, selfie, idCard)
+	if err != nil {
+		return fail(err)
+	}
+	result.Score = math.Round(score*1000) / 1000
+	result.Flags = append(result.Flags, flags...)
+	result.Passed = result.Score >= result.Threshold
+	if !result.Passed {
+		result.Flags = append(result.Flags, FaceFlagBelowThreshold)
+	}
+	return result
+}
+
+// localFaceMatcher runs on the CPU without a model. It finds the largest skin-coloured
+// region in each image as the face, then compares the two crops with a DCT perceptual hash.
+// Good enough to catch a selfie of a different person or an unrelated photo; officers still
+// make the decision.
+type localFaceMatcher struct{}
+
+// faceSampleSize is the long side images are reduced to before looking for skin
+const faceSampleSize = 200
+
+func (localFaceMatcher) Match(ctx context.Context, selfie, idCard image.Image) (float64, []string, error) {
+	var flags []string
+	selfieFace, ok := largestSkinRegion(selfie)
+	if !ok {
+		flags = append(flags, FaceFlagSelfieNoFace)
+	}
+	cardFace, ok := largestSkinRegion(idCard)
+	if !ok {
+		flags = append(flags, FaceFlagIDCardNoFace)
+	}
+
+	distance := bits.OnesCount64(perceptualHash(selfieFace) ^ perceptualHash(cardFace))
+	// Unrelated images differ in about half of the 64 bits; rescale so that scores near 0 mean
+	// no resemblance and 1 means identical
+	score := 1 - float64(distance)/32
+	if score < 0 {
+		score = 0
+	}
+	return score, flags, nil
+}
+
+// largestSkinRegion returns a crop of the largest connected area of skin tones.
+// If none is big enough the whole image is returned with ok false.
+func largestSkinRegion(img image.Image) (image.Image, bool) {
+	b := img.Bounds()
+	small := img
+	if b.Dx() > faceSampleSize || b.Dy() > faceSampleSize {
+		if b.Dx() >= b.Dy() {
+			small = resize.Resize(faceSampleSize, 0, img, resize.Bilinear)
+		} else {
+			small = resize.Resize(0, faceSampleSize, img, resize.Bilinear)
+		}
+	}
+	sb := small.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+
+	skin := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := small.At(sb.Min.X+x, sb.Min.Y+y).RGBA()
+			_, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(bl>>8))
+			skin[y*w+x] = cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+		}
+	}
+
+	// Largest 4-connected component
+	seen := make([]bool, w*h)
+	bestSize := 0
+	var best image.Rectangle
+	stack := []int{}
+	for start := range skin {
+		if !skin[start] || seen[start] {
+			continue
+		}
+		size := 0
+		minX, minY, maxX, maxY := w, h, 0, 0
+		seen[start] = true
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := p%w, p/w
+			size++
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x), max(maxY, y)
+			for _, q := range [4]int{p - 1, p + 1, p - w, p + w} {
+				if q < 0 || q >= w*h || seen[q] || !skin[q] {
+					continue
+				}
+				if (q == p-1 && x == 0) || (q == p+1 && x == w-1) {
+					continue
+				}
+				seen[q] = true
+				stack = append(stack, q)
+			}
+		}
+		if size > bestSize {
+			bestSize = size
+			best = image.Rect(minX, minY, maxX+1, maxY+1)
+		}
+	}
+	// A face covers at least 1% of an ID card photo
+	if bestSize < w*h/100 {
+		return img, false
+	}
+
+	// The middle of the region, mapped back to the original image. Trimming the edges keeps
+	// the background and hair around the face out of the hash.
+	scale := float64(b.Dx()) / float64(w)
+	insetX := float64(best.Dx()) * 0.15
+	insetY := float64(best.Dy()) * 0.1
+	crop := image.Rect(
+		b.Min.X+int((float64(best.Min.X)+insetX)*scale), b.Min.Y+int((float64(best.Min.Y)+insetY)*scale),
+		b.Min.X+int((float64(best.Max.X)-insetX)*scale), b.Min.Y+int((float64(best.Max.Y)-insetY)*scale),
+	).Intersect(b)
+	if crop.Empty() {
+		return img, false
+	}
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(crop), true
+	}
+	return img, false
+}
+
+// perceptualHash is the 64-bit DCT hash of an image: the signs of the 8x8 lowest frequencies
+// of a 32x32 grey copy relative to their median
+func perceptualHash(img image.Image) uint64 {
+	const n = 32
+	small := resize.Resize(n, n, img, resize.Bilinear)
+	sb := small.Bounds()
+	var px [n][n]float64
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			px[y][x] = float64(color.GrayModel.Convert(small.At(sb.Min.X+x, sb.Min.Y+y)).(color.Gray).Y)
+		}
+	}
+
+	var cos [8][n]float64
+	for u := 0; u < 8; u++ {
+		for x := 0; x < n; x++ {
+			cos[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * n))
+		}
+	}
+	coeffs := make([]float64, 0, 64)
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					sum += px[y][x] * cos[u][x] * cos[v][y]
+				}
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+
+	// The DC term only carries overall brightness
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	var hash uint64
+	for i, c := range coeffs {
+		if i > 0 && c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,784 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Maturity instructions for fixed deposits
+const (
+	MaturityRenewPrincipal = "renew_principal" // pay interest out, renew the principal
+	MaturityRenewAll       = "renew_all"       // add interest to the principal and renew
+	MaturityPayout         = "payout"          // pay principal and interest out and close
+)
+
+var (
+	ErrProductNotFound       = errors.New("fixed deposit product not found or inactive")
+	ErrNotFixedDeposit       = errors.New("account is not an active fixed deposit")
+	ErrFixedDepositMatured   = errors.New("fixed deposit has matured and is settled by the maturity job")
+	ErrInvalidMaturityOption = errors.New("maturity_instruction must be renew_principal, renew_all or payout")
+)
+
+// OpenFixedDepositRequest describes a new fixed deposit funded from a savings account
+type OpenFixedDepositRequest struct {
+	MemberID            string
+	ProductCode         string
+	Amount              float64
+	FundingAccountID    string
+	MaturityInstruction string
+	PayoutAccountID     string
+	AccountName         string
+}
+
+// EarlyWithdrawalQuote is what a member receives when closing a fixed deposit before maturity
+type EarlyWithdrawalQuote struct {
+	AccountID        string    `json:"accountid"`
+	Principal        float64   `json:"principal"`
+	StartDate        time.Time `json:"start_date"`
+	MaturityDate     time.Time `json:"maturity_date"`
+	HeldDays         int       `json:"held_days"`
+	ContractInterest float64   `json:"contract_interest"` // accrued at the contract rate
+	AppliedRate      float64   `json:"applied_rate"`
+	GrossInterest    float64   `json:"gross_interest"`
+	TaxWithheld      float64   `json:"tax_withheld"`
+	NetInterest      float64   `json:"net_interest"`
+	Penalty          float64   `json:"penalty"` // contract interest forfeited
+	PayoutAmount     float64   `json:"payout_amount"`
+}
+
+// SaveFixedDepositProduct creates or updates a product by product code
+func SaveFixedDepositProduct(ctx context.Context, db *mongo.Database, p models.FixedDepositProduct) (*models.FixedDepositProduct, error) {
+	if p.ProductCode == "" || p.Name == "" {
+		return nil, fmt.Errorf("product_code and name are required")
+	}
+	if p.TermMonths <= 0 || p.InterestRate < 0 || p.EarlyWithdrawalRate < 0 || p.MinHoldMonths < 0 {
+		return nil, fmt.Errorf("term_months must be positive and rates cannot be negative")
+	}
+	if p.WithholdingTaxRate < 0 || p.WithholdingTaxRate > 100 {
+		return nil, fmt.Errorf("withholding_tax_rate must be between 0 and 100")
+	}
+	if p.Status == "" {
+		p.Status = "active"
+	}
+
+	now := time.Now()
+	p.UpdatedAt = now
+	_, err := db.Collection("fixed_deposit_products").UpdateOne(ctx,
+		bson.M{"productcode": p.ProductCode},
+		bson.M{
+			"$set": bson.M{
+				"name":                p.Name,
+				"termmonths":          p.TermMonths,
+				"interestrate":        p.InterestRate,
+				"minamount":           p.MinAmount,
+				"maxamount":           p.MaxAmount,
+				"withholdingtaxrate":  p.WithholdingTaxRate,
+				"earlywithdrawalrate": p.EarlyWithdrawalRate,
+				"minholdmonths":       p.MinHoldMonths,
+				"status":              p.Status,
+				"updatedat":           now,
+			},
+			"$setOnInsert": bson.M{"createdat": now},
+		},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save product: %w", err)
+	}
+	return &p, nil
+}
+
+// ListFixedDepositProducts returns products, only active ones unless all is set
+func ListFixedDepositProducts(ctx context.Context, db *mongo.Database, all bool) ([]models.FixedDepositProduct, error) {
+	filter := bson.M{"status": "active"}
+	if all {
+		filter = bson.M{}
+	}
+	cursor, err := db.Collection("fixed_deposit_products").Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "termmonths", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	products := []models.FixedDepositProduct{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, fmt.Errorf("failed to decode products: %w", err)
+	}
+	return products, nil
+}
+
+func loadFixedDepositProduct(ctx context.Context, db *mongo.Database, code string) (*models.FixedDepositProduct, error) {
+	var p models.FixedDepositProduct
+	err := db.Collection("fixed_deposit_products").FindOne(ctx, bson.M{"productcode": code, "status": "active"}).Decode(&p)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product: %w", err)
+	}
+	return &p, nil
+}
+
+// OpenFixedDeposit moves the amount from a savings account into a new fixed deposit account
+func OpenFixedDeposit(ctx context.Context, db *mongo.Database, req OpenFixedDepositRequest) (*models.DepositAccount, error) {
+	amount := roundMoney(req.Amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	product, err := loadFixedDepositProduct(ctx, db, req.ProductCode)
+	if err != nil {
+		return nil, err
+	}
+	if amount < product.MinAmount || (product.MaxAmount > 0 && amount > product.MaxAmount) {
+		return nil, fmt.Errorf("%w: amount must be between %.2f and %.2f", ErrInvalidAmount, product.MinAmount, product.MaxAmount)
+	}
+
+	switch req.MaturityInstruction {
+	case "":
+		req.MaturityInstruction = MaturityRenewAll
+	case MaturityRenewAll, MaturityRenewPrincipal, MaturityPayout:
+	default:
+		return nil, ErrInvalidMaturityOption
+	}
+	if req.PayoutAccountID == "" {
+		req.PayoutAccountID = req.FundingAccountID
+	}
+	if err := checkPayoutAccount(ctx, db, req.PayoutAccountID, req.MemberID); err != nil {
+		return nil, err
+	}
+
+	if req.AccountName == "" {
+		req.AccountName = product.Name
+	}
+	account, err := newDepositAccount(ctx, db, OpenAccountRequest{
+		MemberID:    req.MemberID,
+		AccountType: AccountTypeFixed,
+		AccountName: req.AccountName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := account.OpenedAt
+	account.Balance = amount
+	account.FixedDeposit = &models.FixedDepositTerms{
+		ProductCode:         product.ProductCode,
+		Principal:           amount,
+		InterestRate:        product.InterestRate,
+		TermMonths:          product.TermMonths,
+		WithholdingTaxRate:  product.WithholdingTaxRate,
+		StartDate:           now,
+		MaturityDate:        addMonths(now, product.TermMonths),
+		EarlyWithdrawalRate: product.EarlyWithdrawalRate,
+		MinHoldMonths:       product.MinHoldMonths,
+		MaturityInstruction: req.MaturityInstruction,
+		PayoutAccountID:     req.PayoutAccountID,
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	// As in OpenDepositAccount, numbers imported through the gateway may already use part
+	// of the range, so skip ahead on a duplicate key. The whole transaction is retried.
+	for attempt := 0; attempt < 5; attempt++ {
+		account.AccountNumber, err = nextAccountNumber(ctx, db, accountNumberPrefixes[AccountTypeFixed])
+		if err != nil {
+			return nil, err
+		}
+		err = openFixedDepositTx(ctx, session, db, req, product, account, amount, now)
+		if !errors.Is(err, errAccountNumberTaken) {
+			break
+		}
+	}
+	if errors.Is(err, errAccountNumberTaken) {
+		return nil, fmt.Errorf("failed to allocate a unique account number")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// errAccountNumberTaken aborts an opening transaction whose account number is already used
+var errAccountNumberTaken = errors.New("account number already in use")
+
+// openFixedDepositTx moves the principal from the funding account into the new fixed
+// deposit account in one transaction
+func openFixedDepositTx(ctx context.Context, session mongo.Session, db *mongo.Database, req OpenFixedDepositRequest, product *models.FixedDepositProduct, account *models.DepositAccount, amount float64, now time.Time) error {
+	_, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		funding, err := debitAccount(sc, db, req.FundingAccountID, amount, ErrSourceNotFound)
+		if err != nil {
+			return nil, err
+		}
+		if funding["memberid"] != req.MemberID {
+			return nil, ErrNotAccountOwner
+		}
+
+		if _, err := db.Collection("deposit_accounts").InsertOne(sc, account); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errAccountNumberTaken
+			}
+			return nil, fmt.Errorf("failed to create fixed deposit account: %w", err)
+		}
+
+		_, err = db.Collection("deposit_transactions").InsertMany(sc, []interface{}{
+			bson.M{
+				"transactionid": fmt.Sprintf("TXN-OUT-%d", now.UnixNano()),
+				"accountid":     req.FundingAccountID,
+				"type":          "transfer_out",
+				"amount":        amount,
+				"balanceafter":  toFloat(funding["balance"]),
+				"datetime":      now,
+				"description":   fmt.Sprintf("เปิดบัญชีเงินฝากประจำ %s", account.AccountNumber),
+				"referenceno":   account.AccountID,
+				"status":        "completed",
+			},
+			bson.M{
+				"transactionid": fmt.Sprintf("TXN-FD-%d", now.UnixNano()),
+				"accountid":     account.AccountID,
+				"type":          "deposit",
+				"amount":        amount,
+				"balanceafter":  amount,
+				"datetime":      now,
+				"description":   "เงินฝากประจำ " + product.Name,
+				"referenceno":   req.FundingAccountID,
+				"status":        "completed",
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record transactions: %w", err)
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "เปิดบัญชีเงินฝากประจำ",
+			SourceType:  "fixed_deposit_open",
+			SourceRef:   account.AccountID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLMemberDeposits, SubAccount: req.FundingAccountID, Debit: amount},
+				{AccountCode: GLFixedDeposits, SubAccount: account.AccountID, Credit: amount},
+			},
+		})
+		return nil, err
+	})
+	return err
+}
+
+// checkPayoutAccount makes sure interest and principal can be paid to a member's savings account
+func checkPayoutAccount(ctx context.Context, db *mongo.Database, accountID, memberID string) error {
+	var acc bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": accountID}).Decode(&acc)
+	if err == mongo.ErrNoDocuments {
+		return ErrDestNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load payout account: %w", err)
+	}
+	if acc["memberid"] != memberID {
+		return ErrNotAccountOwner
+	}
+	if acc["accounttype"] == AccountTypeFixed {
+		return fmt.Errorf("%w: payout account must be a savings account", ErrAccountNotActive)
+	}
+	if status, _ := acc["status"].(string); status == AccountStatusClosed || status == AccountStatusFrozen {
+		return fmt.Errorf("%w: payout account is %s", ErrAccountNotActive, status)
+	}
+	return nil
+}
+
+// addMonths adds whole months, clamping to the last day of a shorter month
+func addMonths(t time.Time, months int) time.Time {
+	local := t.In(Bangkok)
+	return monthlyAt(local, local.Year(), local.Month()+time.Month(months), local.Day())
+}
+
+// simpleInterest is interest on actual calendar days / 365
+func simpleInterest(principal, rate float64, from, to time.Time) (float64, int) {
+	fromDay := from.In(Bangkok)
+	toDay := to.In(Bangkok)
+	start := time.Date(fromDay.Year(), fromDay.Month(), fromDay.Day(), 0, 0, 0, 0, Bangkok)
+	end := time.Date(toDay.Year(), toDay.Month(), toDay.Day(), 0, 0, 0, 0, Bangkok)
+	days := int(end.Sub(start).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	return roundMoney(principal * rate / 100 * float64(days) / 365), days
+}
+
+// withholdingTax splits gross interest into tax withheld and the net amount paid
+func withholdingTax(gross, rate float64) (float64, float64) {
+	tax := roundMoney(gross * rate / 100)
+	return tax, roundMoney(gross - tax)
+}
+
+// RunFixedDepositMaturities settles every fixed deposit whose maturity date has passed
+func RunFixedDepositMaturities(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	cursor, err := db.Collection("deposit_accounts").Find(ctx, bson.M{
+		"accounttype":               AccountTypeFixed,
+		"status":                    AccountStatusActive,
+		"fixeddeposit.maturitydate": bson.M{"$lte": now},
+	}, options.Find().SetProjection(bson.M{"accountid": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query matured deposits: %w", err)
+	}
+
+	var due []bson.M
+	if err := cursor.All(ctx, &due); err != nil {
+		return 0, fmt.Errorf("failed to decode matured deposits: %w", err)
+	}
+
+	settled := 0
+	for _, acc := range due {
+		accountID, _ := acc["accountid"].(string)
+		if err := matureFixedDeposit(ctx, db, accountID, now); err != nil {
+			log.Printf("Fixed deposit %s maturity failed: %v", accountID, err)
+			continue
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+func runFixedDepositMaturityJob(ctx context.Context, db *mongo.Database) error {
+	n, err := RunFixedDepositMaturities(ctx, db, time.Now())
+	if n > 0 {
+		log.Printf("Fixed deposits: settled %d maturities", n)
+	}
+	return err
+}
+
+// matureFixedDeposit pays the interest for one term and renews or pays out the deposit
+func matureFixedDeposit(ctx context.Context, db *mongo.Database, accountID string, now time.Time) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var notice string
+	var memberID string
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var acc models.DepositAccount
+		err := db.Collection("deposit_accounts").FindOne(sc, bson.M{
+			"accountid":                 accountID,
+			"accounttype":               AccountTypeFixed,
+			"status":                    AccountStatusActive,
+			"fixeddeposit.maturitydate": bson.M{"$lte": now},
+		}).Decode(&acc)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil // settled by another run
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fixed deposit: %w", err)
+		}
+		if acc.FixedDeposit == nil {
+			return nil, fmt.Errorf("fixed deposit terms missing")
+		}
+		memberID = acc.MemberID
+		terms := *acc.FixedDeposit
+
+		gross, _ := simpleInterest(terms.Principal, terms.InterestRate, terms.StartDate, terms.MaturityDate)
+		tax, net := withholdingTax(gross, terms.WithholdingTaxRate)
+
+		instruction := terms.MaturityInstruction
+		if instruction != MaturityRenewAll {
+			// Fall back to renewing everything when the payout account can no longer receive money
+			if err := checkPayoutAccount(sc, db, terms.PayoutAccountID, acc.MemberID); err != nil {
+				log.Printf("Fixed deposit %s: payout account unusable (%v), renewing with interest", accountID, err)
+				instruction = MaturityRenewAll
+			}
+		}
+
+		txID := fmt.Sprintf("TXN-FDM-%d", now.UnixNano())
+		lines := []models.JournalLine{}
+		if gross > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLDepositInterest, SubAccount: accountID, Debit: gross})
+		}
+		if tax > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLWithholdingTax, SubAccount: acc.MemberID, Credit: tax})
+		}
+		paidTo := accountID
+
+		switch instruction {
+		case MaturityRenewAll:
+			renewed := renewTerms(sc, db, terms, roundMoney(terms.Principal+net))
+			if err := updateFixedDeposit(sc, db, accountID, terms.MaturityDate, bson.M{
+				"balance":        renewed.Principal,
+				"fixeddeposit":   renewed,
+				"lastactivityat": now,
+				"updatedat":      now,
+			}); err != nil {
+				return nil, err
+			}
+			if net > 0 {
+				if _, err := db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+					"transactionid": txID,
+					"accountid":     accountID,
+					"type":          "interest",
+					"amount":        net,
+					"balanceafter":  renewed.Principal,
+					"datetime":      now,
+					"description":   fmt.Sprintf("ดอกเบี้ยเงินฝากประจำ (ก่อนภาษี %.2f หักภาษี %.2f)", gross, tax),
+					"status":        "completed",
+				}); err != nil {
+					return nil, fmt.Errorf("failed to record transaction: %w", err)
+				}
+				lines = append(lines, models.JournalLine{AccountCode: GLFixedDeposits, SubAccount: accountID, Credit: net})
+			}
+			notice = fmt.Sprintf("เงินฝากประจำบัญชี %s ครบกำหนด ได้รับดอกเบี้ยสุทธิ %.2f บาท และต่ออายุด้วยเงินต้น %.2f บาท ครบกำหนดครั้งถัดไป %s",
+				acc.AccountNumber, net, renewed.Principal, renewed.MaturityDate.In(Bangkok).Format("2006-01-02"))
+
+		case MaturityRenewPrincipal:
+			renewed := renewTerms(sc, db, terms, terms.Principal)
+			if err := updateFixedDeposit(sc, db, accountID, terms.MaturityDate, bson.M{
+				"fixeddeposit":   renewed,
+				"lastactivityat": now,
+				"updatedat":      now,
+			}); err != nil {
+				return nil, err
+			}
+			if net > 0 {
+				payout, err := creditAccount(sc, db, terms.PayoutAccountID, net, ErrDestNotFound)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+					"transactionid": txID,
+					"accountid":     terms.PayoutAccountID,
+					"type":          "interest",
+					"amount":        net,
+					"balanceafter":  toFloat(payout["balance"]),
+					"datetime":      now,
+					"description":   fmt.Sprintf("ดอกเบี้ยเงินฝากประจำ %s (ก่อนภาษี %.2f หักภาษี %.2f)", acc.AccountNumber, gross, tax),
+					"referenceno":   accountID,
+					"status":        "completed",
+				}); err != nil {
+					return nil, fmt.Errorf("failed to record transaction: %w", err)
+				}
+				lines = append(lines, models.JournalLine{AccountCode: GLMemberDeposits, SubAccount: terms.PayoutAccountID, Credit: net})
+			}
+			paidTo = terms.PayoutAccountID
+			notice = fmt.Sprintf("เงินฝากประจำบัญชี %s ครบกำหนด โอนดอกเบี้ยสุทธิ %.2f บาทเข้าบัญชีออมทรัพย์ และต่ออายุเงินต้น %.2f บาท",
+				acc.AccountNumber, net, renewed.Principal)
+
+		default: // MaturityPayout
+			if err := payOutFixedDeposit(sc, db, &acc, terms.MaturityDate, net, gross, tax, "ครบกำหนด", now, txID); err != nil {
+				return nil, err
+			}
+			lines = append(lines,
+				models.JournalLine{AccountCode: GLFixedDeposits, SubAccount: accountID, Debit: acc.Balance},
+				models.JournalLine{AccountCode: GLMemberDeposits, SubAccount: terms.PayoutAccountID, Credit: roundMoney(acc.Balance + net)},
+			)
+			paidTo = terms.PayoutAccountID
+			notice = fmt.Sprintf("เงินฝากประจำบัญชี %s ครบกำหนด โอนเงินต้นและดอกเบี้ยสุทธิ %.2f บาทเข้าบัญชีออมทรัพย์ และปิดบัญชีแล้ว",
+				acc.AccountNumber, roundMoney(acc.Balance+net))
+		}
+
+		if len(lines) > 0 {
+			if _, err := PostJournal(sc, db, models.JournalEntry{
+				EntryDate:   now,
+				Description: "ดอกเบี้ยเงินฝากประจำครบกำหนด",
+				SourceType:  "fixed_deposit_maturity",
+				SourceRef:   txID,
+				Lines:       lines,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = db.Collection("fixed_deposit_interest").InsertOne(sc, models.FixedDepositInterest{
+			AccountID:    accountID,
+			MemberID:     acc.MemberID,
+			Kind:         "maturity",
+			PeriodStart:  terms.StartDate,
+			PeriodEnd:    terms.MaturityDate,
+			Principal:    terms.Principal,
+			InterestRate: terms.InterestRate,
+			GrossAmount:  gross,
+			TaxWithheld:  tax,
+			NetAmount:    net,
+			PaidTo:       paidTo,
+			PaidAt:       now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record interest: %w", err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	if notice != "" {
+		notifyMember(ctx, db, memberID, "เงินฝากประจำครบกำหนด", notice, "deposit")
+	}
+	return nil
+}
+
+// renewTerms starts a new term at the old maturity date with the product's current rate
+func renewTerms(ctx context.Context, db *mongo.Database, terms models.FixedDepositTerms, principal float64) models.FixedDepositTerms {
+	renewed := terms
+	if product, err := loadFixedDepositProduct(ctx, db, terms.ProductCode); err == nil {
+		renewed.InterestRate = product.InterestRate
+		renewed.WithholdingTaxRate = product.WithholdingTaxRate
+		renewed.EarlyWithdrawalRate = product.EarlyWithdrawalRate
+		renewed.MinHoldMonths = product.MinHoldMonths
+	}
+	renewed.Principal = principal
+	renewed.StartDate = terms.MaturityDate
+	renewed.MaturityDate = addMonths(terms.MaturityDate, terms.TermMonths)
+	renewed.Renewals++
+	return renewed
+}
+
+// updateFixedDeposit updates an active fixed deposit, conditional on the maturity date
+// read earlier so a term is never settled twice
+func updateFixedDeposit(ctx context.Context, db *mongo.Database, accountID string, maturity time.Time, set bson.M) error {
+	res, err := db.Collection("deposit_accounts").UpdateOne(ctx,
+		bson.M{"accountid": accountID, "status": AccountStatusActive, "fixeddeposit.maturitydate": maturity},
+		bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update fixed deposit: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("fixed deposit %s changed while settling, please retry", accountID)
+	}
+	return nil
+}
+
+// payOutFixedDeposit closes the fixed deposit and credits principal and net interest to
+// the payout account. The caller posts the journal entry.
+func payOutFixedDeposit(ctx context.Context, db *mongo.Database, acc *models.DepositAccount, maturity time.Time,
+	net, gross, tax float64, reason string, now time.Time, txID string) error {
+	terms := acc.FixedDeposit
+	principal := acc.Balance
+
+	err := updateFixedDeposit(ctx, db, acc.AccountID, maturity, bson.M{
+		"balance":        0.0,
+		"status":         AccountStatusClosed,
+		"statusreason":   reason,
+		"closedat":       now,
+		"lastactivityat": now,
+		"updatedat":      now,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := db.Collection("deposit_accounts").UpdateOne(ctx, bson.M{"accountid": acc.AccountID},
+		bson.M{"$push": bson.M{"statushistory": models.AccountStatusChange{
+			From: AccountStatusActive, To: AccountStatusClosed, Reason: reason, ChangedBy: "system", ChangedAt: now,
+		}}}); err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
+	}
+
+	total := roundMoney(principal + net)
+	payout, err := creditAccount(ctx, db, terms.PayoutAccountID, total, ErrDestNotFound)
+	if err != nil {
+		return err
+	}
+	payoutBalance := toFloat(payout["balance"])
+
+	records := []interface{}{
+		bson.M{
+			"transactionid": txID + "-OUT",
+			"accountid":     acc.AccountID,
+			"type":          "transfer_out",
+			"amount":        principal,
+			"balanceafter":  0.0,
+			"datetime":      now,
+			"description":   fmt.Sprintf("ปิดบัญชีเงินฝากประจำ (%s)", reason),
+			"referenceno":   terms.PayoutAccountID,
+			"status":        "completed",
+		},
+		bson.M{
+			"transactionid": txID + "-IN",
+			"accountid":     terms.PayoutAccountID,
+			"type":          "transfer_in",
+			"amount":        principal,
+			"balanceafter":  roundMoney(payoutBalance - net),
+			"datetime":      now,
+			"description":   fmt.Sprintf("เงินต้นเงินฝากประจำ %s", acc.AccountNumber),
+			"referenceno":   acc.AccountID,
+			"status":        "completed",
+		},
+	}
+	if net > 0 {
+		records = append(records, bson.M{
+			"transactionid": txID + "-INT",
+			"accountid":     terms.PayoutAccountID,
+			"type":          "interest",
+			"amount":        net,
+			"balanceafter":  payoutBalance,
+			"datetime":      now,
+			"description":   fmt.Sprintf("ดอกเบี้ยเงินฝากประจำ %s (ก่อนภาษี %.2f หักภาษี %.2f)", acc.AccountNumber, gross, tax),
+			"referenceno":   acc.AccountID,
+			"status":        "completed",
+		})
+	}
+	if _, err := db.Collection("deposit_transactions").InsertMany(ctx, records); err != nil {
+		return fmt.Errorf("failed to record transactions: %w", err)
+	}
+	return nil
+}
+
+// QuoteEarlyWithdrawal computes the interest, tax and penalty for closing a fixed deposit now.
+// Before MinHoldMonths no interest is paid; after it the reduced early withdrawal rate applies.
+func QuoteEarlyWithdrawal(ctx context.Context, db *mongo.Database, accountID, memberID string, now time.Time) (*EarlyWithdrawalQuote, error) {
+	acc, err := loadActiveFixedDeposit(ctx, db, accountID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	return quoteEarlyWithdrawal(acc, now)
+}
+
+func quoteEarlyWithdrawal(acc *models.DepositAccount, now time.Time) (*EarlyWithdrawalQuote, error) {
+	terms := acc.FixedDeposit
+	if !now.Before(terms.MaturityDate) {
+		return nil, ErrFixedDepositMatured
+	}
+
+	contract, days := simpleInterest(terms.Principal, terms.InterestRate, terms.StartDate, now)
+	rate := terms.EarlyWithdrawalRate
+	if now.Before(addMonths(terms.StartDate, terms.MinHoldMonths)) {
+		rate = 0
+	}
+	gross, _ := simpleInterest(terms.Principal, rate, terms.StartDate, now)
+	if gross > contract {
+		gross = contract
+	}
+	tax, net := withholdingTax(gross, terms.WithholdingTaxRate)
+
+	return &EarlyWithdrawalQuote{
+		AccountID:        acc.AccountID,
+		Principal:        acc.Balance,
+		StartDate:        terms.StartDate,
+		MaturityDate:     terms.MaturityDate,
+		HeldDays:         days,
+		ContractInterest: contract,
+		AppliedRate:      rate,
+		GrossInterest:    gross,
+		TaxWithheld:      tax,
+		NetInterest:      net,
+		Penalty:          roundMoney(contract - gross),
+		PayoutAmount:     roundMoney(acc.Balance + net),
+	}, nil
+}
+
+func loadActiveFixedDeposit(ctx context.Context, db *mongo.Database, accountID, memberID string) (*models.DepositAccount, error) {
+	var acc models.DepositAccount
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{
+		"accountid":   accountID,
+		"accounttype": AccountTypeFixed,
+		"status":      AccountStatusActive,
+	}).Decode(&acc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFixedDeposit
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixed deposit: %w", err)
+	}
+	if acc.MemberID != memberID {
+		return nil, ErrNotAccountOwner
+	}
+	if acc.FixedDeposit == nil {
+		return nil, fmt.Errorf("fixed deposit terms missing")
+	}
+	return &acc, nil
+}
+
+// WithdrawFixedDepositEarly closes a fixed deposit before maturity, paying principal and
+// the reduced interest to a savings account of the member.
+func WithdrawFixedDepositEarly(ctx context.Context, db *mongo.Database, accountID, memberID, payoutAccountID string) (*EarlyWithdrawalQuote, error) {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var quote *EarlyWithdrawalQuote
+	var accountNumber string
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		acc, err := loadActiveFixedDeposit(sc, db, accountID, memberID)
+		if err != nil {
+			return nil, err
+		}
+		accountNumber = acc.AccountNumber
+		if payoutAccountID != "" {
+			acc.FixedDeposit.PayoutAccountID = payoutAccountID
+		}
+		if err := checkPayoutAccount(sc, db, acc.FixedDeposit.PayoutAccountID, memberID); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		quote, err = quoteEarlyWithdrawal(acc, now)
+		if err != nil {
+			return nil, err
+		}
+
+		txID := fmt.Sprintf("TXN-FDW-%d", now.UnixNano())
+		if err := payOutFixedDeposit(sc, db, acc, acc.FixedDeposit.MaturityDate, quote.NetInterest,
+			quote.GrossInterest, quote.TaxWithheld, "ถอนก่อนกำหนด", now, txID); err != nil {
+			return nil, err
+		}
+
+		lines := []models.JournalLine{
+			{AccountCode: GLFixedDeposits, SubAccount: accountID, Debit: acc.Balance},
+		}
+		if quote.GrossInterest > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLDepositInterest, SubAccount: accountID, Debit: quote.GrossInterest})
+		}
+		if quote.TaxWithheld > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLWithholdingTax, SubAccount: memberID, Credit: quote.TaxWithheld})
+		}
+		lines = append(lines, models.JournalLine{AccountCode: GLMemberDeposits, SubAccount: acc.FixedDeposit.PayoutAccountID, Credit: quote.PayoutAmount})
+		if _, err := PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "ถอนเงินฝากประจำก่อนกำหนด",
+			SourceType:  "fixed_deposit_early_withdrawal",
+			SourceRef:   txID,
+			Lines:       lines,
+		}); err != nil {
+			return nil, err
+		}
+
+		_, err = db.Collection("fixed_deposit_interest").InsertOne(sc, models.FixedDepositInterest{
+			AccountID:    accountID,
+			MemberID:     memberID,
+			Kind:         "early_withdrawal",
+			PeriodStart:  acc.FixedDeposit.StartDate,
+			PeriodEnd:    now,
+			Principal:    acc.FixedDeposit.Principal,
+			InterestRate: quote.AppliedRate,
+			GrossAmount:  quote.GrossInterest,
+			TaxWithheld:  quote.TaxWithheld,
+			NetAmount:    quote.NetInterest,
+			Penalty:      quote.Penalty,
+			PaidTo:       acc.FixedDeposit.PayoutAccountID,
+			PaidAt:       now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record interest: %w", err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifyMember(ctx, db, memberID, "ถอนเงินฝากประจำก่อนกำหนด",
+		fmt.Sprintf("ปิดบัญชีเงินฝากประจำ %s ก่อนกำหนด ได้รับเงิน %.2f บาท (ดอกเบี้ยสุทธิ %.2f บาท)",
+			accountNumber, quote.PayoutAmount, quote.NetInterest), "deposit")
+	return quote, nil
+}
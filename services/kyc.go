@@ -0,0 +1,640 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// KYC statuses, on both the member document (kyc_status) and kyc_submissions
+const (
+	KYCStatusPending  = "pending"
+	KYCStatusVerified = "verified"
+	KYCStatusRejected = "rejected"
+)
+
+var (
+	ErrKYCSubmissionOpen    = errors.New("a KYC submission is already waiting for review")
+	ErrNoOpenKYCSubmission  = errors.New("member has no KYC submission waiting for review")
+	ErrInvalidKYCDecision   = errors.New("status must be 'verified' or 'rejected'")
+	ErrKYCRejectReasonEmpty = errors.New("reason is required when rejecting a KYC submission")
+)
+
+// CheckKYCSubmissionAllowed makes sure the member exists and has nothing waiting for review,
+// so images are not uploaded for a submission that would be refused
+func CheckKYCSubmissionAllowed(ctx context.Context, db *mongo.Database, memberID string) error {
+	var member bson.M
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID}).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load member: %w", err)
+	}
+	if status, _ := member["kyc_status"].(string); status == KYCStatusPending {
+		return ErrKYCSubmissionOpen
+	}
+	// A verified member re-verifying keeps kyc_status; the open submission is kyc_submission_id
+	if id, _ := member["kyc_submission_id"].(string); id != "" {
+		n, err := db.Collection("kyc_submissions").CountDocuments(ctx,
+			bson.M{"submissionid": id, "status": KYCStatusPending}, options.Count().SetLimit(1))
+		if err != nil {
+			return fmt.Errorf("failed to load KYC submission: %w", err)
+		}
+		if n > 0 {
+			return ErrKYCSubmissionOpen
+		}
+	}
+	return nil
+}
+
+// KYCValidationError lists the KYC form fields that failed validation, keyed by field name
+type KYCValidationError struct {
+	Fields map[string]string
+}
+
+func (e *KYCValidationError) Error() string {
+	return fmt.Sprintf("KYC validation failed on %d field(s)", len(e.Fields))
+}
+
+// KYCDetails are the identity and bank fields of a KYC submission
+type KYCDetails struct {
+	CitizenID     string
+	BankID        string
+	BankAccountNo string
+}
+
+// ValidateKYCDetails checks the citizen ID check digit, the bank against the bank list and
+// the account number length for that bank, and that neither the citizen ID nor the bank
+// account belongs to another member. An empty citizen ID falls back to the one on the member
+// document. Returns the details normalised (no separators, bank code) or a *KYCValidationError.
+func ValidateKYCDetails(ctx context.Context, db *mongo.Database, memberID string, in KYCDetails) (*KYCDetails, error) {
+	fields := map[string]string{}
+	out := KYCDetails{
+		CitizenID:     stripSeparators(in.CitizenID),
+		BankAccountNo: stripSeparators(in.BankAccountNo),
+	}
+
+	if out.CitizenID == "" {
+		var member bson.M
+		err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID}).Decode(&member)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to load member: %w", err)
+		}
+		stored, _ := member["citizen_id"].(string)
+		out.CitizenID = stripSeparators(stored)
+	}
+	switch {
+	case out.CitizenID == "":
+		fields["citizen_id"] = "citizen_id is required"
+	case !ValidThaiCitizenID(out.CitizenID):
+		fields["citizen_id"] = "invalid Thai citizen ID"
+	default:
+		taken, err := usedByOtherMember(ctx, db, memberID, bson.M{"citizen_id": out.CitizenID})
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			fields["citizen_id"] = "citizen ID is registered to another member"
+		}
+	}
+
+	banks, err := ListBanks(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	bank := findBank(banks, in.BankID)
+	switch {
+	case strings.TrimSpace(in.BankID) == "":
+		fields["bank_id"] = "bank_id is required"
+	case bank == nil:
+		fields["bank_id"] = "unknown bank"
+	case !bank.Active:
+		fields["bank_id"] = "bank is not accepted"
+	default:
+		out.BankID = bank.Code
+	}
+
+	if out.BankAccountNo == "" {
+		fields["bank_account_no"] = "bank_account_no is required"
+	} else if !isDigits(out.BankAccountNo) {
+		fields["bank_account_no"] = "bank_account_no must contain digits only"
+	} else if out.BankID != "" {
+		lengthOK := false
+		for _, n := range bank.AccountLengths {
+			if len(out.BankAccountNo) == n {
+				lengthOK = true
+			}
+		}
+		if !lengthOK {
+			fields["bank_account_no"] = fmt.Sprintf("%s account numbers have %s digits", bank.ShortName, joinInts(bank.AccountLengths, " or "))
+		} else {
+			taken, err := usedByOtherMember(ctx, db, memberID, bson.M{"bank_id": out.BankID, "bank_account_no": out.BankAccountNo})
+			if err != nil {
+				return nil, err
+			}
+			if taken {
+				fields["bank_account_no"] = "bank account is registered to another member"
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, &KYCValidationError{Fields: fields}
+	}
+	return &out, nil
+}
+
+// ValidThaiCitizenID checks a 13-digit Thai citizen ID: the last digit is
+// (11 - sum(digit[i] × (13 - i)) mod 11) mod 10 over the first 12 digits
+func ValidThaiCitizenID(id string) bool {
+	if len(id) != 13 || !isDigits(id) {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += int(id[i]-'0') * (13 - i)
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+func usedByOtherMember(ctx context.Context, db *mongo.Database, memberID string, filter bson.M) (bool, error) {
+	filter["memberid"] = bson.M{"$ne": memberID}
+	err := db.Collection("members").FindOne(ctx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check members: %w", err)
+	}
+	return true, nil
+}
+
+// stripSeparators drops the dashes and spaces people type into ID and account numbers
+func stripSeparators(s string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+}
+
+func joinInts(ns []int, sep string) string {
+	parts := make([]string, len(ns))
+	for i, n := range ns {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, sep)
+}
+
+// SubmitKYCSubmission records a new attempt and copies it onto the member document. The
+// unique partial index on pending submissions keeps one open submission per member. A member
+// whose verification is still valid keeps it (status, expiry and verified details) while the
+// re-verification waits for review; only kyc_submission_id points at the new submission.
+func SubmitKYCSubmission(ctx context.Context, db *mongo.Database, sub *models.KYCSubmission) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		attempts, err := db.Collection("kyc_submissions").CountDocuments(sc, bson.M{"memberid": sub.MemberID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count KYC submissions: %w", err)
+		}
+		sub.SubmissionID = "KYC-" + uuid.New().String()
+		sub.Attempt = int(attempts) + 1
+		sub.Status = KYCStatusPending
+		sub.SubmittedAt = time.Now()
+
+		res, err := db.Collection("kyc_submissions").InsertOne(sc, sub)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, ErrKYCSubmissionOpen
+			}
+			return nil, fmt.Errorf("failed to save KYC submission: %w", err)
+		}
+		sub.ID, _ = res.InsertedID.(primitive.ObjectID)
+
+		var member bson.M
+		err = db.Collection("members").FindOne(sc, bson.M{"memberid": sub.MemberID}).Decode(&member)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMemberNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load member: %w", err)
+		}
+
+		filter := bson.M{"memberid": sub.MemberID, "kyc_status": bson.M{"$ne": KYCStatusPending}}
+		update := bson.M{"$set": bson.M{"kyc_submission_id": sub.SubmissionID}}
+		if MemberKYCVerified(member, sub.SubmittedAt) {
+			filter["kyc_status"] = KYCStatusVerified
+		} else {
+			update["$set"] = bson.M{
+				"citizen_id":              sub.CitizenID,
+				"bank_id":                 sub.BankID,
+				"bank_account_no":         sub.BankAccountNo,
+				"kyc_status":              KYCStatusPending,
+				"kyc_submitted_at":        sub.SubmittedAt,
+				"kyc_submission_id":       sub.SubmissionID,
+				"kyc_id_card_image_key":   sub.IDCardImageKey,
+				"kyc_bank_book_image_key": sub.BankBookImageKey,
+				"kyc_selfie_image_key":    sub.SelfieImageKey,
+				"kyc_face_match":          sub.FaceMatch,
+			}
+			update["$unset"] = bson.M{"kyc_reject_reason": "", "kyc_reviewed_at": ""}
+		}
+		updated, err := db.Collection("members").UpdateOne(sc, filter, update)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update member: %w", err)
+		}
+		if updated.MatchedCount == 0 {
+			return nil, ErrKYCSubmissionOpen
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// defaultKYCFourEyesRiskLevel is the risk level from which verifying a submission needs a
+// second officer. Override with KYC_FOUR_EYES_RISK_LEVEL (low, medium or high).
+const defaultKYCFourEyesRiskLevel = "medium"
+
+var kycRiskRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// KYCReview is an officer's decision on a member's open KYC submission
+type KYCReview struct {
+	MemberID       string
+	OfficerID      string
+	Status         string // verified, rejected
+	Reason         string
+	PromoteOfficer bool
+	IDCardExpiry   string // YYYY-MM-DD from the ID card, optional; caps the verification expiry
+}
+
+// KYCReviewOutcome is the result of ReviewKYC. Exactly one of Submission and
+// PendingApprovalID is set when err is nil.
+type KYCReviewOutcome struct {
+	Submission        *models.KYCSubmission
+	PendingApprovalID string
+}
+
+func kycFourEyesRiskLevel() string {
+	if level := os.Getenv("KYC_FOUR_EYES_RISK_LEVEL"); kycRiskRank[level] > 0 {
+		return level
+	}
+	return defaultKYCFourEyesRiskLevel
+}
+
+// ReviewKYC applies an officer's decision on the member's open submission. Rejections take
+// effect at once. Verifications that promote the member to officer, or whose risk level is at
+// or above KYC_FOUR_EYES_RISK_LEVEL, are parked in approval_requests for a second officer.
+func ReviewKYC(ctx context.Context, db *mongo.Database, review KYCReview) (*KYCReviewOutcome, error) {
+	if review.Status != KYCStatusVerified && review.Status != KYCStatusRejected {
+		return nil, ErrInvalidKYCDecision
+	}
+	if review.Status == KYCStatusRejected && review.Reason == "" {
+		return nil, ErrKYCRejectReasonEmpty
+	}
+	if review.OfficerID == review.MemberID {
+		return nil, ErrSelfApproval
+	}
+	if review.Status == KYCStatusVerified {
+		if _, err := parseIDCardExpiry(review.IDCardExpiry, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	sub, err := openKYCSubmission(ctx, db, review.MemberID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.ApprovalID != "" {
+		return nil, fmt.Errorf("%w (%s)", ErrApprovalPending, sub.ApprovalID)
+	}
+	// Reviewing claims the submission, so it fails while another officer holds it
+	if _, err := ClaimKYCSubmission(ctx, db, sub.SubmissionID, review.OfficerID); err != nil {
+		return nil, err
+	}
+
+	if review.Status == KYCStatusVerified {
+		level, reasons, err := assessKYCRisk(ctx, db, sub)
+		if err != nil {
+			return nil, err
+		}
+		if review.PromoteOfficer {
+			reasons = append(reasons, "promotion to officer")
+		}
+		_, err = db.Collection("kyc_submissions").UpdateOne(ctx, bson.M{"_id": sub.ID},
+			bson.M{"$set": bson.M{"risklevel": level, "riskreasons": reasons}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update KYC submission: %w", err)
+		}
+
+		if review.PromoteOfficer || kycRiskRank[level] >= kycRiskRank[kycFourEyesRiskLevel()] {
+			approvalID, err := requestKYCApproval(ctx, db, sub, review, level, reasons)
+			if err != nil {
+				return nil, err
+			}
+			return &KYCReviewOutcome{PendingApprovalID: approvalID}, nil
+		}
+	}
+
+	sub, err = applyKYCReview(ctx, db, sub.SubmissionID, review, "")
+	if err != nil {
+		return nil, err
+	}
+	return &KYCReviewOutcome{Submission: sub}, nil
+}
+
+// kycApprovalPayload is what a kyc_verification approval request carries
+type kycApprovalPayload struct {
+	SubmissionID   string `bson:"submissionid"`
+	Reason         string `bson:"reason,omitempty"`
+	PromoteOfficer bool   `bson:"promoteofficer"`
+	RiskLevel      string `bson:"risklevel"`
+	IDCardExpiry   string `bson:"idcardexpiry,omitempty"`
+}
+
+func requestKYCApproval(ctx context.Context, db *mongo.Database, sub *models.KYCSubmission, review KYCReview, level string, reasons []string) (string, error) {
+	payload, err := encodePayload(kycApprovalPayload{
+		SubmissionID:   sub.SubmissionID,
+		Reason:         review.Reason,
+		PromoteOfficer: review.PromoteOfficer,
+		RiskLevel:      level,
+		IDCardExpiry:   review.IDCardExpiry,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := fmt.Sprintf("ยืนยัน KYC สมาชิก %s (ครั้งที่ %d, ความเสี่ยง %s)", sub.MemberID, sub.Attempt, level)
+	if review.PromoteOfficer {
+		summary += " และแต่งตั้งเป็นเจ้าหน้าที่"
+	}
+	if len(reasons) > 0 {
+		summary += ": " + strings.Join(reasons, ", ")
+	}
+
+	req := &models.ApprovalRequest{
+		Type:        ApprovalKYCVerification,
+		TargetID:    sub.MemberID,
+		Summary:     summary,
+		Payload:     payload,
+		RequestedBy: review.OfficerID,
+	}
+	if err := createApprovalRequest(ctx, db, req); err != nil {
+		return "", err
+	}
+	_, err = db.Collection("kyc_submissions").UpdateOne(ctx,
+		bson.M{"_id": sub.ID, "status": KYCStatusPending},
+		bson.M{
+			"$set":   bson.M{"approvalid": req.RequestID},
+			"$unset": bson.M{"claimedby": "", "claimedat": "", "claimexpiresat": ""},
+		})
+	if err != nil {
+		return "", fmt.Errorf("failed to update KYC submission: %w", err)
+	}
+	return req.RequestID, nil
+}
+
+// approveKYCVerification applies a verification once a second officer approves it. The
+// requesting officer is recorded as the reviewer and the approver alongside.
+func approveKYCVerification(ctx context.Context, db *mongo.Database, req *models.ApprovalRequest, approvedBy string) error {
+	var payload kycApprovalPayload
+	if err := decodePayload(req.Payload, &payload); err != nil {
+		return err
+	}
+	_, err := applyKYCReview(ctx, db, payload.SubmissionID, KYCReview{
+		MemberID:       req.TargetID,
+		OfficerID:      req.RequestedBy,
+		Status:         KYCStatusVerified,
+		Reason:         payload.Reason,
+		PromoteOfficer: payload.PromoteOfficer,
+		IDCardExpiry:   payload.IDCardExpiry,
+	}, approvedBy)
+	return err
+}
+
+// assessKYCRisk grades a submission from the member's KYC history
+func assessKYCRisk(ctx context.Context, db *mongo.Database, sub *models.KYCSubmission) (string, []string, error) {
+	level := "low"
+	reasons := []string{}
+	raise := func(to, reason string) {
+		if kycRiskRank[to] > kycRiskRank[level] {
+			level = to
+		}
+		reasons = append(reasons, reason)
+	}
+
+	switch {
+	case sub.Attempt >= 3:
+		raise("high", fmt.Sprintf("submitted %d times", sub.Attempt))
+	case sub.Attempt == 2:
+		raise("medium", "resubmitted after an earlier attempt")
+	}
+
+	if fm := sub.FaceMatch; fm != nil && fm.Error == "" && !fm.Passed {
+		raise("medium", fmt.Sprintf("selfie does not match the ID card photo (score %.2f)", fm.Score))
+	}
+
+	// Bank details that differ from the last verified submission
+	var last models.KYCSubmission
+	err := db.Collection("kyc_submissions").FindOne(ctx,
+		bson.M{"memberid": sub.MemberID, "status": KYCStatusVerified},
+		options.FindOne().SetSort(bson.D{{Key: "reviewedat", Value: -1}})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", nil, fmt.Errorf("failed to load KYC history: %w", err)
+	}
+	if err == nil && (last.BankID != sub.BankID || last.BankAccountNo != sub.BankAccountNo) {
+		raise("medium", "bank account changed since the last verification")
+	}
+	if err == nil && last.CitizenID != "" && last.CitizenID != sub.CitizenID {
+		raise("high", "citizen ID changed since the last verification")
+	}
+
+	return level, reasons, nil
+}
+
+// applyKYCReview records the decision on the submission and copies it onto the member.
+// approvedBy is the second officer for decisions that went through approval_requests.
+func applyKYCReview(ctx context.Context, db *mongo.Database, submissionID string, review KYCReview, approvedBy string) (*models.KYCSubmission, error) {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	promote := review.Status == KYCStatusVerified && review.PromoteOfficer
+	var sub models.KYCSubmission
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+		set := bson.M{
+			"status":          review.Status,
+			"reviewedby":      review.OfficerID,
+			"reason":          review.Reason,
+			"reviewedat":      now,
+			"promotedofficer": promote,
+		}
+		if approvedBy != "" {
+			set["approvedby"] = approvedBy
+		}
+		// The card may have expired while the request waited for a second officer
+		idCardExpiry, err := parseIDCardExpiry(review.IDCardExpiry, now)
+		if review.Status == KYCStatusVerified && err != nil {
+			return nil, err
+		}
+		if idCardExpiry != nil {
+			set["idcardexpiry"] = *idCardExpiry
+		}
+		err = db.Collection("kyc_submissions").FindOneAndUpdate(sc,
+			bson.M{"submissionid": submissionID, "memberid": review.MemberID, "status": KYCStatusPending},
+			bson.M{"$set": set, "$unset": bson.M{"claimedby": "", "claimedat": "", "claimexpiresat": ""}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&sub)
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: submission was reviewed by someone else", ErrNoOpenKYCSubmission)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update KYC submission: %w", err)
+		}
+
+		var current bson.M
+		if err := db.Collection("members").FindOne(sc, bson.M{"memberid": review.MemberID}).Decode(&current); err != nil {
+			return nil, fmt.Errorf("failed to load member: %w", err)
+		}
+		if review.Status == KYCStatusRejected && MemberKYCVerified(current, now) {
+			// A rejected re-verification leaves the current verification in place
+			if _, err := db.Collection("members").UpdateOne(sc, bson.M{"memberid": review.MemberID},
+				bson.M{"$set": bson.M{"updatedat": now}}); err != nil {
+				return nil, fmt.Errorf("failed to update member: %w", err)
+			}
+			return nil, nil
+		}
+
+		member := bson.M{
+			"kyc_status":        review.Status,
+			"kyc_reviewed_at":   now,
+			"kyc_reviewed_by":   review.OfficerID,
+			"kyc_reject_reason": review.Reason,
+			"kyc_approved_by":   approvedBy,
+			"updatedat":         now,
+		}
+		unset := bson.M{"kyc_expiry_warning_sent_at": "", "kyc_expired_at": ""}
+		if review.Status == KYCStatusVerified {
+			// A re-verification's details reach the member only now
+			member["citizen_id"] = sub.CitizenID
+			member["bank_id"] = sub.BankID
+			member["bank_account_no"] = sub.BankAccountNo
+			member["kyc_submitted_at"] = sub.SubmittedAt
+			member["kyc_id_card_image_key"] = sub.IDCardImageKey
+			member["kyc_bank_book_image_key"] = sub.BankBookImageKey
+			member["kyc_selfie_image_key"] = sub.SelfieImageKey
+			member["kyc_face_match"] = sub.FaceMatch
+
+			// Verification lapses after the risk-based interval or with the ID card
+			expires := kycExpiry(now, sub.RiskLevel, idCardExpiry)
+			if _, err := db.Collection("kyc_submissions").UpdateOne(sc, bson.M{"_id": sub.ID},
+				bson.M{"$set": bson.M{"expiresat": expires}}); err != nil {
+				return nil, fmt.Errorf("failed to update KYC submission: %w", err)
+			}
+			sub.ExpiresAt = &expires
+			member["kyc_expires_at"] = expires
+			member["kyc_risk_level"] = sub.RiskLevel
+		} else {
+			unset["kyc_expires_at"] = ""
+		}
+		if promote {
+			member["role"] = "officer"
+		}
+		if _, err := db.Collection("members").UpdateOne(sc, bson.M{"memberid": review.MemberID},
+			bson.M{"$set": member, "$unset": unset}); err != nil {
+			return nil, fmt.Errorf("failed to update member: %w", err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// openKYCSubmission returns the member's pending submission. Members who submitted before
+// kyc_submissions existed get one built from the kyc_* fields on their member document.
+func openKYCSubmission(ctx context.Context, db *mongo.Database, memberID string) (*models.KYCSubmission, error) {
+	var sub models.KYCSubmission
+	err := db.Collection("kyc_submissions").FindOne(ctx,
+		bson.M{"memberid": memberID, "status": KYCStatusPending}).Decode(&sub)
+	if err == nil {
+		return &sub, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load KYC submission: %w", err)
+	}
+
+	var member bson.M
+	err = db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID}).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load member: %w", err)
+	}
+	if status, _ := member["kyc_status"].(string); status != KYCStatusPending {
+		return nil, ErrNoOpenKYCSubmission
+	}
+
+	attempts, err := db.Collection("kyc_submissions").CountDocuments(ctx, bson.M{"memberid": memberID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count KYC submissions: %w", err)
+	}
+	str := func(key string) string {
+		s, _ := member[key].(string)
+		return s
+	}
+	sub = models.KYCSubmission{
+		SubmissionID:     "KYC-" + uuid.New().String(),
+		MemberID:         memberID,
+		Attempt:          int(attempts) + 1,
+		CitizenID:        str("citizen_id"),
+		BankID:           str("bank_id"),
+		BankAccountNo:    str("bank_account_no"),
+		IDCardImageKey:   str("kyc_id_card_image_key"),
+		BankBookImageKey: str("kyc_bank_book_image_key"),
+		SelfieImageKey:   str("kyc_selfie_image_key"),
+		Status:           KYCStatusPending,
+		SubmittedAt:      toTime(member["kyc_submitted_at"]),
+	}
+	res, err := db.Collection("kyc_submissions").InsertOne(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save KYC submission: %w", err)
+	}
+	sub.ID, _ = res.InsertedID.(primitive.ObjectID)
+	_, err = db.Collection("members").UpdateOne(ctx, bson.M{"memberid": memberID},
+		bson.M{"$set": bson.M{"kyc_submission_id": sub.SubmissionID}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+	return &sub, nil
+}
+
+// ListKYCSubmissions returns every KYC attempt of a member, newest first
+func ListKYCSubmissions(ctx context.Context, db *mongo.Database, memberID string) ([]models.KYCSubmission, error) {
+	cursor, err := db.Collection("kyc_submissions").Find(ctx, bson.M{"memberid": memberID},
+		options.Find().SetSort(bson.D{{Key: "submittedat", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query KYC submissions: %w", err)
+	}
+	submissions := []models.KYCSubmission{}
+	if err := cursor.All(ctx, &submissions); err != nil {
+		return nil, fmt.Errorf("failed to decode KYC submissions: %w", err)
+	}
+	return submissions, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// KYCStatusExpired is set on the member when a verification passes its expiry date. The
+// member must submit KYC again; until then they are treated as unverified.
+const KYCStatusExpired = "expired"
+
+// kycReviewIntervals is how long a verification lasts by risk level. Higher-risk members are
+// re-verified more often.
+var kycReviewIntervals = map[string]time.Duration{
+	"low":    5 * 365 * 24 * time.Hour,
+	"medium": 3 * 365 * 24 * time.Hour,
+	"high":   365 * 24 * time.Hour,
+}
+
+// kycExpiryWarning is how far ahead of expiry members are told to re-verify
+const kycExpiryWarning = 30 * 24 * time.Hour
+
+var ErrInvalidIDCardExpiry = errors.New("id_card_expiry must be a future date in YYYY-MM-DD format")
+
+// parseIDCardExpiry reads the card expiry entered by the reviewing officer; empty means unknown
+func parseIDCardExpiry(s string, now time.Time) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, Bangkok)
+	if err != nil || !t.After(now) {
+		return nil, ErrInvalidIDCardExpiry
+	}
+	return &t, nil
+}
+
+// kycExpiry is when a verification made at verifiedAt lapses: the risk-based interval, or the
+// ID card expiry if that comes first
+func kycExpiry(verifiedAt time.Time, riskLevel string, idCardExpiry *time.Time) time.Time {
+	interval, ok := kycReviewIntervals[riskLevel]
+	if !ok {
+		interval = kycReviewIntervals["low"]
+	}
+	expires := verifiedAt.Add(interval)
+	if idCardExpiry != nil && idCardExpiry.Before(expires) {
+		expires = *idCardExpiry
+	}
+	return expires
+}
+
+// MemberKYCVerified reports whether a member document holds a current verification. A
+// verification past kyc_expires_at counts as unverified even before the expiry job runs.
+func MemberKYCVerified(member bson.M, now time.Time) bool {
+	if status, _ := member["kyc_status"].(string); status != KYCStatusVerified {
+		return false
+	}
+	if _, ok := member["kyc_expires_at"]; !ok {
+		return true
+	}
+	return now.Before(toTime(member["kyc_expires_at"]))
+}
+
+func runKYCExpiryJob(ctx context.Context, db *mongo.Database) error {
+	now := time.Now()
+	backfilled, err := backfillKYCExpiry(ctx, db, now)
+	if err != nil {
+		return err
+	}
+	warned, err := warnKYCExpiry(ctx, db, now)
+	if err != nil {
+		return err
+	}
+	expired, err := ExpireKYC(ctx, db, now)
+	if backfilled+warned+expired > 0 {
+		log.Printf("KYC expiry: %d scheduled, %d warned, %d expired", backfilled, warned, expired)
+	}
+	return err
+}
+
+// backfillKYCExpiry gives members verified before expiry dates existed one, counted from
+// their review by their risk level, but never sooner than the warning period from now
+func backfillKYCExpiry(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	cursor, err := db.Collection("members").Find(ctx, bson.M{
+		"kyc_status":     KYCStatusVerified,
+		"kyc_expires_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query members: %w", err)
+	}
+	var members []bson.M
+	if err := cursor.All(ctx, &members); err != nil {
+		return 0, fmt.Errorf("failed to decode members: %w", err)
+	}
+
+	n := 0
+	for _, m := range members {
+		reviewedAt := toTime(m["kyc_reviewed_at"])
+		if reviewedAt.IsZero() {
+			reviewedAt = now
+		}
+		level, _ := m["kyc_risk_level"].(string)
+		expires := kycExpiry(reviewedAt, level, nil)
+		if earliest := now.Add(kycExpiryWarning); expires.Before(earliest) {
+			expires = earliest
+		}
+		res, err := db.Collection("members").UpdateOne(ctx,
+			bson.M{"_id": m["_id"], "kyc_status": KYCStatusVerified, "kyc_expires_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"kyc_expires_at": expires}})
+		if err != nil {
+			return n, fmt.Errorf("failed to schedule KYC expiry: %w", err)
+		}
+		n += int(res.ModifiedCount)
+	}
+	return n, nil
+}
+
+// warnKYCExpiry notifies verified members whose verification lapses within the warning
+// period, once per verification
+func warnKYCExpiry(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	cursor, err := db.Collection("members").Find(ctx, bson.M{
+		"kyc_status":                 KYCStatusVerified,
+		"kyc_expires_at":             bson.M{"$gt": now, "$lte": now.Add(kycExpiryWarning)},
+		"kyc_expiry_warning_sent_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query members: %w", err)
+	}
+	var members []bson.M
+	if err := cursor.All(ctx, &members); err != nil {
+		return 0, fmt.Errorf("failed to decode members: %w", err)
+	}
+
+	n := 0
+	for _, m := range members {
+		res, err := db.Collection("members").UpdateOne(ctx,
+			bson.M{"_id": m["_id"], "kyc_status": KYCStatusVerified, "kyc_expiry_warning_sent_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"kyc_expiry_warning_sent_at": now}})
+		if err != nil {
+			return n, fmt.Errorf("failed to update member: %w", err)
+		}
+		if res.ModifiedCount == 0 {
+			continue
+		}
+		memberID, _ := m["memberid"].(string)
+		notifyMember(ctx, db, memberID, "การยืนยันตัวตนใกล้หมดอายุ",
+			fmt.Sprintf("การยืนยันตัวตน (KYC) ของคุณจะหมดอายุวันที่ %s กรุณายืนยันตัวตนใหม่ก่อนวันดังกล่าวเพื่อให้ทำธุรกรรมได้ต่อเนื่อง",
+				toTime(m["kyc_expires_at"]).In(Bangkok).Format("2006-01-02")), "kyc")
+		n++
+	}
+	return n, nil
+}
+
+// ExpireKYC moves verified members past their expiry date to expired and notifies them
+func ExpireKYC(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	cursor, err := db.Collection("members").Find(ctx, bson.M{
+		"kyc_status":     KYCStatusVerified,
+		"kyc_expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query members: %w", err)
+	}
+	var members []bson.M
+	if err := cursor.All(ctx, &members); err != nil {
+		return 0, fmt.Errorf("failed to decode members: %w", err)
+	}
+
+	n := 0
+	for _, m := range members {
+		res, err := db.Collection("members").UpdateOne(ctx,
+			bson.M{"_id": m["_id"], "kyc_status": KYCStatusVerified, "kyc_expires_at": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"kyc_status": KYCStatusExpired, "kyc_expired_at": now, "updatedat": now}})
+		if err != nil {
+			return n, fmt.Errorf("failed to expire KYC: %w", err)
+		}
+		if res.ModifiedCount == 0 {
+			continue
+		}
+		memberID, _ := m["memberid"].(string)
+		notifyMember(ctx, db, memberID, "การยืนยันตัวตนหมดอายุ",
+			"การยืนยันตัวตน (KYC) ของคุณหมดอายุแล้ว ระบบจะจำกัดการถอนและโอนเงินจนกว่าคุณจะยืนยันตัวตนใหม่", "kyc")
+		n++
+	}
+	return n, nil
+}
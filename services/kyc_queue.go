@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Defaults for the review queue. Override with KYC_SLA_HOURS and KYC_CLAIM_TIMEOUT_MINUTES.
+const (
+	defaultKYCSLAHours         = 24
+	defaultKYCClaimTimeoutMins = 30
+)
+
+var (
+	ErrKYCClaimedByOther = errors.New("another officer is reviewing this submission")
+	ErrKYCClaimNotHeld   = errors.New("submission is not claimed by this officer")
+	ErrInvalidKYCQuery   = errors.New("invalid KYC queue filter")
+)
+
+func kycSLA() time.Duration {
+	if h, err := strconv.Atoi(os.Getenv("KYC_SLA_HOURS")); err == nil && h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return defaultKYCSLAHours * time.Hour
+}
+
+func kycClaimTimeout() time.Duration {
+	if m, err := strconv.Atoi(os.Getenv("KYC_CLAIM_TIMEOUT_MINUTES")); err == nil && m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return defaultKYCClaimTimeoutMins * time.Minute
+}
+
+// KYCQueueFilter selects and pages pending submissions for the officer queue
+type KYCQueueFilter struct {
+	OfficerID     string
+	SubmittedFrom string // YYYY-MM-DD, Bangkok calendar day
+	SubmittedTo   string // YYYY-MM-DD, inclusive
+	Branch        string // members.branch
+	Claim         string // "" all, "mine", "unclaimed"
+	SLABreached   bool
+	Search        string // member ID or part of the Thai name
+	Newest        bool   // newest first; the default is oldest first
+	Page          int64
+	Limit         int64
+}
+
+// KYCQueueItem is a pending submission with its queue state
+type KYCQueueItem struct {
+	SubmissionID   string            `json:"submission_id"`
+	MemberID       string            `json:"memberid"`
+	Name           string            `json:"name_th"`
+	Branch         string            `json:"branch,omitempty"`
+	Attempt        int               `json:"attempt"`
+	SubmittedAt    time.Time         `json:"submitted_at"`
+	MinutesInQueue int64             `json:"minutes_in_queue"`
+	SLADueAt       time.Time         `json:"sla_due_at"`
+	SLABreached    bool              `json:"sla_breached"`
+	FaceMatch      *models.FaceMatch `json:"face_match,omitempty"`
+	ClaimedBy      string            `json:"claimed_by,omitempty"` // only while the claim is active
+	ClaimExpiresAt *time.Time        `json:"claim_expires_at,omitempty"`
+	ClaimedByMe    bool              `json:"claimed_by_me"`
+}
+
+// KYCQueuePage is one page of the queue
+type KYCQueuePage struct {
+	Items []KYCQueueItem `json:"items"`
+	Total int64          `json:"total"`
+	Page  int64          `json:"page"`
+	Limit int64          `json:"limit"`
+}
+
+// ListKYCQueue returns pending submissions that are not waiting for a second officer, with
+// time in queue and SLA state. Claims past their expiry are shown as unclaimed.
+func ListKYCQueue(ctx context.Context, db *mongo.Database, f KYCQueueFilter) (*KYCQueuePage, error) {
+	now := time.Now()
+	sla := kycSLA()
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit <= 0 || f.Limit > 100 {
+		f.Limit = 20
+	}
+
+	match := bson.M{"status": KYCStatusPending, "approvalid": bson.M{"$exists": false}}
+	submitted := bson.M{}
+	if f.SubmittedFrom != "" {
+		from, err := time.ParseInLocation("2006-01-02", f.SubmittedFrom, Bangkok)
+		if err != nil {
+			return nil, fmt.Errorf("%w: submitted_from must be YYYY-MM-DD", ErrInvalidKYCQuery)
+		}
+		submitted["$gte"] = from
+	}
+	if f.SubmittedTo != "" {
+		to, err := time.ParseInLocation("2006-01-02", f.SubmittedTo, Bangkok)
+		if err != nil {
+			return nil, fmt.Errorf("%w: submitted_to must be YYYY-MM-DD", ErrInvalidKYCQuery)
+		}
+		submitted["$lt"] = to.AddDate(0, 0, 1)
+	}
+	if f.SLABreached {
+		if lt, ok := submitted["$lt"].(time.Time); !ok || now.Add(-sla).Before(lt) {
+			submitted["$lt"] = now.Add(-sla)
+		}
+	}
+	if len(submitted) > 0 {
+		match["submittedat"] = submitted
+	}
+	switch f.Claim {
+	case "":
+	case "mine":
+		match["claimedby"] = f.OfficerID
+		match["claimexpiresat"] = bson.M{"$gt": now}
+	case "unclaimed":
+		match["$or"] = bson.A{
+			bson.M{"claimedby": bson.M{"$in": bson.A{nil, ""}}},
+			bson.M{"claimexpiresat": bson.M{"$lte": now}},
+		}
+	default:
+		return nil, fmt.Errorf("%w: claim must be mine or unclaimed", ErrInvalidKYCQuery)
+	}
+
+	memberMatch := bson.M{}
+	if f.Branch != "" {
+		memberMatch["member.branch"] = f.Branch
+	}
+	if f.Search != "" {
+		memberMatch["$or"] = bson.A{
+			bson.M{"memberid": f.Search},
+			bson.M{"member.name_th": bson.M{"$regex": regexp.QuoteMeta(f.Search), "$options": "i"}},
+		}
+	}
+
+	sortDir := 1
+	if f.Newest {
+		sortDir = -1
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{"from": "members", "localField": "memberid", "foreignField": "memberid", "as": "member"}}},
+		{{Key: "$unwind", Value: bson.M{"path": "$member", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$match", Value: memberMatch}},
+		{{Key: "$sort", Value: bson.D{{Key: "submittedat", Value: sortDir}, {Key: "_id", Value: sortDir}}}},
+		{{Key: "$facet", Value: bson.M{
+			"items": bson.A{bson.M{"$skip": (f.Page - 1) * f.Limit}, bson.M{"$limit": f.Limit}},
+			"total": bson.A{bson.M{"$count": "n"}},
+		}}},
+	}
+	cursor, err := db.Collection("kyc_submissions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query KYC queue: %w", err)
+	}
+	var result []struct {
+		Items []struct {
+			models.KYCSubmission `bson:",inline"`
+			Member               struct {
+				Name   string `bson:"name_th"`
+				Branch string `bson:"branch"`
+			} `bson:"member"`
+		} `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode KYC queue: %w", err)
+	}
+
+	page := &KYCQueuePage{Items: []KYCQueueItem{}, Page: f.Page, Limit: f.Limit}
+	if len(result) == 0 {
+		return page, nil
+	}
+	if len(result[0].Total) > 0 {
+		page.Total = result[0].Total[0].N
+	}
+	for _, row := range result[0].Items {
+		item := KYCQueueItem{
+			SubmissionID:   row.SubmissionID,
+			MemberID:       row.MemberID,
+			Name:           row.Member.Name,
+			Branch:         row.Member.Branch,
+			Attempt:        row.Attempt,
+			SubmittedAt:    row.SubmittedAt,
+			MinutesInQueue: int64(now.Sub(row.SubmittedAt).Minutes()),
+			SLADueAt:       row.SubmittedAt.Add(sla),
+			FaceMatch:      row.FaceMatch,
+		}
+		item.SLABreached = now.After(item.SLADueAt)
+		if row.ClaimedBy != "" && row.ClaimExpiresAt != nil && row.ClaimExpiresAt.After(now) {
+			item.ClaimedBy = row.ClaimedBy
+			item.ClaimExpiresAt = row.ClaimExpiresAt
+			item.ClaimedByMe = row.ClaimedBy == f.OfficerID
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// ClaimKYCSubmission gives an officer the submission to review for the claim timeout.
+// Claiming again renews it; a claim left idle past its expiry can be taken by anyone.
+func ClaimKYCSubmission(ctx context.Context, db *mongo.Database, submissionID, officerID string) (*models.KYCSubmission, error) {
+	now := time.Now()
+	var sub models.KYCSubmission
+	err := db.Collection("kyc_submissions").FindOneAndUpdate(ctx,
+		bson.M{
+			"submissionid": submissionID,
+			"status":       KYCStatusPending,
+			"approvalid":   bson.M{"$exists": false},
+			"$or": bson.A{
+				bson.M{"claimedby": bson.M{"$in": bson.A{nil, "", officerID}}},
+				bson.M{"claimexpiresat": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"claimedby":      officerID,
+			"claimedat":      now,
+			"claimexpiresat": now.Add(kycClaimTimeout()),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&sub)
+	if err == nil {
+		return &sub, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to claim KYC submission: %w", err)
+	}
+
+	// Explain why the claim failed
+	err = db.Collection("kyc_submissions").FindOne(ctx, bson.M{"submissionid": submissionID}).Decode(&sub)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNoOpenKYCSubmission
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load KYC submission: %w", err)
+	}
+	switch {
+	case sub.Status != KYCStatusPending:
+		return nil, ErrNoOpenKYCSubmission
+	case sub.ApprovalID != "":
+		return nil, fmt.Errorf("%w (%s)", ErrApprovalPending, sub.ApprovalID)
+	}
+	return nil, fmt.Errorf("%w (%s until %s)", ErrKYCClaimedByOther, sub.ClaimedBy,
+		sub.ClaimExpiresAt.In(Bangkok).Format("15:04"))
+}
+
+// ReleaseKYCClaim hands a claimed submission back to the queue
+func ReleaseKYCClaim(ctx context.Context, db *mongo.Database, submissionID, officerID string) error {
+	res, err := db.Collection("kyc_submissions").UpdateOne(ctx,
+		bson.M{"submissionid": submissionID, "claimedby": officerID},
+		bson.M{"$unset": bson.M{"claimedby": "", "claimedat": "", "claimexpiresat": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to release KYC submission: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrKYCClaimNotHeld
+	}
+	return nil
+}
+
+// BackfillOpenKYCSubmissions creates kyc_submissions entries for members left pending from
+// before the collection existed, so they appear in the queue. Run once at startup; members
+// that already have a submission are skipped, so running it again is harmless.
+func BackfillOpenKYCSubmissions(ctx context.Context, db *mongo.Database) error {
+	memberIDs, err := db.Collection("members").Distinct(ctx, "memberid", bson.M{
+		"kyc_status":        KYCStatusPending,
+		"kyc_submission_id": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query members: %w", err)
+	}
+	for _, id := range memberIDs {
+		memberID, _ := id.(string)
+		if memberID == "" {
+			continue
+		}
+		if _, err := openKYCSubmission(ctx, db, memberID); err != nil && !errors.Is(err, ErrNoOpenKYCSubmission) {
+			return err
+		}
+	}
+	return nil
+}
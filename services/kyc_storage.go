@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/config"
+	"loan-dynamic-api/models"
+)
+
+const (
+	kycImagePrefix   = "kyc/"
+	kycStagingPrefix = "kyc-staging/"
+	// Objects younger than this are left alone by the sweeper so submissions in flight are not touched
+	kycOrphanGracePeriod = 24 * time.Hour
+)
+
+var ErrKYCUploadFailed = errors.New("failed to store KYC images")
+
+// memberKYCFields are the member document fields SubmitKYCSubmission overwrites
+var memberKYCFields = []string{
+	"citizen_id", "bank_id", "bank_account_no",
+	"kyc_status", "kyc_submitted_at", "kyc_submission_id",
+	"kyc_id_card_image_key", "kyc_bank_book_image_key", "kyc_selfie_image_key",
+	"kyc_reject_reason", "kyc_reviewed_at", "kyc_face_match",
+}
+
+// KYCImage is an inspected image to store with a KYC submission. Target is the submission
+// field that receives the final R2 key.
+type KYCImage struct {
+	Name        string // id_card, bank_book, selfie
+	Data        []byte
+	ContentType string
+	Ext         string
+	Target      *string
+}
+
+// StoreKYCSubmission uploads the images and records the submission so that either
+// everything is kept or nothing is. Images are first uploaded under kyc-staging/, then the
+// submission is committed with the final kyc/ keys, then each staged image is copied to its
+// final key. A failure at any step deletes what was uploaded; a failure after the commit
+// also undoes the submission. Anything left behind by a crash is removed by the kyc_orphans job.
+func StoreKYCSubmission(ctx context.Context, db *mongo.Database, client *s3.Client, bucket string, sub *models.KYCSubmission, images []KYCImage) error {
+	stageID := uuid.New().String()
+	staged := make([]string, 0, len(images))
+	final := make([]string, len(images))
+
+	// 1. Stage
+	for i, img := range images {
+		key := fmt.Sprintf("%s%s/%s/%s%s", kycStagingPrefix, sub.MemberID, stageID, img.Name, img.Ext)
+		stored, err := UploadToR2(ctx, client, bucket, R2Upload{
+			Key:         key,
+			ContentType: img.ContentType,
+			Body:        bytes.NewReader(img.Data),
+		})
+		if err != nil {
+			deleteR2Objects(ctx, client, bucket, staged)
+			return fmt.Errorf("%w: %s: %v", ErrKYCUploadFailed, img.Name, err)
+		}
+		staged = append(staged, key)
+		if sub.ImageSHA256 == nil {
+			sub.ImageSHA256 = map[string]string{}
+		}
+		sub.ImageSHA256[img.Name] = stored.SHA256
+		// kyc/<member_id>/<type>_<uuid><ext>
+		final[i] = fmt.Sprintf("%s%s/%s_%s%s", kycImagePrefix, sub.MemberID, img.Name, uuid.New().String(), img.Ext)
+		*img.Target = final[i]
+	}
+
+	// 2. Commit
+	previous, err := memberKYCSnapshot(ctx, db, sub.MemberID)
+	if err != nil {
+		deleteR2Objects(ctx, client, bucket, staged)
+		return err
+	}
+	if err := SubmitKYCSubmission(ctx, db, sub); err != nil {
+		deleteR2Objects(ctx, client, bucket, staged)
+		return err
+	}
+
+	// 3. Promote
+	for i := range images {
+		_, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(final[i]),
+			CopySource: aws.String((&url.URL{Path: bucket + "/" + staged[i]}).EscapedPath()),
+		})
+		if err != nil {
+			deleteR2Objects(ctx, client, bucket, append(staged, final[:i]...))
+			if rerr := revertKYCSubmission(ctx, db, sub, previous); rerr != nil {
+				log.Printf("KYC submission %s could not be undone after upload failure: %v", sub.SubmissionID, rerr)
+			}
+			return fmt.Errorf("%w: %s: %v", ErrKYCUploadFailed, images[i].Name, err)
+		}
+	}
+
+	// Staged copies are no longer needed; the sweeper removes them if this fails
+	deleteR2Objects(ctx, client, bucket, staged)
+	return nil
+}
+
+// memberKYCSnapshot reads the member fields a submission overwrites, so they can be restored
+func memberKYCSnapshot(ctx context.Context, db *mongo.Database, memberID string) (bson.M, error) {
+	projection := bson.M{}
+	for _, f := range memberKYCFields {
+		projection[f] = 1
+	}
+	var member bson.M
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID},
+		options.FindOne().SetProjection(projection)).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrMemberNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load member: %w", err)
+	}
+	return member, nil
+}
+
+// revertKYCSubmission removes a submission whose images could not be stored and puts the
+// member's KYC fields back as they were. Nothing is changed if the member has moved on.
+func revertKYCSubmission(ctx context.Context, db *mongo.Database, sub *models.KYCSubmission, previous bson.M) error {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		set, unset := bson.M{}, bson.M{}
+		for _, f := range memberKYCFields {
+			if v, ok := previous[f]; ok {
+				set[f] = v
+			} else {
+				unset[f] = ""
+			}
+		}
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		// kyc_status is not checked: a re-verifying member stays verified while pending
+		deleted, err := db.Collection("kyc_submissions").DeleteOne(sc,
+			bson.M{"submissionid": sub.SubmissionID, "status": KYCStatusPending})
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove KYC submission: %w", err)
+		}
+		res, err := db.Collection("members").UpdateOne(sc,
+			bson.M{"memberid": sub.MemberID, "kyc_submission_id": sub.SubmissionID},
+			update)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore member: %w", err)
+		}
+		if deleted.DeletedCount == 0 || res.MatchedCount == 0 {
+			return nil, fmt.Errorf("member %s has moved past submission %s", sub.MemberID, sub.SubmissionID)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// deleteR2Objects removes keys best-effort; failures are logged and left to the sweeper
+func deleteR2Objects(ctx context.Context, client *s3.Client, bucket string, keys []string) int {
+	deleted := 0
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		ids := make([]types.ObjectIdentifier, 0, end-start)
+		for _, k := range keys[start:end] {
+			ids = append(ids, types.ObjectIdentifier{Key: aws.String(k)})
+		}
+		out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			log.Printf("Failed to delete %d R2 objects: %v", len(ids), err)
+			continue
+		}
+		for _, e := range out.Errors {
+			log.Printf("Failed to delete R2 object %s: %s", aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+		deleted += len(ids) - len(out.Errors)
+	}
+	return deleted
+}
+
+// runKYCOrphansJob deletes KYC images that no record references: staged uploads that were
+// never promoted or cleaned up, and kyc/ objects missing from both kyc_submissions and the
+// members' kyc_*_image_key fields. Only objects older than the grace period are considered.
+func runKYCOrphansJob(ctx context.Context, db *mongo.Database) error {
+	client := config.GetR2Client()
+	if client == nil {
+		return fmt.Errorf("R2 storage not configured")
+	}
+	bucket := config.GetR2Bucket()
+	cutoff := time.Now().Add(-kycOrphanGracePeriod)
+
+	staged, err := listR2Keys(ctx, client, bucket, kycStagingPrefix, cutoff)
+	if err != nil {
+		return err
+	}
+
+	images, err := listR2Keys(ctx, client, bucket, kycImagePrefix, cutoff)
+	if err != nil {
+		return err
+	}
+	var orphans []string
+	for start := 0; start < len(images); start += 500 {
+		end := start + 500
+		if end > len(images) {
+			end = len(images)
+		}
+		batch := images[start:end]
+		referenced, err := referencedKYCKeys(ctx, db, batch)
+		if err != nil {
+			return err
+		}
+		for _, k := range batch {
+			if !referenced[k] {
+				orphans = append(orphans, k)
+			}
+		}
+	}
+
+	deleted := deleteR2Objects(ctx, client, bucket, append(staged, orphans...))
+	if deleted > 0 {
+		log.Printf("KYC sweeper: deleted %d orphaned objects (%d staged, %d unreferenced)", deleted, len(staged), len(orphans))
+	}
+	return nil
+}
+
+// listR2Keys returns the keys under prefix last modified before cutoff
+func listR2Keys(ctx context.Context, client *s3.Client, bucket, prefix string, cutoff time.Time) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects: %w", prefix, err)
+		}
+		for _, obj := range page.Contents {
+			if obj.LastModified != nil && obj.LastModified.Before(cutoff) && strings.HasPrefix(aws.ToString(obj.Key), prefix) {
+				keys = append(keys, aws.ToString(obj.Key))
+			}
+		}
+	}
+	return keys, nil
+}
+
+// referencedKYCKeys reports which of keys are stored on a KYC submission or member
+func referencedKYCKeys(ctx context.Context, db *mongo.Database, keys []string) (map[string]bool, error) {
+	referenced := map[string]bool{}
+	sources := []struct {
+		collection string
+		fields     []string
+	}{
+		{"kyc_submissions", []string{"idcardimagekey", "bankbookimagekey", "selfieimagekey"}},
+		{"members", []string{"kyc_id_card_image_key", "kyc_bank_book_image_key", "kyc_selfie_image_key"}},
+	}
+	for _, src := range sources {
+		or := bson.A{}
+		projection := bson.M{}
+		for _, f := range src.fields {
+			or = append(or, bson.M{f: bson.M{"$in": keys}})
+			projection[f] = 1
+		}
+		cursor, err := db.Collection(src.collection).Find(ctx, bson.M{"$or": or},
+			options.Find().SetProjection(projection))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s: %w", src.collection, err)
+		}
+		var docs []bson.M
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", src.collection, err)
+		}
+		for _, doc := range docs {
+			for _, f := range src.fields {
+				if k, ok := doc[f].(string); ok {
+					referenced[k] = true
+				}
+			}
+		}
+	}
+	return referenced, nil
+}
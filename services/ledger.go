@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Chart of accounts codes
+const (
+	GLCash               = "1010"
+	GLLoansReceivable    = "1200"
+	GLMemberDeposits     = "2010"
+	GLFixedDeposits      = "2020"
+	GLWithholdingTax     = "2150"
+	GLShareCapital       = "3010"
+	GLRetainedEarnings   = "3200"
+	GLOpeningBalance     = "3900"
+	GLLoanInterestIncome = "4010"
+	GLDepositInterest    = "5010"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+var chartOfAccounts = []models.GLAccount{
+	{Code: GLCash, Name: "เงินสดและเงินฝากธนาคาร", Type: "asset", NormalBalance: "debit"},
+	{Code: GLLoansReceivable, Name: "ลูกหนี้เงินกู้สมาชิก", Type: "asset", NormalBalance: "debit"},
+	{Code: GLMemberDeposits, Name: "เงินรับฝากออมทรัพย์สมาชิก", Type: "liability", NormalBalance: "credit"},
+	{Code: GLFixedDeposits, Name: "เงินรับฝากประจำสมาชิก", Type: "liability", NormalBalance: "credit"},
+	{Code: GLWithholdingTax, Name: "ภาษีหัก ณ ที่จ่ายค้างนำส่ง", Type: "liability", NormalBalance: "credit"},
+	{Code: GLShareCapital, Name: "ทุนเรือนหุ้น", Type: "equity", NormalBalance: "credit"},
+	{Code: GLRetainedEarnings, Name: "กำไรสุทธิประจำปี", Type: "equity", NormalBalance: "credit"},
+	{Code: GLOpeningBalance, Name: "ยอดยกมา", Type: "equity", NormalBalance: "credit"},
+	{Code: GLLoanInterestIncome, Name: "ดอกเบี้ยรับเงินกู้", Type: "income", NormalBalance: "credit"},
+	{Code: GLDepositInterest, Name: "ดอกเบี้ยจ่ายเงินรับฝาก", Type: "expense", NormalBalance: "debit"},
+}
+
+// depositGLAccount is the liability account that holds a deposit account's balance
+func depositGLAccount(accountType interface{}) string {
+	if accountType == AccountTypeFixed {
+		return GLFixedDeposits
+	}
+	return GLMemberDeposits
+}
+
+// depositLedgerBalances returns ledger balances per deposit sub account for savings and fixed deposits
+func depositLedgerBalances(ctx context.Context, db *mongo.Database) (map[string]map[string]float64, error) {
+	balances := map[string]map[string]float64{}
+	for _, code := range []string{GLMemberDeposits, GLFixedDeposits} {
+		b, err := subAccountBalances(ctx, db, code)
+		if err != nil {
+			return nil, err
+		}
+		balances[code] = b
+	}
+	return balances, nil
+}
+
+// EnsureChartOfAccounts seeds gl_accounts with the built-in chart of accounts
+func EnsureChartOfAccounts(ctx context.Context, db *mongo.Database) error {
+	for _, acc := range chartOfAccounts {
+		_, err := db.Collection("gl_accounts").UpdateOne(ctx,
+			bson.M{"code": acc.Code},
+			bson.M{"$setOnInsert": acc},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("failed to seed gl account %s: %w", acc.Code, err)
+		}
+	}
+	return nil
+}
+
+func isKnownGLAccount(code string) bool {
+	for _, acc := range chartOfAccounts {
+		if acc.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// toSatang converts baht to integer satang so balancing is exact
+func toSatang(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+// PostJournal validates and stores a balanced journal entry. Call it with the
+// session context of the transaction that moves the money so both commit together.
+func PostJournal(ctx context.Context, db *mongo.Database, entry models.JournalEntry) (*models.JournalEntry, error) {
+	if len(entry.Lines) < 2 {
+		return nil, fmt.Errorf("%w: at least two lines are required", ErrUnbalancedEntry)
+	}
+
+	var debits, credits int64
+	for i, line := range entry.Lines {
+		if !isKnownGLAccount(line.AccountCode) {
+			return nil, fmt.Errorf("unknown gl account %q", line.AccountCode)
+		}
+		d, c := toSatang(line.Debit), toSatang(line.Credit)
+		if d < 0 || c < 0 || (d == 0) == (c == 0) {
+			return nil, fmt.Errorf("line %d must have exactly one positive debit or credit", i+1)
+		}
+		entry.Lines[i].Debit = float64(d) / 100
+		entry.Lines[i].Credit = float64(c) / 100
+		debits += d
+		credits += c
+	}
+	if debits != credits {
+		return nil, fmt.Errorf("%w: debits %.2f, credits %.2f", ErrUnbalancedEntry, float64(debits)/100, float64(credits)/100)
+	}
+
+	now := time.Now()
+	entry.EntryID = "JE-" + uuid.New().String()
+	if entry.EntryDate.IsZero() {
+		entry.EntryDate = now
+	}
+	entry.CreatedAt = now
+
+	if _, err := db.Collection("gl_journal_entries").InsertOne(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to post journal entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// TrialBalanceRow is the total debits and credits of one gl account
+type TrialBalanceRow struct {
+	AccountCode string  `bson:"_id" json:"account_code"`
+	Name        string  `bson:"-" json:"name"`
+	Debit       float64 `bson:"debit" json:"debit"`
+	Credit      float64 `bson:"credit" json:"credit"`
+	Balance     float64 `bson:"-" json:"balance"` // in the account's normal direction
+}
+
+// TrialBalance sums all journal lines per gl account
+func TrialBalance(ctx context.Context, db *mongo.Database) ([]TrialBalanceRow, bool, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$lines.accountcode",
+			"debit":  bson.M{"$sum": "$lines.debit"},
+			"credit": bson.M{"$sum": "$lines.credit"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := db.Collection("gl_journal_entries").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to aggregate ledger: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rows := []TrialBalanceRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, false, fmt.Errorf("failed to decode trial balance: %w", err)
+	}
+
+	var debits, credits int64
+	for i := range rows {
+		for _, acc := range chartOfAccounts {
+			if acc.Code == rows[i].AccountCode {
+				rows[i].Name = acc.Name
+				if acc.NormalBalance == "debit" {
+					rows[i].Balance = roundMoney(rows[i].Debit - rows[i].Credit)
+				} else {
+					rows[i].Balance = roundMoney(rows[i].Credit - rows[i].Debit)
+				}
+			}
+		}
+		rows[i].Debit = roundMoney(rows[i].Debit)
+		rows[i].Credit = roundMoney(rows[i].Credit)
+		debits += toSatang(rows[i].Debit)
+		credits += toSatang(rows[i].Credit)
+	}
+	return rows, debits == credits, nil
+}
+
+// LedgerMismatch is a deposit account whose cached balance differs from the ledger
+type LedgerMismatch struct {
+	AccountID     string  `json:"accountid"`
+	CachedBalance float64 `json:"cached_balance"`
+	LedgerBalance float64 `json:"ledger_balance"`
+	Difference    float64 `json:"difference"`
+}
+
+// VerifyDepositBalances compares every deposit_accounts.balance with the balance
+// derived from the member deposit ledger account.
+func VerifyDepositBalances(ctx context.Context, db *mongo.Database) ([]LedgerMismatch, int, error) {
+	ledger, err := depositLedgerBalances(ctx, db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := db.Collection("deposit_accounts").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"accountid": 1, "balance": 1, "accounttype": 1}))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query deposit accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	mismatches := []LedgerMismatch{}
+	checked := 0
+	for cursor.Next(ctx) {
+		var acc bson.M
+		if err := cursor.Decode(&acc); err != nil {
+			return nil, checked, fmt.Errorf("failed to decode deposit account: %w", err)
+		}
+		checked++
+
+		accountID := fmt.Sprintf("%v", acc["accountid"])
+		cached := roundMoney(toFloat(acc["balance"]))
+		derived := ledger[depositGLAccount(acc["accounttype"])][accountID]
+		if toSatang(cached) != toSatang(derived) {
+			mismatches = append(mismatches, LedgerMismatch{
+				AccountID:     accountID,
+				CachedBalance: cached,
+				LedgerBalance: derived,
+				Difference:    roundMoney(cached - derived),
+			})
+		}
+	}
+	return mismatches, checked, cursor.Err()
+}
+
+// subAccountBalances returns credit-minus-debit balances per sub account of a gl account
+func subAccountBalances(ctx context.Context, db *mongo.Database, accountCode string) (map[string]float64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$match", Value: bson.M{"lines.accountcode": accountCode}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$lines.subaccount",
+			"balance": bson.M{"$sum": bson.M{"$subtract": bson.A{"$lines.credit", "$lines.debit"}}},
+		}}},
+	}
+
+	cursor, err := db.Collection("gl_journal_entries").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate ledger: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []bson.M
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode ledger balances: %w", err)
+	}
+
+	balances := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		sub, _ := row["_id"].(string)
+		balances[sub] = roundMoney(toFloat(row["balance"]))
+	}
+	return balances, nil
+}
+
+// PostOpeningBalances brings deposit accounts that predate the ledger into it.
+// Each account gets at most one opening entry for the difference between its
+// cached balance and the ledger at the time of migration.
+func PostOpeningBalances(ctx context.Context, db *mongo.Database) (int, error) {
+	ledger, err := depositLedgerBalances(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	opened, err := db.Collection("gl_journal_entries").Distinct(ctx, "sourceref", bson.M{"sourcetype": "opening_balance"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load opening entries: %w", err)
+	}
+	alreadyOpened := make(map[string]bool, len(opened))
+	for _, ref := range opened {
+		if s, ok := ref.(string); ok {
+			alreadyOpened[s] = true
+		}
+	}
+
+	cursor, err := db.Collection("deposit_accounts").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query deposit accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	posted := 0
+	for cursor.Next(ctx) {
+		var acc bson.M
+		if err := cursor.Decode(&acc); err != nil {
+			return posted, fmt.Errorf("failed to decode deposit account: %w", err)
+		}
+
+		accountID := fmt.Sprintf("%v", acc["accountid"])
+		if alreadyOpened[accountID] {
+			continue
+		}
+		glAccount := depositGLAccount(acc["accounttype"])
+		diff := roundMoney(toFloat(acc["balance"]) - ledger[glAccount][accountID])
+		if diff == 0 {
+			continue
+		}
+
+		lines := []models.JournalLine{
+			{AccountCode: GLOpeningBalance, Debit: diff},
+			{AccountCode: glAccount, SubAccount: accountID, Credit: diff},
+		}
+		if diff < 0 {
+			lines = []models.JournalLine{
+				{AccountCode: glAccount, SubAccount: accountID, Debit: -diff},
+				{AccountCode: GLOpeningBalance, Credit: -diff},
+			}
+		}
+
+		_, err := PostJournal(ctx, db, models.JournalEntry{
+			Description: "ยอดยกมาบัญชีเงินฝาก",
+			SourceType:  "opening_balance",
+			SourceRef:   accountID,
+			Lines:       lines,
+		})
+		if err != nil {
+			return posted, err
+		}
+		posted++
+	}
+	return posted, cursor.Err()
+}
+
+// ListJournalEntries returns journal entries, optionally for one source reference
+func ListJournalEntries(ctx context.Context, db *mongo.Database, sourceRef string, limit int64) ([]models.JournalEntry, error) {
+	filter := bson.M{}
+	if sourceRef != "" {
+		filter["sourceref"] = sourceRef
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "entrydate", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := db.Collection("gl_journal_entries").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.JournalEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode journal entries: %w", err)
+	}
+	return entries, nil
+}
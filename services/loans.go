@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"loan-dynamic-api/models"
+)
+
+var (
+	ErrLoanNotFound      = errors.New("loan application not found")
+	ErrLoanNotApproved   = errors.New("loan application is not approved")
+	ErrLoanNotDisbursed  = errors.New("loan has not been disbursed or is already closed")
+	ErrRepaymentTooLarge = errors.New("repayment exceeds the outstanding balance")
+)
+
+// LoanPaymentResult describes a posted loan repayment
+type LoanPaymentResult struct {
+	PaymentID            string    `json:"payment_id"`
+	ApplicationID        string    `json:"application_id"`
+	Amount               float64   `json:"amount"`
+	Principal            float64   `json:"principal"`
+	Interest             float64   `json:"interest"`
+	OutstandingPrincipal float64   `json:"outstanding_principal"`
+	BalanceAfter         float64   `json:"deposit_balance_after"`
+	PaymentDate          time.Time `json:"payment_date"`
+}
+
+// DisburseLoan pays an approved loan into the member's deposit account and
+// posts Dr loans receivable / Cr member deposits.
+func DisburseLoan(ctx context.Context, db *mongo.Database, applicationID, accountID, officerID string) (float64, error) {
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var amount float64
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		loan, err := loadLoan(sc, db, applicationID)
+		if err != nil {
+			return nil, err
+		}
+		if status, _ := loan["status"].(string); !strings.EqualFold(status, "approved") {
+			return nil, ErrLoanNotApproved
+		}
+
+		amount = roundMoney(toFloat(loan["approvedamount"]))
+		if amount <= 0 {
+			amount = roundMoney(toFloat(loan["requestamount"]))
+		}
+		if amount <= 0 {
+			return nil, ErrInvalidAmount
+		}
+
+		account, err := creditAccount(sc, db, accountID, amount, ErrDestNotFound)
+		if err != nil {
+			return nil, err
+		}
+		if owner, _ := account["memberid"].(string); owner != loan["memberid"] {
+			return nil, fmt.Errorf("deposit account does not belong to the borrower")
+		}
+
+		now := time.Now()
+		txID := fmt.Sprintf("TXN-LOAN-%d", now.UnixNano())
+		_, err = db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+			"transactionid": txID,
+			"accountid":     accountID,
+			"type":          "loan_disbursement",
+			"amount":        amount,
+			"balanceafter":  toFloat(account["balance"]),
+			"datetime":      now,
+			"description":   "รับเงินกู้",
+			"referenceno":   applicationID,
+			"status":        "completed",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record transaction: %w", err)
+		}
+
+		_, err = db.Collection("loan_applications").UpdateOne(sc,
+			bson.M{"applicationid": applicationID},
+			bson.M{"$set": bson.M{
+				"status":               "DISBURSED",
+				"disbursedamount":      amount,
+				"outstandingprincipal": amount,
+				"disbursedaccountid":   accountID,
+				"disbursedby":          officerID,
+				"disbursedat":          now,
+				"updatedat":            now,
+			}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update loan: %w", err)
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "จ่ายเงินกู้",
+			SourceType:  "loan_disbursement",
+			SourceRef:   applicationID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLLoansReceivable, SubAccount: applicationID, Debit: amount},
+				{AccountCode: GLMemberDeposits, SubAccount: accountID, Credit: amount},
+			},
+		})
+		return nil, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// RepayLoan takes a repayment from the member's deposit account. Interest for the
+// installment is settled first (flat rate, as calculated at application time),
+// the rest reduces the outstanding principal.
+func RepayLoan(ctx context.Context, db *mongo.Database, applicationID, accountID string, amount float64) (*LoanPaymentResult, error) {
+	amount = roundMoney(amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var result *LoanPaymentResult
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		loan, err := loadLoan(sc, db, applicationID)
+		if err != nil {
+			return nil, err
+		}
+		if status, _ := loan["status"].(string); !strings.EqualFold(status, "disbursed") {
+			return nil, ErrLoanNotDisbursed
+		}
+
+		outstanding := roundMoney(toFloat(loan["outstandingprincipal"]))
+		interest := roundMoney(installmentInterest(loan))
+		if interest > amount {
+			interest = amount
+		}
+		principal := roundMoney(amount - interest)
+		if principal > outstanding {
+			return nil, fmt.Errorf("%w: outstanding principal is %.2f", ErrRepaymentTooLarge, outstanding)
+		}
+		remaining := roundMoney(outstanding - principal)
+
+		account, err := debitAccount(sc, db, accountID, amount, ErrSourceNotFound)
+		if err != nil {
+			return nil, err
+		}
+		if owner, _ := account["memberid"].(string); owner != loan["memberid"] {
+			return nil, fmt.Errorf("deposit account does not belong to the borrower")
+		}
+
+		now := time.Now()
+		paymentID := fmt.Sprintf("PAY-%d", now.UnixNano())
+		_, err = db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+			"transactionid": fmt.Sprintf("TXN-PAY-%d", now.UnixNano()),
+			"accountid":     accountID,
+			"type":          "loan_repayment",
+			"amount":        amount,
+			"balanceafter":  toFloat(account["balance"]),
+			"datetime":      now,
+			"description":   "ชำระเงินกู้",
+			"referenceno":   paymentID,
+			"status":        "completed",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record transaction: %w", err)
+		}
+
+		_, err = db.Collection("loan_payments").InsertOne(sc, bson.M{
+			"paymentid":     paymentID,
+			"applicationid": applicationID,
+			"memberid":      loan["memberid"],
+			"accountid":     accountID,
+			"amount":        amount,
+			"principal":     principal,
+			"interest":      interest,
+			"balanceafter":  remaining,
+			"paymentdate":   now,
+			"status":        "completed",
+			"createdat":     now,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record loan payment: %w", err)
+		}
+
+		loanUpdate := bson.M{"outstandingprincipal": remaining, "lastpaymentat": now, "updatedat": now}
+		if remaining == 0 {
+			loanUpdate["status"] = "CLOSED"
+			loanUpdate["closedat"] = now
+		}
+		if _, err := db.Collection("loan_applications").UpdateOne(sc,
+			bson.M{"applicationid": applicationID}, bson.M{"$set": loanUpdate}); err != nil {
+			return nil, fmt.Errorf("failed to update loan: %w", err)
+		}
+
+		lines := []models.JournalLine{
+			{AccountCode: GLMemberDeposits, SubAccount: accountID, Debit: amount},
+		}
+		if principal > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLLoansReceivable, SubAccount: applicationID, Credit: principal})
+		}
+		if interest > 0 {
+			lines = append(lines, models.JournalLine{AccountCode: GLLoanInterestIncome, SubAccount: applicationID, Credit: interest})
+		}
+		if _, err := PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "รับชำระเงินกู้",
+			SourceType:  "loan_repayment",
+			SourceRef:   paymentID,
+			Lines:       lines,
+		}); err != nil {
+			return nil, err
+		}
+
+		result = &LoanPaymentResult{
+			PaymentID:            paymentID,
+			ApplicationID:        applicationID,
+			Amount:               amount,
+			Principal:            principal,
+			Interest:             interest,
+			OutstandingPrincipal: remaining,
+			BalanceAfter:         toFloat(account["balance"]),
+			PaymentDate:          now,
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// installmentInterest is the interest part of one flat-rate installment
+func installmentInterest(loan bson.M) float64 {
+	term := toFloat(loan["requestterm"])
+	if total := toFloat(loan["totalinterest"]); total > 0 && term > 0 {
+		return total / term
+	}
+	principal := toFloat(loan["disbursedamount"])
+	return principal * (toFloat(loan["interestrate"]) / 100) / 12
+}
+
+func loadLoan(ctx context.Context, db *mongo.Database, applicationID string) (bson.M, error) {
+	var loan bson.M
+	err := db.Collection("loan_applications").FindOne(ctx, bson.M{"applicationid": applicationID}).Decode(&loan)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLoanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loan: %w", err)
+	}
+	return loan, nil
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MemberName returns the member's Thai name for printed documents, or "" if unknown
+func MemberName(ctx context.Context, db *mongo.Database, memberID string) string {
+	var member bson.M
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID},
+		options.FindOne().SetProjection(bson.M{"name_th": 1})).Decode(&member)
+	if err != nil {
+		return ""
+	}
+	name, _ := member["name_th"].(string)
+	return name
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Notify stores an in-app notification for a member, in the same shape as AddNotification
+func Notify(ctx context.Context, db *mongo.Database, memberID, title, message, notifType string) error {
+	_, err := db.Collection("notifications").InsertOne(ctx, bson.M{
+		"memberid":   memberID,
+		"title":      title,
+		"message":    message,
+		"type":       notifType,
+		"is_read":    false,
+		"created_at": time.Now(),
+	})
+	return err
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotOfficer is returned when an officer-only action is requested by someone else
+var ErrNotOfficer = errors.New("officer privileges required")
+
+// RequireOfficer checks that officerID belongs to a member with the officer role
+func RequireOfficer(ctx context.Context, db *mongo.Database, officerID string) error {
+	if officerID == "" {
+		return fmt.Errorf("%w: officer_id is required", ErrNotOfficer)
+	}
+
+	var member bson.M
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": officerID}).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotOfficer
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load officer: %w", err)
+	}
+
+	if role, _ := member["role"].(string); role != "officer" {
+		return ErrNotOfficer
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Uploads are sent in parts of r2UploadPartSize, r2UploadConcurrency at a time, so an upload
+// holds at most their product in memory whatever the file size. Smaller files go in one PUT.
+const (
+	r2UploadPartSize    = 8 * 1024 * 1024
+	r2UploadConcurrency = 2
+)
+
+var ErrFileTooLarge = errors.New("file size exceeds limit")
+
+// R2Upload is an object to stream to R2. MaxSize is enforced on the bytes read, not on any
+// size the client declared; 0 means no limit.
+type R2Upload struct {
+	Key         string
+	ContentType string
+	Body        io.Reader
+	MaxSize     int64
+}
+
+// R2UploadResult is what was actually stored
+type R2UploadResult struct {
+	Size   int64
+	SHA256 string // hex
+}
+
+// UploadToR2 streams Body to R2 with the multipart upload manager, hashing it on the way.
+// If the body passes MaxSize the upload is aborted and ErrFileTooLarge returned; no object
+// is left behind.
+func UploadToR2(ctx context.Context, client *s3.Client, bucket string, up R2Upload) (*R2UploadResult, error) {
+	body := &hashingReader{r: up.Body, h: sha256.New(), max: up.MaxSize}
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = r2UploadPartSize
+		u.Concurrency = r2UploadConcurrency
+	})
+	_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(up.Key),
+		Body:        body,
+		ContentType: aws.String(up.ContentType),
+	})
+	if body.tooLarge {
+		return nil, ErrFileTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", up.Key, err)
+	}
+	return &R2UploadResult{Size: body.n, SHA256: hex.EncodeToString(body.h.Sum(nil))}, nil
+}
+
+// hashingReader counts and hashes what is read and fails once more than max bytes are read.
+// It hides any Seek method so the manager reads it as a stream.
+type hashingReader struct {
+	r        io.Reader
+	h        hash.Hash
+	n        int64
+	max      int64
+	tooLarge bool
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	r.h.Write(p[:n])
+	if r.max > 0 && r.n > r.max {
+		r.tooLarge = true
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// maxReportIssues caps the issues stored in one report to stay under the 16MB document limit
+const maxReportIssues = 5000
+
+var ErrReportNotFound = errors.New("reconciliation report not found")
+
+// Transaction types that add to or subtract from a deposit account balance
+var (
+	creditTransactionTypes = map[string]bool{
+		"deposit":           true,
+		"transfer_in":       true,
+		"loan_disbursement": true,
+		"interest":          true,
+		"dividend":          true,
+		"patronage_refund":  true,
+		"share_redemption":  true,
+	}
+	debitTransactionTypes = map[string]bool{
+		"withdrawal":     true,
+		"transfer_out":   true,
+		"payment":        true,
+		"pay":            true,
+		"loan_repayment": true,
+		"fee":            true,
+		"share_purchase": true,
+	}
+)
+
+// signedAmount returns the balance effect of a transaction, or false for unknown types
+func signedAmount(txType string, amount float64) (float64, bool) {
+	switch {
+	case creditTransactionTypes[txType]:
+		return amount, true
+	case debitTransactionTypes[txType]:
+		return -amount, true
+	}
+	return 0, false
+}
+
+// ReconcileBalances recomputes each deposit account's balance from its completed
+// transactions, checks the balanceafter chain and stores a dated report.
+func ReconcileBalances(ctx context.Context, db *mongo.Database, generatedBy string) (*models.ReconciliationReport, error) {
+	now := time.Now()
+	report := &models.ReconciliationReport{
+		ReportID:    "RECON-" + uuid.New().String(),
+		ReportDate:  now.In(Bangkok).Format("2006-01-02"),
+		GeneratedAt: now,
+		GeneratedBy: generatedBy,
+		Mismatches:  []models.BalanceMismatch{},
+		ChainBreaks: []models.ChainBreak{},
+	}
+
+	cursor, err := db.Collection("deposit_accounts").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"accountid": 1, "accountnumber": 1, "balance": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deposit accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var acc bson.M
+		if err := cursor.Decode(&acc); err != nil {
+			return nil, fmt.Errorf("failed to decode deposit account: %w", err)
+		}
+		if err := reconcileAccount(ctx, db, acc, report); err != nil {
+			return nil, err
+		}
+		report.AccountsChecked++
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate deposit accounts: %w", err)
+	}
+
+	report.MismatchCount = len(report.Mismatches)
+	report.ChainBreakCount = len(report.ChainBreaks)
+	if report.MismatchCount+report.ChainBreakCount > maxReportIssues {
+		report.Truncated = true
+		if len(report.Mismatches) > maxReportIssues {
+			report.Mismatches = report.Mismatches[:maxReportIssues]
+		}
+		if room := maxReportIssues - len(report.Mismatches); len(report.ChainBreaks) > room {
+			report.ChainBreaks = report.ChainBreaks[:room]
+		}
+	}
+
+	if _, err := db.Collection("reconciliation_reports").InsertOne(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to save reconciliation report: %w", err)
+	}
+	return report, nil
+}
+
+// reconcileAccount replays one account's history into the report
+func reconcileAccount(ctx context.Context, db *mongo.Database, acc bson.M, report *models.ReconciliationReport) error {
+	accountID := fmt.Sprintf("%v", acc["accountid"])
+	accountNumber, _ := acc["accountnumber"].(string)
+
+	cursor, err := db.Collection("deposit_transactions").Find(ctx,
+		bson.M{"accountid": accountID, "status": "completed"},
+		options.Find().SetSort(bson.D{{Key: "datetime", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to query transactions for %s: %w", accountID, err)
+	}
+	defer cursor.Close(ctx)
+
+	var computed float64
+	var prevAfter *float64
+	count := 0
+
+	for cursor.Next(ctx) {
+		var tx bson.M
+		if err := cursor.Decode(&tx); err != nil {
+			return fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		count++
+
+		txType, _ := tx["type"].(string)
+		txID, _ := tx["transactionid"].(string)
+		amount := toFloat(tx["amount"])
+		dt := toTime(tx["datetime"])
+
+		delta, known := signedAmount(txType, amount)
+		if !known {
+			report.ChainBreaks = append(report.ChainBreaks, models.ChainBreak{
+				AccountID:     accountID,
+				TransactionID: txID,
+				DateTime:      dt,
+				Type:          txType,
+				Amount:        amount,
+				Reason:        "unknown transaction type",
+			})
+			continue
+		}
+		computed = roundMoney(computed + delta)
+
+		after, hasAfter := tx["balanceafter"]
+		if !hasAfter {
+			continue
+		}
+		actual := roundMoney(toFloat(after))
+		if prevAfter != nil {
+			expected := roundMoney(*prevAfter + delta)
+			if toSatang(expected) != toSatang(actual) {
+				report.ChainBreaks = append(report.ChainBreaks, models.ChainBreak{
+					AccountID:            accountID,
+					TransactionID:        txID,
+					DateTime:             dt,
+					Type:                 txType,
+					Amount:               amount,
+					PreviousBalanceAfter: *prevAfter,
+					ExpectedBalanceAfter: expected,
+					ActualBalanceAfter:   actual,
+					Reason:               "balanceafter does not follow previous transaction",
+				})
+			}
+		}
+		prevAfter = &actual
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate transactions for %s: %w", accountID, err)
+	}
+
+	stored := roundMoney(toFloat(acc["balance"]))
+	if toSatang(stored) != toSatang(computed) {
+		report.Mismatches = append(report.Mismatches, models.BalanceMismatch{
+			AccountID:        accountID,
+			AccountNumber:    accountNumber,
+			StoredBalance:    stored,
+			ComputedBalance:  computed,
+			Difference:       roundMoney(stored - computed),
+			TransactionCount: count,
+		})
+	}
+	return nil
+}
+
+// ListReconciliationReports returns report summaries, newest first
+func ListReconciliationReports(ctx context.Context, db *mongo.Database, limit int64) ([]models.ReconciliationReport, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "generatedat", Value: -1}}).
+		SetProjection(bson.M{"mismatches": 0, "chainbreaks": 0})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := db.Collection("reconciliation_reports").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconciliation reports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.ReconciliationReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode reconciliation reports: %w", err)
+	}
+	return reports, nil
+}
+
+// GetReconciliationReport loads a full report by id
+func GetReconciliationReport(ctx context.Context, db *mongo.Database, reportID string) (*models.ReconciliationReport, error) {
+	var report models.ReconciliationReport
+	err := db.Collection("reconciliation_reports").FindOne(ctx, bson.M{"reportid": reportID}).Decode(&report)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reconciliation report: %w", err)
+	}
+	return &report, nil
+}
+
+func runReconciliationJob(ctx context.Context, db *mongo.Database) error {
+	report, err := ReconcileBalances(ctx, db, "system")
+	if err != nil {
+		return err
+	}
+	log.Printf("Reconciliation %s: %d accounts, %d mismatches, %d chain breaks",
+		report.ReportDate, report.AccountsChecked, report.MismatchCount, report.ChainBreakCount)
+	return nil
+}
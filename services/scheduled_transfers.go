@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+const (
+	scheduledTransferMaxRetries = 3
+	scheduledTransferRetryDelay = time.Hour
+	scheduledTransferLockTTL    = 5 * time.Minute
+	scheduledTransferBatchSize  = 100
+)
+
+var ErrScheduleNotFound = errors.New("scheduled transfer not found")
+
+// CreateScheduledTransfer validates and stores a standing order
+func CreateScheduledTransfer(ctx context.Context, db *mongo.Database, s models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+	s.Amount = roundMoney(s.Amount)
+	if s.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if s.SourceAccountID == s.DestAccountID {
+		return nil, ErrSameAccount
+	}
+	if s.RunAt.IsZero() {
+		return nil, fmt.Errorf("run_at is required")
+	}
+
+	switch s.Frequency {
+	case "once", "daily":
+	case "weekly":
+		if s.DayOfWeek < 0 || s.DayOfWeek > 6 {
+			return nil, fmt.Errorf("day_of_week must be between 0 (Sunday) and 6")
+		}
+	case "monthly":
+		if s.DayOfMonth < 1 || s.DayOfMonth > 31 {
+			return nil, fmt.Errorf("day_of_month must be between 1 and 31")
+		}
+	default:
+		return nil, fmt.Errorf("frequency must be once, daily, weekly or monthly")
+	}
+
+	// The source account must belong to the member creating the order
+	var source bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": s.SourceAccountID}).Decode(&source)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSourceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load source account: %w", err)
+	}
+	if owner, _ := source["memberid"].(string); owner != s.MemberID {
+		return nil, fmt.Errorf("source account does not belong to member")
+	}
+	if n, err := db.Collection("deposit_accounts").CountDocuments(ctx, bson.M{"accountid": s.DestAccountID}); err != nil {
+		return nil, fmt.Errorf("failed to load destination account: %w", err)
+	} else if n == 0 {
+		return nil, ErrDestNotFound
+	}
+
+	now := time.Now()
+	s.ScheduleID = "SCH-" + uuid.New().String()
+	s.NextRunAt = firstOccurrence(&s)
+	if s.EndDate != nil && s.NextRunAt.After(*s.EndDate) {
+		return nil, fmt.Errorf("end_date is before the first run")
+	}
+	s.Status = "active"
+	s.RetryCount = 0
+	s.CreatedAt = now
+	s.UpdatedAt = now
+
+	if _, err := db.Collection("scheduled_transfers").InsertOne(ctx, s); err != nil {
+		return nil, fmt.Errorf("failed to save scheduled transfer: %w", err)
+	}
+	return &s, nil
+}
+
+// ListScheduledTransfers returns a member's standing orders, newest first
+func ListScheduledTransfers(ctx context.Context, db *mongo.Database, memberID string) ([]models.ScheduledTransfer, error) {
+	cursor, err := db.Collection("scheduled_transfers").Find(ctx, bson.M{"memberid": memberID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scheduled transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	results := []models.ScheduledTransfer{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled transfers: %w", err)
+	}
+	return results, nil
+}
+
+// CancelScheduledTransfer stops a member's active standing order
+func CancelScheduledTransfer(ctx context.Context, db *mongo.Database, memberID, scheduleID string) error {
+	res, err := db.Collection("scheduled_transfers").UpdateOne(ctx,
+		bson.M{"scheduleid": scheduleID, "memberid": memberID, "status": "active"},
+		bson.M{"$set": bson.M{"status": "cancelled", "updated_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to cancel scheduled transfer: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// RunDueScheduledTransfers executes every standing order whose next run is due.
+// Each order is claimed with a short lock so concurrent runners never execute it twice.
+func RunDueScheduledTransfers(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	coll := db.Collection("scheduled_transfers")
+	processed := 0
+
+	for processed < scheduledTransferBatchSize {
+		var s models.ScheduledTransfer
+		err := coll.FindOneAndUpdate(ctx,
+			bson.M{
+				"status":      "active",
+				"nextrunat":   bson.M{"$lte": now},
+				"lockeduntil": bson.M{"$lt": now},
+			},
+			bson.M{"$set": bson.M{"lockeduntil": now.Add(scheduledTransferLockTTL)}},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "nextrunat", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(&s)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return processed, fmt.Errorf("failed to claim scheduled transfer: %w", err)
+		}
+
+		executeScheduledTransfer(ctx, db, &s, now)
+		processed++
+	}
+	return processed, nil
+}
+
+func runScheduledTransfersJob(ctx context.Context, db *mongo.Database) error {
+	n, err := RunDueScheduledTransfers(ctx, db, time.Now())
+	if n > 0 {
+		log.Printf("Scheduled transfers: processed %d", n)
+	}
+	return err
+}
+
+// executeScheduledTransfer runs one claimed order through the same core as
+// PerformInternalTransfer and records the outcome.
+func executeScheduledTransfer(ctx context.Context, db *mongo.Database, s *models.ScheduledTransfer, now time.Time) {
+	description := s.Description
+	if description == "" {
+		description = "รายการโอนอัตโนมัติ"
+	}
+
+	occurrence := scheduledOccurrence(s)
+
+	// One reference per occurrence: if the schedule could not be advanced after a transfer
+	// (crash, failed update), running the occurrence again is refused instead of paying twice
+	reference := fmt.Sprintf("%s:%s", s.ScheduleID, occurrence.UTC().Format("20060102T1504"))
+	outcome, err := SubmitTransfer(ctx, db, TransferRequest{
+		SourceAccountID: s.SourceAccountID,
+		DestAccountID:   s.DestAccountID,
+		Amount:          s.Amount,
+		Description:     description,
+		Reference:       reference,
+	})
+
+	set := bson.M{
+		"lastrunat":   now,
+		"lockeduntil": time.Time{},
+		"updated_at":  time.Now(),
+	}
+
+	if err == nil {
+		set["retrycount"] = 0
+		set["currentoccurrence"] = nil
+		set["lasterror"] = ""
+		if outcome.PendingApprovalID != "" {
+			set["lastresult"] = "pending_approval"
+			set["lasttransactionid"] = outcome.PendingApprovalID
+			notifyMember(ctx, db, s.MemberID, "รายการโอนอัตโนมัติรออนุมัติ",
+				fmt.Sprintf("รายการโอนอัตโนมัติจำนวน %.2f บาท รอเจ้าหน้าที่อนุมัติ", s.Amount), "info")
+		} else {
+			set["lastresult"] = "completed"
+			set["lasttransactionid"] = outcome.Result.SourceTxID
+			notifyMember(ctx, db, s.MemberID, "โอนเงินอัตโนมัติสำเร็จ",
+				fmt.Sprintf("โอนเงินอัตโนมัติจำนวน %.2f บาท สำเร็จ", s.Amount), "success")
+		}
+		advanceSchedule(s, set)
+	} else if errors.Is(err, ErrDuplicateTransfer) {
+		// This occurrence was already transferred or sent for approval; just move on
+		set["retrycount"] = 0
+		set["currentoccurrence"] = nil
+		set["lasterror"] = ""
+		advanceSchedule(s, set)
+	} else {
+		set["lastresult"] = "failed"
+		set["lasterror"] = err.Error()
+		retries := s.RetryCount + 1
+
+		if retries <= scheduledTransferMaxRetries {
+			// Retry the same occurrence later
+			set["retrycount"] = retries
+			set["currentoccurrence"] = occurrence
+			set["nextrunat"] = now.Add(time.Duration(retries) * scheduledTransferRetryDelay)
+		} else {
+			// Give up on this occurrence
+			set["retrycount"] = 0
+			set["currentoccurrence"] = nil
+			notifyMember(ctx, db, s.MemberID, "โอนเงินอัตโนมัติไม่สำเร็จ",
+				fmt.Sprintf("โอนเงินอัตโนมัติจำนวน %.2f บาท ไม่สำเร็จ: %s", s.Amount, err.Error()), "error")
+			if s.Frequency == "once" {
+				set["status"] = "failed"
+			} else {
+				advanceSchedule(s, set)
+			}
+		}
+	}
+
+	if _, uerr := db.Collection("scheduled_transfers").UpdateOne(ctx, bson.M{"scheduleid": s.ScheduleID}, bson.M{"$set": set}); uerr != nil {
+		log.Printf("Failed to update scheduled transfer %s: %v", s.ScheduleID, uerr)
+	}
+}
+
+// advanceSchedule moves a recurring order to its next occurrence, or finishes it
+func advanceSchedule(s *models.ScheduledTransfer, set bson.M) {
+	if s.Frequency == "once" {
+		set["status"] = "completed"
+		return
+	}
+
+	// Step from the scheduled occurrence rather than the run time (retries must not cause drift)
+	next := nextOccurrence(s, scheduledOccurrence(s))
+	if s.EndDate != nil && next.After(*s.EndDate) {
+		set["status"] = "completed"
+		return
+	}
+	set["nextrunat"] = next
+}
+
+// scheduledOccurrence is the occurrence currently being processed, before retry delays
+func scheduledOccurrence(s *models.ScheduledTransfer) time.Time {
+	if s.RetryCount == 0 {
+		return s.NextRunAt
+	}
+	if s.CurrentOccurrence != nil {
+		return *s.CurrentOccurrence
+	}
+	if s.Frequency == "once" {
+		return s.RunAt
+	}
+	// Retries scheduled before currentoccurrence was stored: walk back to the last regular occurrence
+	t := firstOccurrence(s)
+	for {
+		next := nextOccurrence(s, t)
+		if !next.After(t) || next.After(s.NextRunAt) {
+			return t
+		}
+		t = next
+	}
+}
+
+// firstOccurrence is the first run time at or after RunAt
+func firstOccurrence(s *models.ScheduledTransfer) time.Time {
+	start := s.RunAt.In(Bangkok)
+	switch s.Frequency {
+	case "weekly":
+		days := (s.DayOfWeek - int(start.Weekday()) + 7) % 7
+		return start.AddDate(0, 0, days)
+	case "monthly":
+		t := monthlyAt(start, start.Year(), start.Month(), s.DayOfMonth)
+		if t.Before(start) {
+			t = monthlyAt(start, start.Year(), start.Month()+1, s.DayOfMonth)
+		}
+		return t
+	}
+	return start
+}
+
+// nextOccurrence is the recurring run after prev, keeping the time of day of RunAt
+func nextOccurrence(s *models.ScheduledTransfer, prev time.Time) time.Time {
+	prev = prev.In(Bangkok)
+	switch s.Frequency {
+	case "daily":
+		return prev.AddDate(0, 0, 1)
+	case "weekly":
+		return prev.AddDate(0, 0, 7)
+	case "monthly":
+		return monthlyAt(s.RunAt.In(Bangkok), prev.Year(), prev.Month()+1, s.DayOfMonth)
+	}
+	return prev
+}
+
+// monthlyAt returns day N of the given month (clamped to the month end) at clock's time of day
+func monthlyAt(clock time.Time, year int, month time.Month, day int) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, Bangkok).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), 0, 0, Bangkok)
+}
+
+func notifyMember(ctx context.Context, db *mongo.Database, memberID, title, message, notifType string) {
+	if err := Notify(ctx, db, memberID, title, message, notifType); err != nil {
+		log.Printf("Failed to notify member %s: %v", memberID, err)
+	}
+}
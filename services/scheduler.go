@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Job is a periodic background task. On the long-running server the scheduler
+// runs it every Interval; on Vercel it is triggered through the jobs endpoint.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, db *mongo.Database) error
+}
+
+var jobs = []Job{
+	{Name: "scheduled_transfers", Interval: time.Minute, Run: runScheduledTransfersJob},
+	{Name: "reconciliation", Interval: 24 * time.Hour, Run: runReconciliationJob},
+	{Name: "dormant_accounts", Interval: 24 * time.Hour, Run: runDormantAccountsJob},
+	{Name: "fixed_deposit_maturity", Interval: time.Hour, Run: runFixedDepositMaturityJob},
+	{Name: "share_prices", Interval: time.Hour, Run: runSharePricesJob},
+	{Name: "kyc_orphans", Interval: 24 * time.Hour, Run: runKYCOrphansJob},
+	{Name: "kyc_expiry", Interval: 24 * time.Hour, Run: runKYCExpiryJob},
+	{Name: "document_uploads", Interval: time.Hour, Run: runDocumentUploadsJob},
+}
+
+// StartScheduler runs every registered job on its interval until ctx is cancelled
+func StartScheduler(ctx context.Context, db *mongo.Database) {
+	for _, job := range jobs {
+		go func(job Job) {
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := runJob(ctx, db, job); err != nil {
+						log.Printf("Job %s failed: %v", job.Name, err)
+					}
+				}
+			}
+		}(job)
+	}
+	log.Printf("Scheduler started with %d jobs", len(jobs))
+}
+
+// RunJob runs a registered job once by name
+func RunJob(ctx context.Context, db *mongo.Database, name string) error {
+	for _, job := range jobs {
+		if job.Name == name {
+			return runJob(ctx, db, job)
+		}
+	}
+	return fmt.Errorf("unknown job: %s", name)
+}
+
+// JobNames lists the registered jobs
+func JobNames() []string {
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+func runJob(ctx context.Context, db *mongo.Database, job Job) error {
+	if db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	return job.Run(jobCtx, db)
+}
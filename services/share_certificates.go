@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+var (
+	ErrNoShares            = errors.New("member holds no shares")
+	ErrCertificateNotFound = errors.New("share certificate not found")
+	ErrNoSignature         = errors.New("officer has no signature on file, upload one through /officer/signature")
+)
+
+// PrepareShareCertificate builds a certificate for the member's current holdings with the
+// next certificate number. It is saved with SaveShareCertificate once the PDF is stored.
+func PrepareShareCertificate(ctx context.Context, db *mongo.Database, memberID, officerID string) (*models.ShareCertificate, error) {
+	if err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID}).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to load member: %w", err)
+	}
+
+	holdings, err := GetShareHoldings(ctx, db, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if holdings.TotalUnits <= 0 {
+		return nil, ErrNoShares
+	}
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err = db.Collection("counters").FindOneAndUpdate(ctx,
+		bson.M{"_id": "sharecertificate"},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate certificate number: %w", err)
+	}
+
+	return &models.ShareCertificate{
+		CertificateID: "CERT-" + uuid.New().String(),
+		CertificateNo: fmt.Sprintf("%06d", counter.Seq),
+		MemberID:      memberID,
+		MemberName:    MemberName(ctx, db, memberID),
+		Units:         holdings.TotalUnits,
+		Value:         holdings.TotalValue,
+		IssuedAt:      time.Now(),
+		IssuedBy:      officerID,
+		Status:        "valid",
+	}, nil
+}
+
+// SaveShareCertificate stores a certificate and supersedes the member's earlier ones
+func SaveShareCertificate(ctx context.Context, db *mongo.Database, cert *models.ShareCertificate) error {
+	_, err := db.Collection("share_certificates").UpdateMany(ctx,
+		bson.M{"memberid": cert.MemberID, "status": "valid"},
+		bson.M{"$set": bson.M{"status": "superseded"}})
+	if err != nil {
+		return fmt.Errorf("failed to supersede certificates: %w", err)
+	}
+	if _, err := db.Collection("share_certificates").InsertOne(ctx, cert); err != nil {
+		return fmt.Errorf("failed to save certificate: %w", err)
+	}
+	return nil
+}
+
+// GetShareCertificate returns a certificate by id for QR verification
+func GetShareCertificate(ctx context.Context, db *mongo.Database, certificateID string) (*models.ShareCertificate, error) {
+	var cert models.ShareCertificate
+	err := db.Collection("share_certificates").FindOne(ctx, bson.M{"certificateid": certificateID}).Decode(&cert)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCertificateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+// OfficerSignature is an officer's signature image for issued documents (officer_signatures).
+// Rows are written only by SaveOfficerSignature, behind RequireOfficer; the documents
+// collection is not used because anyone can upload there under any ref_id.
+type OfficerSignature struct {
+	OfficerID  string    `bson:"officerid" json:"officer_id"`
+	R2Key      string    `bson:"r2key" json:"-"`
+	SHA256     string    `bson:"sha256" json:"sha256"`
+	UploadedAt time.Time `bson:"uploadedat" json:"uploaded_at"`
+}
+
+// SaveOfficerSignature records sig as the officer's signature and returns the R2 key of the
+// one it replaces, if any, so the caller can delete it
+func SaveOfficerSignature(ctx context.Context, db *mongo.Database, sig OfficerSignature) (string, error) {
+	var previous OfficerSignature
+	err := db.Collection("officer_signatures").FindOneAndUpdate(ctx,
+		bson.M{"officerid": sig.OfficerID},
+		bson.M{"$set": sig},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)).Decode(&previous)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", fmt.Errorf("failed to save signature: %w", err)
+	}
+	return previous.R2Key, nil
+}
+
+// OfficerSignatureKey returns the R2 key of the officer's signature image
+func OfficerSignatureKey(ctx context.Context, db *mongo.Database, officerID string) (string, error) {
+	var sig OfficerSignature
+	err := db.Collection("officer_signatures").FindOne(ctx, bson.M{"officerid": officerID}).Decode(&sig)
+	if err == mongo.ErrNoDocuments || (err == nil && sig.R2Key == "") {
+		return "", ErrNoSignature
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load signature: %w", err)
+	}
+	return sig.R2Key, nil
+}
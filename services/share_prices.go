@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+var (
+	ErrInvalidSharePrice   = errors.New("price must be greater than zero")
+	ErrDuplicatePriceEntry = errors.New("a price is already set for this share type at that time")
+	ErrPriceInPast         = errors.New("effective_from cannot be in the past")
+)
+
+// SharePriceTimeline is the price history of a share type with the price in effect now
+type SharePriceTimeline struct {
+	ShareTypeID  string                  `json:"share_type_id"`
+	CurrentPrice float64                 `json:"current_price"`
+	History      []models.ShareTypePrice `json:"history"`   // effective now or in the past, oldest first
+	Scheduled    []models.ShareTypePrice `json:"scheduled"` // future changes, soonest first
+}
+
+// SetShareTypePrice records a price effective from the given time. A change effective now
+// or earlier also updates the cached price on share_types; future changes are applied by
+// the share_prices job.
+func SetShareTypePrice(ctx context.Context, db *mongo.Database, shareTypeID string, price float64, effectiveFrom time.Time, note, createdBy string) (*models.ShareTypePrice, error) {
+	price = roundMoney(price)
+	if price <= 0 {
+		return nil, ErrInvalidSharePrice
+	}
+	shareType, err := loadShareType(ctx, db, shareTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if effectiveFrom.IsZero() {
+		effectiveFrom = now
+	}
+	// Past purchases were made at the price in effect then; history is never rewritten
+	if effectiveFrom.Before(now.Add(-time.Minute)) {
+		return nil, ErrPriceInPast
+	}
+
+	// Share types created before price history existed get their old price as the first entry
+	if err := EnsureSharePriceHistory(ctx, db, shareType); err != nil {
+		return nil, err
+	}
+
+	entry := models.ShareTypePrice{
+		ShareTypeID:   shareTypeID,
+		Price:         price,
+		EffectiveFrom: effectiveFrom,
+		Note:          note,
+		CreatedBy:     createdBy,
+		CreatedAt:     now,
+	}
+	if _, err := db.Collection("share_type_prices").InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrDuplicatePriceEntry
+		}
+		return nil, fmt.Errorf("failed to save price: %w", err)
+	}
+
+	if !effectiveFrom.After(now) {
+		if _, err := syncShareTypePrice(ctx, db, shareType, now); err != nil {
+			return nil, err
+		}
+	}
+	return &entry, nil
+}
+
+// EnsureSharePriceHistory records the current price of a share type without history,
+// effective from its creation date. Called when a share type is created.
+func EnsureSharePriceHistory(ctx context.Context, db *mongo.Database, shareType *models.ShareType) error {
+	shareTypeID := shareType.ID.Hex()
+	count, err := db.Collection("share_type_prices").CountDocuments(ctx, bson.M{"sharetypeid": shareTypeID})
+	if err != nil {
+		return fmt.Errorf("failed to check price history: %w", err)
+	}
+	if count > 0 || shareType.Price <= 0 {
+		return nil
+	}
+
+	from := shareType.CreatedAt
+	if from.IsZero() {
+		from = shareType.ID.Timestamp()
+	}
+	_, err = db.Collection("share_type_prices").InsertOne(ctx, models.ShareTypePrice{
+		ShareTypeID:   shareTypeID,
+		Price:         roundMoney(shareType.Price),
+		EffectiveFrom: from,
+		Note:          "ราคาเริ่มต้น",
+		CreatedBy:     "system",
+		CreatedAt:     time.Now(),
+	})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to seed price history: %w", err)
+	}
+	return nil
+}
+
+// ParseEffectiveFrom accepts YYYY-MM-DD (midnight Bangkok time) or RFC 3339.
+// An empty string, or today's date, means now.
+func ParseEffectiveFrom(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, Bangkok); err == nil {
+		if s == time.Now().In(Bangkok).Format("2006-01-02") {
+			return time.Time{}, nil
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("effective_from must be YYYY-MM-DD or RFC 3339")
+	}
+	return t, nil
+}
+
+// SharePriceAt returns the price of a share type in effect at the given time. Share types
+// without price history fall back to the price stored on the share type.
+func SharePriceAt(ctx context.Context, db *mongo.Database, shareType *models.ShareType, at time.Time) (float64, error) {
+	var entry models.ShareTypePrice
+	err := db.Collection("share_type_prices").FindOne(ctx,
+		bson.M{"sharetypeid": shareType.ID.Hex(), "effectivefrom": bson.M{"$lte": at}},
+		options.FindOne().SetSort(bson.D{{Key: "effectivefrom", Value: -1}})).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return roundMoney(shareType.Price), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up share price: %w", err)
+	}
+	return entry.Price, nil
+}
+
+// syncShareTypePrice updates the cached price on share_types to the price in effect at now
+// and reports whether it changed
+func syncShareTypePrice(ctx context.Context, db *mongo.Database, shareType *models.ShareType, now time.Time) (bool, error) {
+	price, err := SharePriceAt(ctx, db, shareType, now)
+	if err != nil {
+		return false, err
+	}
+	if price == shareType.Price {
+		return false, nil
+	}
+	_, err = db.Collection("share_types").UpdateOne(ctx, bson.M{"_id": shareType.ID},
+		bson.M{"$set": bson.M{"price": price, "updated_at": now}})
+	if err != nil {
+		return false, fmt.Errorf("failed to update share type price: %w", err)
+	}
+	return true, nil
+}
+
+// GetSharePriceTimeline returns past and scheduled prices of a share type
+func GetSharePriceTimeline(ctx context.Context, db *mongo.Database, shareTypeID string) (*SharePriceTimeline, error) {
+	shareType, err := loadShareType(ctx, db, shareTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.Collection("share_type_prices").Find(ctx, bson.M{"sharetypeid": shareTypeID},
+		options.Find().SetSort(bson.D{{Key: "effectivefrom", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.ShareTypePrice
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode price history: %w", err)
+	}
+
+	now := time.Now()
+	timeline := &SharePriceTimeline{
+		ShareTypeID:  shareTypeID,
+		CurrentPrice: roundMoney(shareType.Price),
+		History:      []models.ShareTypePrice{},
+		Scheduled:    []models.ShareTypePrice{},
+	}
+	for _, e := range entries {
+		if e.EffectiveFrom.After(now) {
+			timeline.Scheduled = append(timeline.Scheduled, e)
+			continue
+		}
+		timeline.History = append(timeline.History, e)
+		timeline.CurrentPrice = e.Price
+	}
+	return timeline, nil
+}
+
+// ApplyScheduledSharePrices updates share_types.price for scheduled changes that have taken effect
+func ApplyScheduledSharePrices(ctx context.Context, db *mongo.Database, now time.Time) (int, error) {
+	cursor, err := db.Collection("share_types").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query share types: %w", err)
+	}
+	var types []models.ShareType
+	if err := cursor.All(ctx, &types); err != nil {
+		return 0, fmt.Errorf("failed to decode share types: %w", err)
+	}
+
+	updated := 0
+	for i := range types {
+		changed, err := syncShareTypePrice(ctx, db, &types[i], now)
+		if err != nil {
+			return updated, err
+		}
+		if changed {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+func runSharePricesJob(ctx context.Context, db *mongo.Database) error {
+	n, err := ApplyScheduledSharePrices(ctx, db, time.Now())
+	if n > 0 {
+		log.Printf("Share prices: applied %d scheduled changes", n)
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Share type statuses. Inactive types cannot be bought but keep their holdings;
+// deleted types have no holdings left.
+const (
+	ShareTypeStatusActive   = "active"
+	ShareTypeStatusInactive = "inactive"
+	ShareTypeStatusDeleted  = "deleted"
+)
+
+var (
+	ErrShareTypeNameRequired        = errors.New("share type name is required")
+	ErrDuplicateShareTypeName       = errors.New("a share type with this name already exists")
+	ErrShareTypeHeld                = errors.New("members still hold shares of this type, pass migrate_to to move them to another share type")
+	ErrInvalidMigrationTarget       = errors.New("migration target must be a different, active share type")
+	ErrInvalidShareTypeStatusChange = errors.New("share type status change is not allowed")
+)
+
+// ValidateShareTypeName checks that the name is present and not used by another share
+// type that has not been deleted. Names are compared case-insensitively.
+func ValidateShareTypeName(ctx context.Context, db *mongo.Database, name string, excludeID primitive.ObjectID) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrShareTypeNameRequired
+	}
+	filter := bson.M{
+		"name":   name,
+		"status": bson.M{"$ne": ShareTypeStatusDeleted},
+	}
+	if !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+	err := db.Collection("share_types").FindOne(ctx, filter,
+		options.FindOne().SetCollation(&options.Collation{Locale: "en", Strength: 2})).Err()
+	if err == nil {
+		return ErrDuplicateShareTypeName
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to check share type name: %w", err)
+	}
+	return nil
+}
+
+// ActivateShareType reopens an inactive or deleted share type for purchases
+func ActivateShareType(ctx context.Context, db *mongo.Database, shareTypeID, officerID, reason string) error {
+	shareType, err := loadShareType(ctx, db, shareTypeID)
+	if err != nil {
+		return err
+	}
+	// The name may have been reused while the type was deleted
+	if shareType.Status == ShareTypeStatusDeleted {
+		if err := ValidateShareTypeName(ctx, db, shareType.Name, shareType.ID); err != nil {
+			return err
+		}
+	}
+	return changeShareTypeStatus(ctx, db, shareType, ShareTypeStatusActive, reason, officerID,
+		[]string{ShareTypeStatusInactive, ShareTypeStatusDeleted})
+}
+
+// DeactivateShareType stops new purchases of a share type. Members keep their holdings
+// and can still redeem them.
+func DeactivateShareType(ctx context.Context, db *mongo.Database, shareTypeID, officerID, reason string) error {
+	shareType, err := loadShareType(ctx, db, shareTypeID)
+	if err != nil {
+		return err
+	}
+	return changeShareTypeStatus(ctx, db, shareType, ShareTypeStatusInactive, reason, officerID,
+		[]string{ShareTypeStatusActive})
+}
+
+// DeleteShareType retires a share type. If members still hold it the delete is refused
+// unless migrateTo names another active share type, in which case every holding (units and
+// paid-up value) is moved to that type in the same transaction. Returns the number of
+// holdings migrated.
+func DeleteShareType(ctx context.Context, db *mongo.Database, shareTypeID, migrateTo, officerID, reason string) (int, error) {
+	shareType, err := loadShareType(ctx, db, shareTypeID)
+	if err != nil {
+		return 0, err
+	}
+
+	var target *models.ShareType
+	if migrateTo != "" {
+		target, err = loadShareType(ctx, db, migrateTo)
+		if errors.Is(err, ErrShareTypeNotFound) {
+			return 0, ErrInvalidMigrationTarget
+		}
+		if err != nil {
+			return 0, err
+		}
+		if target.ID == shareType.ID || target.Status != ShareTypeStatusActive {
+			return 0, ErrInvalidMigrationTarget
+		}
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	migrated := 0
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		migrated = 0
+		// Close the type first. BuyShares writes the share type inside its own transaction
+		// only while it is active, so a concurrent purchase either commits before this
+		// update (and its holding is migrated below) or conflicts and is refused.
+		err := changeShareTypeStatus(sc, db, shareType, ShareTypeStatusDeleted, reason, officerID,
+			[]string{ShareTypeStatusActive, ShareTypeStatusInactive})
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, err := db.Collection("share_accounts").Find(sc, bson.M{
+			"sharetypeid": shareTypeID,
+			"units":       bson.M{"$gt": 0},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query share accounts: %w", err)
+		}
+		var holdings []models.ShareAccount
+		if err := cursor.All(sc, &holdings); err != nil {
+			return nil, fmt.Errorf("failed to decode share accounts: %w", err)
+		}
+		if len(holdings) == 0 {
+			return nil, nil
+		}
+		if target == nil {
+			return nil, fmt.Errorf("%w (%d members)", ErrShareTypeHeld, len(holdings))
+		}
+
+		for _, h := range holdings {
+			if err := migrateShareAccount(sc, db, h, shareType, target, officerID); err != nil {
+				return nil, err
+			}
+			migrated++
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return migrated, nil
+}
+
+// migrateShareAccount moves one holding to the target share type and records a "migration"
+// share transaction. Both types sit in share capital, so nothing is posted to the ledger.
+func migrateShareAccount(sc mongo.SessionContext, db *mongo.Database, h models.ShareAccount, from, to *models.ShareType, officerID string) error {
+	now := time.Now()
+	res, err := db.Collection("share_accounts").DeleteOne(sc, bson.M{"_id": h.ID, "units": h.Units})
+	if err != nil {
+		return fmt.Errorf("failed to close share account: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: holding changed, please retry", ErrInvalidShareTypeStatusChange)
+	}
+
+	var holding models.ShareAccount
+	err = db.Collection("share_accounts").FindOneAndUpdate(sc,
+		bson.M{"memberid": h.MemberID, "sharetypeid": to.ID.Hex()},
+		bson.M{
+			"$inc":         bson.M{"units": h.Units, "value": h.Value},
+			"$set":         bson.M{"sharetypename": to.Name, "updatedat": now},
+			"$setOnInsert": bson.M{"createdat": now},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&holding)
+	if err != nil {
+		return fmt.Errorf("failed to update share account: %w", err)
+	}
+
+	unitPrice := 0.0
+	if h.Units > 0 {
+		unitPrice = roundMoney(h.Value / float64(h.Units))
+	}
+	_, err = db.Collection("share_transactions").InsertOne(sc, models.ShareTransaction{
+		TransactionID: fmt.Sprintf("TXN-SHR-%d-%s", now.UnixNano(), h.MemberID),
+		MemberID:      h.MemberID,
+		ShareTypeID:   to.ID.Hex(),
+		Type:          "migration",
+		Units:         h.Units,
+		UnitPrice:     unitPrice,
+		Amount:        roundMoney(h.Value),
+		UnitsAfter:    holding.Units,
+		ValueAfter:    roundMoney(holding.Value),
+		ProcessedBy:   officerID,
+		Reason:        fmt.Sprintf("ย้ายจากหุ้น %s", from.Name),
+		DateTime:      now,
+		Status:        "completed",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record share transaction: %w", err)
+	}
+	return nil
+}
+
+// changeShareTypeStatus moves a share type between statuses and appends to its history.
+// The update is conditional on the status read so concurrent changes cannot interleave.
+func changeShareTypeStatus(ctx context.Context, db *mongo.Database, shareType *models.ShareType, to, reason, changedBy string, from []string) error {
+	current := shareType.Status
+	if current == "" {
+		current = ShareTypeStatusActive
+	}
+	allowed := false
+	for _, s := range from {
+		if s == current {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: share type is %s", ErrInvalidShareTypeStatusChange, current)
+	}
+
+	now := time.Now()
+	res, err := db.Collection("share_types").UpdateOne(ctx,
+		bson.M{"_id": shareType.ID, "status": shareType.Status},
+		bson.M{
+			"$set": bson.M{"status": to, "status_reason": reason, "updated_at": now},
+			"$push": bson.M{"status_history": models.AccountStatusChange{
+				From: current, To: to, Reason: reason, ChangedBy: changedBy, ChangedAt: now,
+			}},
+		})
+	if err != nil {
+		return fmt.Errorf("failed to update share type status: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: share type status changed, please retry", ErrInvalidShareTypeStatusChange)
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// defaultMinShareUnits is the number of shares a member must keep while a member.
+// Override with MIN_SHARE_UNITS.
+const defaultMinShareUnits = 1
+
+var (
+	ErrShareTypeNotFound   = errors.New("share type not found")
+	ErrShareTypeInactive   = errors.New("share type is not active")
+	ErrInvalidShareUnits   = errors.New("units must be greater than zero")
+	ErrInsufficientShares  = errors.New("member does not hold enough shares")
+	ErrBelowMinimumShares  = errors.New("redemption would leave the member below the minimum shareholding")
+	ErrSharesPledged       = errors.New("shares cannot be redeemed while the member has an outstanding loan")
+	ErrShareAccountMissing = errors.New("member has no shares of this type")
+)
+
+// ShareOrder describes a purchase or redemption of shares against a deposit account
+type ShareOrder struct {
+	MemberID    string
+	ShareTypeID string
+	Units       int64
+	AccountID   string
+	OfficerID   string // redemptions only
+	Reason      string
+}
+
+// ShareHoldings is a member's shares across all share types
+type ShareHoldings struct {
+	MemberID   string                `json:"memberid"`
+	TotalUnits int64                 `json:"total_units"`
+	TotalValue float64               `json:"total_value"`
+	Holdings   []models.ShareAccount `json:"holdings"`
+}
+
+func minShareUnits() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("MIN_SHARE_UNITS"), 10, 64); err == nil && v >= 0 {
+		return v
+	}
+	return defaultMinShareUnits
+}
+
+func loadShareType(ctx context.Context, db *mongo.Database, shareTypeID string) (*models.ShareType, error) {
+	oid, err := primitive.ObjectIDFromHex(shareTypeID)
+	if err != nil {
+		return nil, ErrShareTypeNotFound
+	}
+	var st models.ShareType
+	err = db.Collection("share_types").FindOne(ctx, bson.M{"_id": oid}).Decode(&st)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrShareTypeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share type: %w", err)
+	}
+	return &st, nil
+}
+
+// BuyShares debits the deposit account for units × the price in effect now and adds the
+// units to the member's share account. Posts Dr member deposits / Cr share capital.
+func BuyShares(ctx context.Context, db *mongo.Database, order ShareOrder) (*models.ShareTransaction, error) {
+	if order.Units <= 0 {
+		return nil, ErrInvalidShareUnits
+	}
+
+	shareType, err := loadShareType(ctx, db, order.ShareTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if shareType.Status != "active" {
+		return nil, ErrShareTypeInactive
+	}
+	price, err := SharePriceAt(ctx, db, shareType, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if price <= 0 {
+		return nil, fmt.Errorf("%w: share type has no price", ErrShareTypeInactive)
+	}
+	amount := roundMoney(price * float64(order.Units))
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var record models.ShareTransaction
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+		// Writing the share type conflicts with DeleteShareType closing it in its own
+		// transaction, so a purchase cannot land on a type that is being migrated away
+		res, err := db.Collection("share_types").UpdateOne(sc,
+			bson.M{"_id": shareType.ID, "status": ShareTypeStatusActive},
+			bson.M{"$set": bson.M{"last_purchase_at": now}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check share type: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, ErrShareTypeInactive
+		}
+
+		account, err := debitAccount(sc, db, order.AccountID, amount, ErrSourceNotFound)
+		if err != nil {
+			return nil, err
+		}
+		if account["memberid"] != order.MemberID {
+			return nil, ErrNotAccountOwner
+		}
+
+		var holding models.ShareAccount
+		err = db.Collection("share_accounts").FindOneAndUpdate(sc,
+			bson.M{"memberid": order.MemberID, "sharetypeid": order.ShareTypeID},
+			bson.M{
+				"$inc":         bson.M{"units": order.Units, "value": amount},
+				"$set":         bson.M{"sharetypename": shareType.Name, "updatedat": now},
+				"$setOnInsert": bson.M{"createdat": now},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&holding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update share account: %w", err)
+		}
+
+		txID := fmt.Sprintf("TXN-SHR-%d", now.UnixNano())
+		record = models.ShareTransaction{
+			TransactionID: txID,
+			MemberID:      order.MemberID,
+			ShareTypeID:   order.ShareTypeID,
+			Type:          "purchase",
+			Units:         order.Units,
+			UnitPrice:     price,
+			Amount:        amount,
+			UnitsAfter:    holding.Units,
+			ValueAfter:    roundMoney(holding.Value),
+			AccountID:     order.AccountID,
+			DateTime:      now,
+			Status:        "completed",
+		}
+		if _, err := db.Collection("share_transactions").InsertOne(sc, record); err != nil {
+			return nil, fmt.Errorf("failed to record share transaction: %w", err)
+		}
+
+		_, err = db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+			"transactionid": txID,
+			"accountid":     order.AccountID,
+			"type":          "share_purchase",
+			"amount":        amount,
+			"balanceafter":  toFloat(account["balance"]),
+			"datetime":      now,
+			"description":   fmt.Sprintf("ซื้อหุ้น %s %d หุ้น", shareType.Name, order.Units),
+			"referenceno":   txID,
+			"status":        "completed",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record transaction: %w", err)
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "ซื้อหุ้นสหกรณ์",
+			SourceType:  "share_purchase",
+			SourceRef:   txID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLMemberDeposits, SubAccount: order.AccountID, Debit: amount},
+				{AccountCode: GLShareCapital, SubAccount: order.MemberID, Credit: amount},
+			},
+		})
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// RedeemShares returns shares to the co-op and credits their paid-up value to the
+// member's deposit account. Shares are collateral, so members with an outstanding loan
+// cannot redeem, and every member keeps at least MIN_SHARE_UNITS shares.
+// Posts Dr share capital / Cr member deposits.
+func RedeemShares(ctx context.Context, db *mongo.Database, order ShareOrder) (*models.ShareTransaction, error) {
+	if order.Units <= 0 {
+		return nil, ErrInvalidShareUnits
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var record models.ShareTransaction
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		loans, err := db.Collection("loan_applications").CountDocuments(sc, bson.M{
+			"memberid": order.MemberID,
+			"status":   bson.M{"$in": []string{"DISBURSED", "disbursed"}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check loans: %w", err)
+		}
+		if loans > 0 {
+			return nil, ErrSharesPledged
+		}
+
+		holdings, err := memberShareAccounts(sc, db, order.MemberID)
+		if err != nil {
+			return nil, err
+		}
+		var holding *models.ShareAccount
+		var totalUnits int64
+		for i := range holdings {
+			totalUnits += holdings[i].Units
+			if holdings[i].ShareTypeID == order.ShareTypeID {
+				holding = &holdings[i]
+			}
+		}
+		if holding == nil {
+			return nil, ErrShareAccountMissing
+		}
+		if holding.Units < order.Units {
+			return nil, ErrInsufficientShares
+		}
+		if totalUnits-order.Units < minShareUnits() {
+			return nil, ErrBelowMinimumShares
+		}
+
+		// Redeemed at the average paid-up price of the holding
+		amount := roundMoney(holding.Value)
+		if order.Units < holding.Units {
+			amount = roundMoney(holding.Value * float64(order.Units) / float64(holding.Units))
+		}
+
+		now := time.Now()
+		res, err := db.Collection("share_accounts").UpdateOne(sc,
+			bson.M{"_id": holding.ID, "units": holding.Units},
+			bson.M{
+				"$inc": bson.M{"units": -order.Units, "value": -amount},
+				"$set": bson.M{"updatedat": now},
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update share account: %w", err)
+		}
+		if res.MatchedCount == 0 {
+			return nil, fmt.Errorf("%w: holding changed, please retry", ErrInsufficientShares)
+		}
+
+		account, err := creditAccount(sc, db, order.AccountID, amount, ErrDestNotFound)
+		if err != nil {
+			return nil, err
+		}
+		if account["memberid"] != order.MemberID {
+			return nil, ErrNotAccountOwner
+		}
+
+		txID := fmt.Sprintf("TXN-SHR-%d", now.UnixNano())
+		record = models.ShareTransaction{
+			TransactionID: txID,
+			MemberID:      order.MemberID,
+			ShareTypeID:   order.ShareTypeID,
+			Type:          "redemption",
+			Units:         order.Units,
+			UnitPrice:     roundMoney(amount / float64(order.Units)),
+			Amount:        amount,
+			UnitsAfter:    holding.Units - order.Units,
+			ValueAfter:    roundMoney(holding.Value - amount),
+			AccountID:     order.AccountID,
+			ProcessedBy:   order.OfficerID,
+			Reason:        order.Reason,
+			DateTime:      now,
+			Status:        "completed",
+		}
+		if _, err := db.Collection("share_transactions").InsertOne(sc, record); err != nil {
+			return nil, fmt.Errorf("failed to record share transaction: %w", err)
+		}
+
+		_, err = db.Collection("deposit_transactions").InsertOne(sc, bson.M{
+			"transactionid": txID,
+			"accountid":     order.AccountID,
+			"type":          "share_redemption",
+			"amount":        amount,
+			"balanceafter":  toFloat(account["balance"]),
+			"datetime":      now,
+			"description":   fmt.Sprintf("ถอนหุ้น %s %d หุ้น", holding.ShareTypeName, order.Units),
+			"referenceno":   txID,
+			"status":        "completed",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record transaction: %w", err)
+		}
+
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "ถอนหุ้นสหกรณ์",
+			SourceType:  "share_redemption",
+			SourceRef:   txID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLShareCapital, SubAccount: order.MemberID, Debit: amount},
+				{AccountCode: GLMemberDeposits, SubAccount: order.AccountID, Credit: amount},
+			},
+		})
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func memberShareAccounts(ctx context.Context, db *mongo.Database, memberID string) ([]models.ShareAccount, error) {
+	cursor, err := db.Collection("share_accounts").Find(ctx, bson.M{"memberid": memberID},
+		options.Find().SetSort(bson.D{{Key: "sharetypename", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	holdings := []models.ShareAccount{}
+	if err := cursor.All(ctx, &holdings); err != nil {
+		return nil, fmt.Errorf("failed to decode share accounts: %w", err)
+	}
+	return holdings, nil
+}
+
+// GetShareHoldings returns a member's share accounts with totals
+func GetShareHoldings(ctx context.Context, db *mongo.Database, memberID string) (*ShareHoldings, error) {
+	holdings, err := memberShareAccounts(ctx, db, memberID)
+	if err != nil {
+		return nil, err
+	}
+	result := &ShareHoldings{MemberID: memberID, Holdings: holdings}
+	for _, h := range holdings {
+		result.TotalUnits += h.Units
+		result.TotalValue += h.Value
+	}
+	result.TotalValue = roundMoney(result.TotalValue)
+	return result, nil
+}
+
+// ListShareTransactions returns a member's share transactions, newest first
+func ListShareTransactions(ctx context.Context, db *mongo.Database, memberID string, limit int64) ([]models.ShareTransaction, error) {
+	cursor, err := db.Collection("share_transactions").Find(ctx, bson.M{"memberid": memberID},
+		options.Find().SetSort(bson.D{{Key: "datetime", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share transactions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	txs := []models.ShareTransaction{}
+	if err := cursor.All(ctx, &txs); err != nil {
+		return nil, fmt.Errorf("failed to decode share transactions: %w", err)
+	}
+	return txs, nil
+}
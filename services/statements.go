@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// maxStatementDays limits one statement to about a year of history
+const maxStatementDays = 366
+
+var (
+	ErrStatementPeriod    = errors.New("invalid statement period")
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrNotAccountOwner    = errors.New("account does not belong to member")
+	ErrStatementNotFound  = errors.New("statement not found")
+	ErrUnknownAccountType = errors.New("account_type must be deposit or loan")
+)
+
+// StatementLine is one row of an account statement
+type StatementLine struct {
+	Date        time.Time
+	Reference   string
+	Description string
+	Debit       float64
+	Credit      float64
+	Balance     float64
+}
+
+// StatementData is everything needed to render a statement for a period.
+// For loan statements the balance is the outstanding principal.
+type StatementData struct {
+	AccountType    string
+	AccountID      string
+	AccountNumber  string
+	AccountName    string
+	MemberID       string
+	From           time.Time // inclusive, start of day in Asia/Bangkok
+	To             time.Time // inclusive, start of the last day in Asia/Bangkok
+	OpeningBalance float64
+	ClosingBalance float64
+	TotalDebit     float64
+	TotalCredit    float64
+	Lines          []StatementLine
+}
+
+// ParseStatementPeriod parses YYYY-MM-DD dates as Bangkok calendar days
+func ParseStatementPeriod(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", from, Bangkok)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrStatementPeriod)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, Bangkok)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrStatementPeriod)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to is before from", ErrStatementPeriod)
+	}
+	if end.Sub(start) > maxStatementDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d days per statement", ErrStatementPeriod, maxStatementDays)
+	}
+	return start, end, nil
+}
+
+// BuildStatement collects the opening balance, period rows and closing balance
+// of a deposit account (deposit_transactions) or loan (loan_payments).
+func BuildStatement(ctx context.Context, db *mongo.Database, accountType, accountID, memberID string, from, to time.Time) (*StatementData, error) {
+	switch accountType {
+	case "deposit":
+		return buildDepositStatement(ctx, db, accountID, memberID, from, to)
+	case "loan":
+		return buildLoanStatement(ctx, db, accountID, memberID, from, to)
+	}
+	return nil, ErrUnknownAccountType
+}
+
+func buildDepositStatement(ctx context.Context, db *mongo.Database, accountID, memberID string, from, to time.Time) (*StatementData, error) {
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": accountID}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account: %w", err)
+	}
+	if owner, _ := account["memberid"].(string); owner != memberID {
+		return nil, ErrNotAccountOwner
+	}
+
+	data := &StatementData{
+		AccountType: "deposit",
+		AccountID:   accountID,
+		MemberID:    memberID,
+		From:        from,
+		To:          to,
+		Lines:       []StatementLine{},
+	}
+	data.AccountNumber, _ = account["accountnumber"].(string)
+	data.AccountName, _ = account["accountname"].(string)
+
+	coll := db.Collection("deposit_transactions")
+	end := to.AddDate(0, 0, 1)
+
+	// Opening balance is the balanceafter of the last transaction before the period
+	var last bson.M
+	err = coll.FindOne(ctx,
+		bson.M{"accountid": accountID, "status": "completed", "datetime": bson.M{"$lt": from}},
+		options.FindOne().SetSort(bson.D{{Key: "datetime", Value: -1}, {Key: "_id", Value: -1}})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load opening balance: %w", err)
+	}
+	if last != nil {
+		data.OpeningBalance = roundMoney(toFloat(last["balanceafter"]))
+	}
+
+	cursor, err := coll.Find(ctx,
+		bson.M{"accountid": accountID, "status": "completed", "datetime": bson.M{"$gte": from, "$lt": end}},
+		options.Find().SetSort(bson.D{{Key: "datetime", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	running := data.OpeningBalance
+	for cursor.Next(ctx) {
+		var tx bson.M
+		if err := cursor.Decode(&tx); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		txType, _ := tx["type"].(string)
+		amount := roundMoney(toFloat(tx["amount"]))
+		delta, _ := signedAmount(txType, amount)
+
+		line := StatementLine{Date: toTime(tx["datetime"])}
+		line.Reference, _ = tx["transactionid"].(string)
+		line.Description, _ = tx["description"].(string)
+		if line.Description == "" {
+			line.Description = txType
+		}
+		if delta < 0 {
+			line.Debit = amount
+			data.TotalDebit += amount
+		} else {
+			line.Credit = amount
+			data.TotalCredit += amount
+		}
+
+		running = roundMoney(running + delta)
+		if after, ok := tx["balanceafter"]; ok {
+			running = roundMoney(toFloat(after))
+		}
+		line.Balance = running
+		data.Lines = append(data.Lines, line)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transactions: %w", err)
+	}
+
+	data.ClosingBalance = running
+	data.TotalDebit = roundMoney(data.TotalDebit)
+	data.TotalCredit = roundMoney(data.TotalCredit)
+	return data, nil
+}
+
+func buildLoanStatement(ctx context.Context, db *mongo.Database, applicationID, memberID string, from, to time.Time) (*StatementData, error) {
+	loan, err := loadLoan(ctx, db, applicationID)
+	if errors.Is(err, ErrLoanNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if owner, _ := loan["memberid"].(string); owner != memberID {
+		return nil, ErrNotAccountOwner
+	}
+
+	data := &StatementData{
+		AccountType:   "loan",
+		AccountID:     applicationID,
+		AccountNumber: applicationID,
+		MemberID:      memberID,
+		From:          from,
+		To:            to,
+		Lines:         []StatementLine{},
+	}
+	data.AccountName, _ = loan["loantype"].(string)
+	if data.AccountName == "" {
+		data.AccountName = "สินเชื่อ"
+	}
+
+	end := to.AddDate(0, 0, 1)
+	disbursed := roundMoney(toFloat(loan["disbursedamount"]))
+	disbursedAt := toTime(loan["disbursedat"])
+
+	// Outstanding principal at the start of the period
+	running := 0.0
+	if !disbursedAt.IsZero() && disbursedAt.Before(from) {
+		running = disbursed
+	}
+
+	coll := db.Collection("loan_payments")
+	var last bson.M
+	err = coll.FindOne(ctx,
+		bson.M{"applicationid": applicationID, "status": "completed", "paymentdate": bson.M{"$lt": from}},
+		options.FindOne().SetSort(bson.D{{Key: "paymentdate", Value: -1}, {Key: "_id", Value: -1}})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load opening balance: %w", err)
+	}
+	if last != nil {
+		running = roundMoney(toFloat(last["balanceafter"]))
+	}
+	data.OpeningBalance = running
+
+	if !disbursedAt.IsZero() && !disbursedAt.Before(from) && disbursedAt.Before(end) {
+		running = disbursed
+		data.TotalDebit = disbursed
+		data.Lines = append(data.Lines, StatementLine{
+			Date:        disbursedAt,
+			Reference:   applicationID,
+			Description: "รับเงินกู้",
+			Debit:       disbursed,
+			Balance:     running,
+		})
+	}
+
+	cursor, err := coll.Find(ctx,
+		bson.M{"applicationid": applicationID, "status": "completed", "paymentdate": bson.M{"$gte": from, "$lt": end}},
+		options.Find().SetSort(bson.D{{Key: "paymentdate", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query loan payments: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var p bson.M
+		if err := cursor.Decode(&p); err != nil {
+			return nil, fmt.Errorf("failed to decode loan payment: %w", err)
+		}
+		amount := roundMoney(toFloat(p["amount"]))
+		running = roundMoney(toFloat(p["balanceafter"]))
+
+		line := StatementLine{
+			Date: toTime(p["paymentdate"]),
+			Description: fmt.Sprintf("ชำระเงินกู้ (เงินต้น %.2f ดอกเบี้ย %.2f)",
+				toFloat(p["principal"]), toFloat(p["interest"])),
+			Credit:  amount,
+			Balance: running,
+		}
+		line.Reference, _ = p["paymentid"].(string)
+		data.TotalCredit += amount
+		data.Lines = append(data.Lines, line)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate loan payments: %w", err)
+	}
+
+	data.ClosingBalance = running
+	data.TotalDebit = roundMoney(data.TotalDebit)
+	data.TotalCredit = roundMoney(data.TotalCredit)
+	return data, nil
+}
+
+// SaveStatement records a generated statement for later verification
+func SaveStatement(ctx context.Context, db *mongo.Database, s *models.Statement) error {
+	if _, err := db.Collection("statements").InsertOne(ctx, s); err != nil {
+		return fmt.Errorf("failed to save statement: %w", err)
+	}
+	return nil
+}
+
+// GetStatement loads a statement record by id
+func GetStatement(ctx context.Context, db *mongo.Database, statementID string) (*models.Statement, error) {
+	var s models.Statement
+	err := db.Collection("statements").FindOne(ctx, bson.M{"statementid": statementID}).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrStatementNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statement: %w", err)
+	}
+	return &s, nil
+}
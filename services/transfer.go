@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+// Transfer errors. Handlers map these to HTTP status codes with errors.Is.
+var (
+	ErrInvalidAmount       = errors.New("amount must be greater than zero")
+	ErrSameAccount         = errors.New("source and destination accounts must be different")
+	ErrSourceNotFound      = errors.New("source account not found")
+	ErrDestNotFound        = errors.New("destination account not found")
+	ErrAccountNotActive    = errors.New("account is not active")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrDuplicateTransfer   = errors.New("a transfer with this reference has already been made")
+)
+
+// blockedAccountStatuses are deposit account statuses that cannot send or receive money.
+// Accounts without a status field are treated as active.
+var blockedAccountStatuses = []string{"inactive", AccountStatusFrozen, AccountStatusClosed}
+
+// debitBlockedAccountStatuses cannot send money. Dormant accounts still accept
+// incoming money but must be reactivated by an officer before withdrawals.
+var debitBlockedAccountStatuses = append([]string{AccountStatusDormant}, blockedAccountStatuses...)
+
+// TransferRequest describes a money movement between two deposit accounts
+type TransferRequest struct {
+	SourceAccountID string
+	DestAccountID   string
+	Amount          float64
+	Description     string
+	// Reference makes the transfer idempotent: a second transfer with the same reference
+	// fails with ErrDuplicateTransfer. Empty for one-off transfers.
+	Reference string
+
+	// limits, when set, are re-checked inside the transaction (see reserveTransferLimit)
+	limits *LimitStatus
+}
+
+// TransferResult holds the outcome of a committed transfer.
+// SourceAccount and DestAccount are the post-update account documents.
+type TransferResult struct {
+	SourceTxID    string
+	DestTxID      string
+	DateTime      time.Time
+	Amount        float64
+	SourceAccount bson.M
+	DestAccount   bson.M
+}
+
+// SourceBalanceAfter returns the source account balance after the transfer
+func (r *TransferResult) SourceBalanceAfter() float64 {
+	return toFloat(r.SourceAccount["balance"])
+}
+
+// DestBalanceAfter returns the destination account balance after the transfer
+func (r *TransferResult) DestBalanceAfter() float64 {
+	return toFloat(r.DestAccount["balance"])
+}
+
+// Transfer moves money between two deposit accounts inside a MongoDB transaction.
+// The debit is conditional on balance >= amount, so concurrent transfers cannot
+// overdraw the source account. Any failure aborts the whole transaction.
+func Transfer(ctx context.Context, db *mongo.Database, req TransferRequest) (*TransferResult, error) {
+	amount := roundMoney(req.Amount)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if req.SourceAccountID == "" || req.DestAccountID == "" {
+		return nil, fmt.Errorf("source and destination accounts are required")
+	}
+	if req.SourceAccountID == req.DestAccountID {
+		return nil, ErrSameAccount
+	}
+
+	now := time.Now()
+	result := &TransferResult{
+		SourceTxID: fmt.Sprintf("TXN-OUT-%d", now.UnixNano()),
+		DestTxID:   fmt.Sprintf("TXN-IN-%d", now.UnixNano()),
+		DateTime:   now,
+		Amount:     amount,
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// Enforce the member's limits against committed usage
+		if req.limits != nil {
+			if err := reserveTransferLimit(sc, db, req.limits, amount); err != nil {
+				return nil, err
+			}
+		}
+
+		// A. Deduct from Source (conditional on sufficient balance)
+		sourceAccount, err := debitAccount(sc, db, req.SourceAccountID, amount, ErrSourceNotFound)
+		if err != nil {
+			return nil, err
+		}
+
+		// B. Add to Destination
+		destAccount, err := creditAccount(sc, db, req.DestAccountID, amount, ErrDestNotFound)
+		if err != nil {
+			return nil, err
+		}
+
+		// C. Create Transactions with balances from the post-update documents
+		sourceTx := bson.M{
+			"transactionid": result.SourceTxID,
+			"accountid":     req.SourceAccountID,
+			"type":          "transfer_out",
+			"amount":        amount,
+			"balanceafter":  toFloat(sourceAccount["balance"]),
+			"datetime":      now,
+			"description":   fmt.Sprintf("%s (โอนให้ %s)", req.Description, destAccount["accountname"]),
+			"referenceno":   req.DestAccountID,
+			"status":        "completed",
+		}
+		if req.Reference != "" {
+			sourceTx["idempotencykey"] = req.Reference
+		}
+
+		destTx := bson.M{
+			"transactionid": result.DestTxID,
+			"accountid":     req.DestAccountID,
+			"type":          "transfer_in",
+			"amount":        amount,
+			"balanceafter":  toFloat(destAccount["balance"]),
+			"datetime":      now,
+			"description":   fmt.Sprintf("%s (รับจาก %s)", req.Description, sourceAccount["accountname"]),
+			"referenceno":   req.SourceAccountID,
+			"status":        "completed",
+		}
+
+		if _, err := db.Collection("deposit_transactions").InsertMany(sc, []interface{}{sourceTx, destTx}); err != nil {
+			if req.Reference != "" && mongo.IsDuplicateKeyError(err) {
+				return nil, fmt.Errorf("%w (%s)", ErrDuplicateTransfer, req.Reference)
+			}
+			return nil, fmt.Errorf("failed to record transactions: %w", err)
+		}
+
+		// D. Post to the general ledger
+		_, err = PostJournal(sc, db, models.JournalEntry{
+			EntryDate:   now,
+			Description: "โอนเงินระหว่างบัญชีสมาชิก",
+			SourceType:  "transfer",
+			SourceRef:   result.SourceTxID,
+			Lines: []models.JournalLine{
+				{AccountCode: GLMemberDeposits, SubAccount: req.SourceAccountID, Debit: amount},
+				{AccountCode: GLMemberDeposits, SubAccount: req.DestAccountID, Credit: amount},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result.SourceAccount = sourceAccount
+		result.DestAccount = destAccount
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// debitAccount atomically deducts amount from an active account with enough balance
+// and returns the post-update document.
+func debitAccount(ctx context.Context, db *mongo.Database, accountID string, amount float64, notFound error) (bson.M, error) {
+	filter := bson.M{
+		"accountid":   accountID,
+		"accounttype": bson.M{"$ne": AccountTypeFixed},
+		"status":      bson.M{"$nin": debitBlockedAccountStatuses},
+		"balance":     bson.M{"$gte": amount},
+	}
+	update := bson.M{
+		"$inc": bson.M{"balance": -amount},
+		"$set": bson.M{"lastactivityat": time.Now()},
+	}
+
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, explainAccountFailure(ctx, db, accountID, notFound, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit account %s: %w", accountID, err)
+	}
+
+	// Invariant: a conditional debit can never leave a negative balance
+	if toFloat(account["balance"]) < 0 {
+		return nil, fmt.Errorf("%w: account %s would be overdrawn", ErrInsufficientBalance, accountID)
+	}
+	return account, nil
+}
+
+// creditAccount atomically adds amount to an active account and returns the post-update document.
+func creditAccount(ctx context.Context, db *mongo.Database, accountID string, amount float64, notFound error) (bson.M, error) {
+	filter := bson.M{
+		"accountid":   accountID,
+		"accounttype": bson.M{"$ne": AccountTypeFixed},
+		"status":      bson.M{"$nin": blockedAccountStatuses},
+	}
+	update := bson.M{
+		"$inc": bson.M{"balance": amount},
+		"$set": bson.M{"lastactivityat": time.Now()},
+	}
+
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, explainAccountFailure(ctx, db, accountID, notFound, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to credit account %s: %w", accountID, err)
+	}
+	return account, nil
+}
+
+// explainAccountFailure works out why a conditional account update matched nothing
+func explainAccountFailure(ctx context.Context, db *mongo.Database, accountID string, notFound error, debit bool) error {
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": accountID}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return notFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load account %s: %w", accountID, err)
+	}
+
+	// Fixed deposits only move money at maturity or on early withdrawal
+	if account["accounttype"] == AccountTypeFixed {
+		return fmt.Errorf("%w: account %s is a fixed deposit", ErrAccountNotActive, accountID)
+	}
+
+	blocked := blockedAccountStatuses
+	if debit {
+		blocked = debitBlockedAccountStatuses
+	}
+	status, _ := account["status"].(string)
+	for _, s := range blocked {
+		if status == s {
+			return fmt.Errorf("%w: account %s is %s", ErrAccountNotActive, accountID, status)
+		}
+	}
+	if debit {
+		return ErrInsufficientBalance
+	}
+	return fmt.Errorf("failed to update account %s", accountID)
+}
@@ -0,0 +1,510 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"loan-dynamic-api/models"
+)
+
+var (
+	ErrLimitExceeded    = errors.New("transfer limit exceeded")
+	ErrApprovalNotFound = errors.New("transfer approval not found or already processed")
+)
+
+// Bangkok is the co-op's business timezone; daily and monthly periods start at local midnight
+var Bangkok = time.FixedZone("ICT", 7*60*60)
+
+// TransferLimits are the amounts a member may transfer. A zero ApprovalThreshold
+// means no transfer needs officer approval.
+type TransferLimits struct {
+	PerTransaction    float64 `bson:"pertransaction" json:"per_transaction"`
+	Daily             float64 `bson:"daily" json:"daily"`
+	Monthly           float64 `bson:"monthly" json:"monthly"`
+	ApprovalThreshold float64 `bson:"approvalthreshold" json:"approval_threshold"`
+}
+
+// defaultTransferLimits apply per KYC level when transfer_limits has no matching row.
+// transfer_limits is maintained directly in the database; the generic gateway cannot write it.
+var defaultTransferLimits = map[string]TransferLimits{
+	"verified":   {PerTransaction: 200000, Daily: 500000, Monthly: 2000000, ApprovalThreshold: 100000},
+	"unverified": {PerTransaction: 5000, Daily: 10000, Monthly: 50000},
+}
+
+// MemberLimitOverride is an officer-set limit for one member (member_transfer_limits)
+type MemberLimitOverride struct {
+	MemberID   string         `bson:"memberid" json:"memberid"`
+	Limits     TransferLimits `bson:"limits" json:"limits"`
+	Reason     string         `bson:"reason" json:"reason"`
+	UpdatedBy  string         `bson:"updatedby" json:"updated_by"`
+	ApprovedBy string         `bson:"approvedby,omitempty" json:"approved_by,omitempty"`
+	UpdatedAt  time.Time      `bson:"updatedat" json:"updated_at"`
+}
+
+// LimitStatus is the effective limit for a member and how much of it is used
+type LimitStatus struct {
+	MemberID         string         `json:"memberid"`
+	AccountType      string         `json:"account_type"`
+	KYCLevel         string         `json:"kyc_level"`
+	Source           string         `json:"source"` // member_override, account_type, default
+	Limits           TransferLimits `json:"limits"`
+	UsedToday        float64        `json:"used_today"`
+	UsedThisMonth    float64        `json:"used_this_month"`
+	RemainingDaily   float64        `json:"remaining_daily"`
+	RemainingMonthly float64        `json:"remaining_monthly"`
+}
+
+// deduct updates the remaining amounts after a successful transfer
+func (s *LimitStatus) deduct(amount float64) {
+	s.UsedToday = roundMoney(s.UsedToday + amount)
+	s.UsedThisMonth = roundMoney(s.UsedThisMonth + amount)
+	s.RemainingDaily = roundMoney(s.RemainingDaily - amount)
+	s.RemainingMonthly = roundMoney(s.RemainingMonthly - amount)
+}
+
+// TransferOutcome is the result of SubmitTransfer. Exactly one of Result and
+// PendingApprovalID is set when err is nil.
+type TransferOutcome struct {
+	Result            *TransferResult
+	PendingApprovalID string
+	Limits            *LimitStatus
+}
+
+// SubmitTransfer applies transfer limits and either executes the transfer or,
+// above the approval threshold, parks it in transfer_approvals for an officer.
+func SubmitTransfer(ctx context.Context, db *mongo.Database, req TransferRequest) (*TransferOutcome, error) {
+	if req.SourceAccountID == req.DestAccountID {
+		return nil, ErrSameAccount
+	}
+
+	status, err := GetLimitStatusForAccount(ctx, db, req.SourceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	outcome := &TransferOutcome{Limits: status}
+
+	if err := status.check(req.Amount); err != nil {
+		return outcome, err
+	}
+
+	if status.Limits.ApprovalThreshold > 0 && req.Amount > status.Limits.ApprovalThreshold {
+		approvalID, err := createTransferApproval(ctx, db, status.MemberID, req)
+		if err != nil {
+			return nil, err
+		}
+		outcome.PendingApprovalID = approvalID
+		return outcome, nil
+	}
+
+	if err := ensureTransferLimitLock(ctx, db, status.MemberID); err != nil {
+		return nil, err
+	}
+	req.limits = status
+	result, err := Transfer(ctx, db, req)
+	if err != nil {
+		return outcome, err
+	}
+	status.deduct(result.Amount)
+	outcome.Result = result
+	return outcome, nil
+}
+
+// check verifies amount against the per-transaction, daily and monthly limits
+func (s *LimitStatus) check(amount float64) error {
+	if amount > s.Limits.PerTransaction {
+		return fmt.Errorf("%w: per-transaction limit is %.2f", ErrLimitExceeded, s.Limits.PerTransaction)
+	}
+	if amount > s.RemainingDaily {
+		return fmt.Errorf("%w: remaining daily limit is %.2f", ErrLimitExceeded, s.RemainingDaily)
+	}
+	if amount > s.RemainingMonthly {
+		return fmt.Errorf("%w: remaining monthly limit is %.2f", ErrLimitExceeded, s.RemainingMonthly)
+	}
+	return nil
+}
+
+// GetLimitStatusForAccount resolves the limits for the owner of a deposit account
+func GetLimitStatusForAccount(ctx context.Context, db *mongo.Database, accountID string) (*LimitStatus, error) {
+	var account bson.M
+	err := db.Collection("deposit_accounts").FindOne(ctx, bson.M{"accountid": accountID}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSourceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account: %w", err)
+	}
+
+	memberID, _ := account["memberid"].(string)
+	accountType, _ := account["accounttype"].(string)
+	return GetLimitStatus(ctx, db, memberID, accountType)
+}
+
+// GetLimitStatus resolves the effective limits for a member and the amounts
+// already transferred in the current day and month.
+func GetLimitStatus(ctx context.Context, db *mongo.Database, memberID, accountType string) (*LimitStatus, error) {
+	if memberID == "" {
+		return nil, fmt.Errorf("account has no owner")
+	}
+	if accountType == "" {
+		accountType = "savings"
+	}
+
+	kycLevel, err := memberKYCLevel(ctx, db, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &LimitStatus{
+		MemberID:    memberID,
+		AccountType: accountType,
+		KYCLevel:    kycLevel,
+	}
+
+	// 1. Member override
+	var override MemberLimitOverride
+	err = db.Collection("member_transfer_limits").FindOne(ctx, bson.M{"memberid": memberID}).Decode(&override)
+	switch {
+	case err == nil:
+		status.Limits = override.Limits
+		status.Source = "member_override"
+	case err != mongo.ErrNoDocuments:
+		return nil, fmt.Errorf("failed to load limit override: %w", err)
+	default:
+		// 2. Configured limit per account type and KYC level
+		var configured TransferLimits
+		err = db.Collection("transfer_limits").FindOne(ctx, bson.M{
+			"accounttype": accountType,
+			"kyclevel":    kycLevel,
+		}).Decode(&configured)
+		switch {
+		case err == nil:
+			status.Limits = configured
+			status.Source = "account_type"
+		case err != mongo.ErrNoDocuments:
+			return nil, fmt.Errorf("failed to load transfer limits: %w", err)
+		default:
+			// 3. Built-in default
+			status.Limits = defaultTransferLimits[kycLevel]
+			status.Source = "default"
+		}
+	}
+
+	if err := status.loadUsage(ctx, db); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// loadUsage fills the used and remaining amounts for the current day and month
+func (s *LimitStatus) loadUsage(ctx context.Context, db *mongo.Database) error {
+	now := time.Now().In(Bangkok)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, Bangkok)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, Bangkok)
+
+	var err error
+	s.UsedToday, err = transferredSince(ctx, db, s.MemberID, startOfDay)
+	if err != nil {
+		return err
+	}
+	s.UsedThisMonth, err = transferredSince(ctx, db, s.MemberID, startOfMonth)
+	if err != nil {
+		return err
+	}
+
+	s.RemainingDaily = roundMoney(s.Limits.Daily - s.UsedToday)
+	s.RemainingMonthly = roundMoney(s.Limits.Monthly - s.UsedThisMonth)
+	return nil
+}
+
+// ensureTransferLimitLock creates the member's transfer_limit_locks row outside any
+// transaction, so reserveTransferLimit only ever updates an existing document.
+func ensureTransferLimitLock(ctx context.Context, db *mongo.Database, memberID string) error {
+	_, err := db.Collection("transfer_limit_locks").UpdateOne(ctx,
+		bson.M{"memberid": memberID},
+		bson.M{"$setOnInsert": bson.M{"memberid": memberID, "transfers": 0}},
+		options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to prepare transfer limit lock: %w", err)
+	}
+	return nil
+}
+
+// reserveTransferLimit runs inside the transfer transaction. Writing the member's lock
+// row makes concurrent transfers by the same member conflict, so MongoDB retries all but
+// one of them; each then re-reads usage from a snapshot that includes the others and
+// checks the limits again. The pre-check in SubmitTransfer alone can be raced past.
+func reserveTransferLimit(sc mongo.SessionContext, db *mongo.Database, status *LimitStatus, amount float64) error {
+	res, err := db.Collection("transfer_limit_locks").UpdateOne(sc,
+		bson.M{"memberid": status.MemberID},
+		bson.M{"$inc": bson.M{"transfers": 1}, "$set": bson.M{"lastat": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to lock transfer limits: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("transfer limit lock missing for member %s", status.MemberID)
+	}
+	if err := status.loadUsage(sc, db); err != nil {
+		return err
+	}
+	return status.check(amount)
+}
+
+// memberKYCLevel maps a member's KYC state to a limit tier
+func memberKYCLevel(ctx context.Context, db *mongo.Database, memberID string) (string, error) {
+	var member bson.M
+	err := db.Collection("members").FindOne(ctx, bson.M{"memberid": memberID}).Decode(&member)
+	if err == mongo.ErrNoDocuments {
+		return "", fmt.Errorf("member profile not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load member: %w", err)
+	}
+
+	if level, _ := member["kyc_level"].(string); level != "" {
+		return level, nil
+	}
+	if MemberKYCVerified(member, time.Now()) {
+		return "verified", nil
+	}
+	return "unverified", nil
+}
+
+// transferredSince sums completed outgoing transfers from all of a member's accounts.
+// Moves between the member's own accounts (fixed deposit opening and payout, account
+// closure, own-account transfers) do not count towards the limits.
+func transferredSince(ctx context.Context, db *mongo.Database, memberID string, since time.Time) (float64, error) {
+	accountIDs, err := db.Collection("deposit_accounts").Distinct(ctx, "accountid", bson.M{"memberid": memberID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load member accounts: %w", err)
+	}
+	if len(accountIDs) == 0 {
+		return 0, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"accountid":   bson.M{"$in": accountIDs},
+			"referenceno": bson.M{"$nin": accountIDs},
+			"type":        "transfer_out",
+			"status":      "completed",
+			"datetime":    bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "total": bson.M{"$sum": "$amount"}}}},
+	}
+
+	cursor, err := db.Collection("deposit_transactions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []bson.M
+	if err := cursor.All(ctx, &rows); err != nil {
+		return 0, fmt.Errorf("failed to sum transfers: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return roundMoney(toFloat(rows[0]["total"])), nil
+}
+
+// SetMemberLimitOverride stores officer-set limits for a member
+func SetMemberLimitOverride(ctx context.Context, db *mongo.Database, override MemberLimitOverride) error {
+	if err := validateLimitOverride(override); err != nil {
+		return err
+	}
+	override.UpdatedAt = time.Now()
+
+	_, err := db.Collection("member_transfer_limits").UpdateOne(ctx,
+		bson.M{"memberid": override.MemberID},
+		bson.M{"$set": override},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save limit override: %w", err)
+	}
+	return nil
+}
+
+func validateLimitOverride(override MemberLimitOverride) error {
+	if override.Limits.PerTransaction <= 0 || override.Limits.Daily <= 0 || override.Limits.Monthly <= 0 {
+		return fmt.Errorf("per_transaction, daily and monthly limits must be greater than zero")
+	}
+	if override.Limits.ApprovalThreshold < 0 {
+		return fmt.Errorf("approval_threshold cannot be negative")
+	}
+	return nil
+}
+
+// RequestMemberLimitOverride parks a limit override in approval_requests; it takes effect
+// when a second officer approves it. Returns the approval request id.
+func RequestMemberLimitOverride(ctx context.Context, db *mongo.Database, override MemberLimitOverride) (string, error) {
+	if err := validateLimitOverride(override); err != nil {
+		return "", err
+	}
+	payload, err := encodePayload(override)
+	if err != nil {
+		return "", err
+	}
+	req := &models.ApprovalRequest{
+		Type:     ApprovalLimitOverride,
+		TargetID: override.MemberID,
+		Summary: fmt.Sprintf("ปรับวงเงินโอนสมาชิก %s เป็น ต่อรายการ %.2f / วัน %.2f / เดือน %.2f: %s",
+			override.MemberID, override.Limits.PerTransaction, override.Limits.Daily, override.Limits.Monthly, override.Reason),
+		Payload:     payload,
+		RequestedBy: override.UpdatedBy,
+	}
+	if err := createApprovalRequest(ctx, db, req); err != nil {
+		return "", err
+	}
+	return req.RequestID, nil
+}
+
+// approveLimitOverride saves an override once a second officer approves it
+func approveLimitOverride(ctx context.Context, db *mongo.Database, req *models.ApprovalRequest, approvedBy string) error {
+	var override MemberLimitOverride
+	if err := decodePayload(req.Payload, &override); err != nil {
+		return err
+	}
+	override.MemberID = req.TargetID
+	override.UpdatedBy = req.RequestedBy
+	override.ApprovedBy = approvedBy
+	return SetMemberLimitOverride(ctx, db, override)
+}
+
+// RemoveMemberLimitOverride reverts a member to the configured limits
+func RemoveMemberLimitOverride(ctx context.Context, db *mongo.Database, memberID string) (bool, error) {
+	res, err := db.Collection("member_transfer_limits").DeleteOne(ctx, bson.M{"memberid": memberID})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove limit override: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// createTransferApproval parks a transfer above the approval threshold
+func createTransferApproval(ctx context.Context, db *mongo.Database, memberID string, req TransferRequest) (string, error) {
+	approvalID := "TAPR-" + uuid.New().String()
+	approval := bson.M{
+		"approvalid":      approvalID,
+		"memberid":        memberID,
+		"sourceaccountid": req.SourceAccountID,
+		"destaccountid":   req.DestAccountID,
+		"amount":          roundMoney(req.Amount),
+		"description":     req.Description,
+		"status":          "pending",
+		"requestedat":     time.Now(),
+	}
+	if req.Reference != "" {
+		approval["reference"] = req.Reference
+	}
+	_, err := db.Collection("transfer_approvals").InsertOne(ctx, approval)
+	if err != nil {
+		if req.Reference != "" && mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("%w (%s)", ErrDuplicateTransfer, req.Reference)
+		}
+		return "", fmt.Errorf("failed to create transfer approval: %w", err)
+	}
+	return approvalID, nil
+}
+
+// ListPendingTransferApprovals returns transfers waiting for an officer
+func ListPendingTransferApprovals(ctx context.Context, db *mongo.Database) ([]bson.M, error) {
+	cursor, err := db.Collection("transfer_approvals").Find(ctx, bson.M{"status": "pending"},
+		options.Find().SetSort(bson.D{{Key: "requestedat", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transfer approvals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	results := []bson.M{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode transfer approvals: %w", err)
+	}
+	return results, nil
+}
+
+// ApproveTransfer executes a parked transfer. The approval threshold is waived
+// but the per-transaction, daily and monthly limits still apply.
+func ApproveTransfer(ctx context.Context, db *mongo.Database, approvalID, officerID string) (*TransferResult, error) {
+	// An officer who is also a member may not approve their own transfer
+	n, err := db.Collection("transfer_approvals").CountDocuments(ctx, bson.M{"approvalid": approvalID, "memberid": officerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transfer approval: %w", err)
+	}
+	if n > 0 {
+		return nil, ErrSelfApproval
+	}
+
+	var approval bson.M
+	err = db.Collection("transfer_approvals").FindOneAndUpdate(ctx,
+		bson.M{"approvalid": approvalID, "status": "pending", "memberid": bson.M{"$ne": officerID}},
+		bson.M{"$set": bson.M{"status": "processing", "approvedby": officerID, "approvedat": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&approval)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim transfer approval: %w", err)
+	}
+
+	req := TransferRequest{
+		SourceAccountID: fmt.Sprintf("%v", approval["sourceaccountid"]),
+		DestAccountID:   fmt.Sprintf("%v", approval["destaccountid"]),
+		Amount:          toFloat(approval["amount"]),
+	}
+	req.Description, _ = approval["description"].(string)
+	req.Reference, _ = approval["reference"].(string)
+
+	result, err := func() (*TransferResult, error) {
+		status, err := GetLimitStatusForAccount(ctx, db, req.SourceAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if err := status.check(req.Amount); err != nil {
+			return nil, err
+		}
+		if err := ensureTransferLimitLock(ctx, db, status.MemberID); err != nil {
+			return nil, err
+		}
+		req.limits = status
+		return Transfer(ctx, db, req)
+	}()
+
+	update := bson.M{"updatedat": time.Now()}
+	if err != nil {
+		update["status"] = "failed"
+		update["error"] = err.Error()
+	} else {
+		update["status"] = "completed"
+		update["transactionid"] = result.SourceTxID
+	}
+	if _, uerr := db.Collection("transfer_approvals").UpdateOne(ctx, bson.M{"approvalid": approvalID}, bson.M{"$set": update}); uerr != nil && err == nil {
+		err = fmt.Errorf("transfer completed but approval record update failed: %w", uerr)
+	}
+	return result, err
+}
+
+// RejectTransfer declines a parked transfer
+func RejectTransfer(ctx context.Context, db *mongo.Database, approvalID, officerID, reason string) error {
+	res, err := db.Collection("transfer_approvals").UpdateOne(ctx,
+		bson.M{"approvalid": approvalID, "status": "pending"},
+		bson.M{"$set": bson.M{
+			"status":     "rejected",
+			"rejectedby": officerID,
+			"reason":     reason,
+			"updatedat":  time.Now(),
+		}})
+	if err != nil {
+		return fmt.Errorf("failed to reject transfer approval: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrApprovalNotFound
+	}
+	return nil
+}